@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// judge-worker 独立的评测进程：从 Redis 的 judge:queue 取任务，
+// 调用 go-judge 评测后把结果写入 judge:result，由 API 服务落库。
+// 不连接数据库，只需要 Redis 和题目数据目录 (data/problems)，
+// 可以和 go-judge 部署在同一台机器上按需扩容。
+func main() {
+	// 加载 .env 文件
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	// 初始化语言配置
+	if err := config.InitLanguageConfig(); err != nil {
+		log.Fatalf("Failed to load language config: %v", err)
+	}
+
+	config.InitRedis()
+	config.InitJudgeConfig()
+
+	// 使用 API 服务发布的语言配置，并在管理员修改后自动更新
+	config.WatchLanguages()
+
+	// 初始化评测系统
+	if err := judge.Init(); err != nil {
+		log.Fatalf("Failed to initialize judge system: %v", err)
+	}
+
+	log.Printf("Judge worker started, judge addr: %s, concurrency: %d",
+		config.Judge.JudgeAddr, config.Judge.Concurrency)
+
+	// 等待退出信号
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Judge worker stopped")
+}
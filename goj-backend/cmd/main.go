@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/events"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/plagiarism"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/rank"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/routes"
 	"io"
@@ -26,12 +28,9 @@ func main() {
 	config.InitJudgeConfig()
 
 	// 初始化 WebSocket 管理器
+	config.InitWebSocketConfig()
 	handler.InitWebSocketManager()
-
-	// 初始化评测系统
-	if err := judge.Init(); err != nil {
-		log.Fatalf("Failed to initialize judge system: %v", err)
-	}
+	handler.StartProgressSubscriber(handler.GetWebSocketManager())
 
 	// 初始化排行榜更新任务
 	rank.InitRankUpdateTask()
@@ -46,6 +45,29 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// 加载数据库中的语言配置并同步给评测机
+	if err := config.SyncLanguagesFromDB(); err != nil {
+		log.Printf("Failed to sync languages: %v", err)
+	}
+	config.WatchLanguages()
+
+	// 比赛结束后自动查重
+	plagiarism.InitContestReportTask()
+
+	// 消费评测结果：落库并推送
+	judge.InitResultConsumer()
+
+	// 事件总线：投递 Webhook，发布比赛开始和结束事件
+	events.StartDispatcher()
+	events.StartContestWatcher()
+
+	// 单机部署时可以在 API 进程内同时运行评测机
+	if os.Getenv("JUDGE_EMBEDDED_WORKER") == "true" {
+		if err := judge.Init(); err != nil {
+			log.Fatalf("Failed to initialize judge system: %v", err)
+		}
+	}
+
 	// 创建 Gin 实例
 	r := gin.Default()
 
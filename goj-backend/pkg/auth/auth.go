@@ -738,24 +738,28 @@ func GetUserContests(c *gin.Context) {
 		return
 	}
 
+	// 比赛信息单独作为字段，models.Contest 自定义了 JSON 序列化，嵌入时会覆盖其他字段
 	var contestRecords []struct {
-		models.Contest
-		Rank              int   `json:"rank"`
-		Score             int   `json:"score"`
-		TotalParticipants int64 `json:"totalParticipants"`
+		Contest           models.Contest `json:"contest" gorm:"embedded"`
+		Rank              int            `json:"rank"`
+		Score             int            `json:"score"`
+		TotalParticipants int64          `json:"totalParticipants"`
+		TeamID            uint           `json:"teamId"`
+		TeamName          string         `json:"teamName"` // 团队赛中代表的队伍，成绩为队伍成绩
 	}
 	var total int64
 
 	// 获取总数
-	config.DB.Table("contest_participants").
+	config.DB.Model(&models.ContestParticipant{}).
 		Where("user_id = ?", user.ID).
 		Count(&total)
 
 	// 获取分页数据
 	err := config.DB.Table("contests").
-		Select("contests.*, cp.rank, cp.score, (SELECT COUNT(*) FROM contest_participants WHERE contest_id = contests.id) as total_participants").
+		Select("contests.*, cp.rank, cp.score, cp.team_id, teams.name as team_name, contests.participant_count as total_participants").
 		Joins("JOIN contest_participants cp ON cp.contest_id = contests.id").
-		Where("cp.user_id = ?", user.ID).
+		Joins("LEFT JOIN teams ON teams.id = cp.team_id").
+		Where("cp.user_id = ? AND cp.deleted_at IS NULL", user.ID).
 		Order("contests.start_time DESC").
 		Limit(pageSize).
 		Offset(offset).
@@ -13,6 +13,24 @@ type JudgeConfig struct {
 	JudgeAddr     string // 评测机地址
 	Concurrency   int    // 评测并发数
 	MemoryLimitMB int    // 每个评测任务的内存限制(MB)
+
+	TestcaseMode        string // 测试点执行方式: sequential, parallel, batch
+	TestcaseParallelism int    // 单个提交同时运行的测试点数
+	NodeMaxRuns         int    // 本节点同时运行的测试点总数上限
+
+	MaxSourceBytes int // 全局代码长度上限(字节)，题目可以单独设置
+
+	RecheckCount  int    // 运行时间接近时间限制的测试点的复测次数，0 表示不复测
+	RecheckMargin int    // 触发复测的时间范围，为时间限制的百分比
+	RecheckPick   string // 复测后取哪次运行的结果: best, median
+
+	BenchmarkEnabled     bool // 启动时测试节点速度，按速度系数换算已标定题目的时间限制
+	BenchmarkReferenceMs int  // 基准用时(ms)，0 表示以第一台完成测试的节点为基准
+
+	TraceEnabled       bool // 是否记录评测追踪（沙箱请求与响应）
+	TraceMaxFieldBytes int  // 追踪中单个文件内容的最大保留长度
+	TraceRetentionDays int  // 追踪记录保留天数
+	TraceMaxCount      int  // 最多保留的追踪记录数，0 表示不限制
 }
 
 var Judge JudgeConfig
@@ -101,4 +119,52 @@ func InitJudgeConfig() {
 
 	// 计算最优并发数
 	Judge.Concurrency = calculateConcurrency(Judge.MemoryLimitMB)
+
+	// 测试点并行执行，默认逐个运行
+	Judge.TestcaseMode = os.Getenv("JUDGE_TESTCASE_MODE")
+	if Judge.TestcaseMode == "" {
+		Judge.TestcaseMode = "sequential"
+	}
+	Judge.TestcaseParallelism = getEnvInt("JUDGE_TESTCASE_PARALLELISM", 4)
+	Judge.NodeMaxRuns = getEnvInt("JUDGE_NODE_MAX_RUNS", runtime.NumCPU())
+	if Judge.NodeMaxRuns < 1 {
+		Judge.NodeMaxRuns = 1
+	}
+	log.Printf("[Config] Testcase mode: %s, parallelism: %d, node max runs: %d",
+		Judge.TestcaseMode, Judge.TestcaseParallelism, Judge.NodeMaxRuns)
+
+	// 代码长度上限，默认 64KB
+	Judge.MaxSourceBytes = getEnvInt("JUDGE_MAX_SOURCE_BYTES", 64*1024)
+
+	// 临界超时复测，默认关闭
+	Judge.RecheckCount = getEnvInt("JUDGE_RECHECK_COUNT", 0)
+	Judge.RecheckMargin = getEnvInt("JUDGE_RECHECK_MARGIN", 10)
+	Judge.RecheckPick = os.Getenv("JUDGE_RECHECK_PICK")
+	if Judge.RecheckPick != "median" {
+		Judge.RecheckPick = "best"
+	}
+	if Judge.RecheckCount > 0 {
+		log.Printf("[Config] Recheck borderline testcases: count: %d, margin: %d%%, pick: %s",
+			Judge.RecheckCount, Judge.RecheckMargin, Judge.RecheckPick)
+	}
+
+	// 节点性能测试，默认开启
+	Judge.BenchmarkEnabled = os.Getenv("JUDGE_BENCHMARK_ENABLED") != "false"
+	Judge.BenchmarkReferenceMs = getEnvInt("JUDGE_BENCHMARK_REFERENCE_MS", 0)
+
+	// 评测追踪，默认关闭
+	Judge.TraceEnabled = os.Getenv("JUDGE_TRACE_ENABLED") == "true"
+	Judge.TraceMaxFieldBytes = getEnvInt("JUDGE_TRACE_MAX_FIELD_BYTES", 4096)
+	Judge.TraceRetentionDays = getEnvInt("JUDGE_TRACE_RETENTION_DAYS", 7)
+	Judge.TraceMaxCount = getEnvInt("JUDGE_TRACE_MAX_COUNT", 50000)
+}
+
+// getEnvInt 读取整数环境变量，未设置或格式错误时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if val, err := strconv.Atoi(value); err == nil {
+			return val
+		}
+	}
+	return defaultValue
 }
@@ -1,10 +1,24 @@
 package config
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
 
 	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
+)
+
+const (
+	LanguageSnapshotKey = "judge:languages"         // 当前启用的语言配置快照
+	LanguageChannel     = "judge:languages:updated" // 语言配置变更通知
 )
 
 // LanguageConfig 语言配置结构体
@@ -22,28 +36,85 @@ type DefaultConfig struct {
 
 // LangConfig 单个语言的配置
 type LangConfig struct {
-	Name     string     `yaml:"name"`     // 语言名称
-	Filename string     `yaml:"filename"` // 源代码文件名
-	Env      []string   `yaml:"env"`      // 环境变量
-	Compile  *CmdConfig `yaml:"compile"`  // 编译配置,解释型语言为nil
-	Run      CmdConfig  `yaml:"run"`      // 运行配置
+	Name     string     `yaml:"name" json:"name"`         // 语言名称
+	Version  string     `yaml:"version" json:"version"`   // 编译器或解释器版本
+	Filename string     `yaml:"filename" json:"filename"` // 源代码文件名
+	Template string     `yaml:"template" json:"template"` // 默认代码模板
+	Env      []string   `yaml:"env" json:"env"`           // 环境变量
+	Compile  *CmdConfig `yaml:"compile" json:"compile"`   // 编译配置,解释型语言为nil
+	Run      CmdConfig  `yaml:"run" json:"run"`           // 运行配置
+
+	Filters []SourceFilter `yaml:"filters" json:"filters,omitempty"` // 编译前的代码检查规则
 }
 
+// SourceFilter 编译前的代码检查规则，代码匹配 Pattern 时拒绝提交
+type SourceFilter struct {
+	Pattern     string `yaml:"pattern" json:"pattern"`          // 正则表达式
+	Message     string `yaml:"message" json:"message"`          // 拒绝时展示给用户的说明
+	ContestOnly bool   `yaml:"contest_only" json:"contestOnly"` // 只对比赛提交生效，如考试中禁止的函数
+	Scope       string `yaml:"scope" json:"scope,omitempty"`    // 匹配范围，见 FilterScope* 常量
+}
+
+// 代码检查规则的匹配范围
+const (
+	FilterScopeSource = "source" // 匹配整份代码，默认
+	FilterScopeCode   = "code"   // 忽略注释和字符串字面量，只匹配代码部分
+)
+
 // CmdConfig 命令配置结构体
 type CmdConfig struct {
-	Command      []string `yaml:"command"`       // 命令及参数
-	CompiledName string   `yaml:"compiled_name"` // 编译后的文件名
-	CPULimit     int64    `yaml:"cpu_limit"`     // CPU时间限制(ns)
-	MemoryLimit  int64    `yaml:"memory_limit"`  // 内存限制(bytes)
-	ProcLimit    int      `yaml:"proc_limit"`    // 进程数限制
-	StdoutMax    int64    `yaml:"stdout_max"`    // 标准输出限制
-	StderrMax    int64    `yaml:"stderr_max"`    // 标准错误限制
-	StackLimit   int64    `yaml:"stack_limit"`   // 栈空间限制
-	LimitAmplify int      `yaml:"limit_amplify"` // 时间和内存限制的放大倍数
+	Command      []string `yaml:"command" json:"command"`            // 命令及参数
+	CompiledName string   `yaml:"compiled_name" json:"compiledName"` // 编译后的文件名
+	CPULimit     int64    `yaml:"cpu_limit" json:"cpuLimit"`         // CPU时间限制(ns)
+	MemoryLimit  int64    `yaml:"memory_limit" json:"memoryLimit"`   // 内存限制(bytes)
+	ProcLimit    int      `yaml:"proc_limit" json:"procLimit"`       // 进程数限制
+	StdoutMax    int64    `yaml:"stdout_max" json:"stdoutMax"`       // 标准输出限制
+	StderrMax    int64    `yaml:"stderr_max" json:"stderrMax"`       // 标准错误限制
+	StackLimit   int64    `yaml:"stack_limit" json:"stackLimit"`     // 栈空间限制
+	LimitAmplify int      `yaml:"limit_amplify" json:"limitAmplify"` // 时间和内存限制的放大倍数
+}
+
+// Validate 检查语言配置是否完整
+func (l *LangConfig) Validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if l.Filename == "" {
+		return fmt.Errorf("filename is required")
+	}
+	if len(l.Run.Command) == 0 {
+		return fmt.Errorf("run command is required")
+	}
+	if l.Compile != nil {
+		if len(l.Compile.Command) == 0 {
+			return fmt.Errorf("compile command is required")
+		}
+		if l.Compile.CompiledName == "" {
+			return fmt.Errorf("compiled name is required")
+		}
+	}
+	for _, filter := range l.Filters {
+		if _, err := regexp.Compile(filter.Pattern); err != nil {
+			return fmt.Errorf("invalid filter pattern %q: %v", filter.Pattern, err)
+		}
+		if filter.Scope != "" && filter.Scope != FilterScopeSource && filter.Scope != FilterScopeCode {
+			return fmt.Errorf("invalid filter scope %q", filter.Scope)
+		}
+	}
+	if l.Run.LimitAmplify < 1 {
+		l.Run.LimitAmplify = 1
+	}
+	return nil
 }
 
+// Language 从 language.yaml 读取的初始配置，运行时请通过 GetLanguage 获取当前配置
 var Language LanguageConfig
 
+var (
+	languageMu sync.RWMutex
+	languages  map[string]LangConfig // 当前启用的语言
+)
+
 // InitLanguageConfig 初始化语言配置
 func InitLanguageConfig() error {
 	// 获取配置文件路径
@@ -59,5 +130,137 @@ func InitLanguageConfig() error {
 	}
 
 	// 解析配置
-	return yaml.Unmarshal(data, &Language)
+	if err := yaml.Unmarshal(data, &Language); err != nil {
+		return err
+	}
+	SetLanguages(Language.Languages)
+	return nil
+}
+
+// GetLanguage 获取启用的语言配置
+func GetLanguage(key string) (LangConfig, bool) {
+	languageMu.RLock()
+	defer languageMu.RUnlock()
+	lang, ok := languages[key]
+	return lang, ok
+}
+
+// ActiveLanguageKeys 返回所有启用的语言，按名称排序
+func ActiveLanguageKeys() []string {
+	languageMu.RLock()
+	defer languageMu.RUnlock()
+	keys := make([]string, 0, len(languages))
+	for key := range languages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetLanguages 替换当前启用的语言配置
+func SetLanguages(langs map[string]LangConfig) {
+	copied := make(map[string]LangConfig, len(langs))
+	for key, lang := range langs {
+		copied[key] = lang
+	}
+	languageMu.Lock()
+	languages = copied
+	languageMu.Unlock()
+}
+
+// SyncLanguagesFromDB 由 API 服务在迁移后调用：语言表为空时用 language.yaml 初始化，
+// 然后加载启用的语言并发布给评测机
+func SyncLanguagesFromDB() error {
+	var count int64
+	if err := DB.Model(&models.Language{}).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		// 语言和第一个历史版本一起写入，否则之后无法回滚到初始配置
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			for key, lang := range Language.Languages {
+				data, err := json.Marshal(lang)
+				if err != nil {
+					return err
+				}
+				record := models.Language{Key: key, Config: string(data), Active: true, Revision: 1}
+				if err := tx.Create(&record).Error; err != nil {
+					return err
+				}
+				if err := tx.Create(&models.LanguageRevision{LanguageKey: key, Revision: 1, Config: string(data)}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("[Config] Seeded %d languages from language.yaml", len(Language.Languages))
+	}
+
+	return ReloadLanguages()
+}
+
+// ReloadLanguages 从数据库加载启用的语言，更新本进程并通知其他进程
+func ReloadLanguages() error {
+	var records []models.Language
+	if err := DB.Where("active = ?", true).Find(&records).Error; err != nil {
+		return err
+	}
+
+	langs := make(map[string]LangConfig, len(records))
+	for _, record := range records {
+		var lang LangConfig
+		if err := json.Unmarshal([]byte(record.Config), &lang); err != nil {
+			log.Printf("[Config] Invalid config for language %s: %v", record.Key, err)
+			continue
+		}
+		langs[record.Key] = lang
+	}
+
+	SetLanguages(langs)
+	return publishLanguages(langs)
+}
+
+// publishLanguages 写入快照并通知所有 API 服务和评测机
+func publishLanguages(langs map[string]LangConfig) error {
+	data, err := json.Marshal(langs)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := RDB.Set(ctx, LanguageSnapshotKey, data, 0).Err(); err != nil {
+		return err
+	}
+	return RDB.Publish(ctx, LanguageChannel, "").Err()
+}
+
+// loadLanguageSnapshot 从 Redis 快照加载语言配置，快照不存在时保持当前配置
+func loadLanguageSnapshot() {
+	data, err := RDB.Get(context.Background(), LanguageSnapshotKey).Bytes()
+	if err != nil {
+		return
+	}
+	var langs map[string]LangConfig
+	if err := json.Unmarshal(data, &langs); err != nil {
+		log.Printf("[Config] Invalid language snapshot: %v", err)
+		return
+	}
+	SetLanguages(langs)
+	log.Printf("[Config] Loaded %d languages from snapshot", len(langs))
+}
+
+// WatchLanguages 加载最新的语言快照并订阅变更，评测机和 API 服务都会调用
+func WatchLanguages() {
+	loadLanguageSnapshot()
+
+	go func() {
+		pubsub := RDB.Subscribe(context.Background(), LanguageChannel)
+		defer pubsub.Close()
+		for range pubsub.Channel() {
+			loadLanguageSnapshot()
+		}
+	}()
 }
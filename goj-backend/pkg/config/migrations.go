@@ -14,6 +14,12 @@ func AutoMigrate() error {
 		&models.Submission{},
 		&models.Contest{},
 		&models.ContestParticipant{},
+		&models.ContestProblem{},
+		&models.ContestClarification{},
+		&models.ContestClarificationRead{},
+		&models.ContestAnnouncement{},
+		&models.ContestAnnouncementRead{},
+		&models.VirtualParticipation{},
 		&models.Discussion{},
 		&models.Comment{},
 		&models.UserProblemStatus{},
@@ -22,6 +28,18 @@ func AutoMigrate() error {
 		&models.DiscussionStar{},
 		&models.RatingHistory{},
 		&models.WebsiteSetting{},
+		&models.PlagiarismReport{},
+		&models.PlagiarismPair{},
+		&models.JudgeTrace{},
+		&models.Language{},
+		&models.LanguageRevision{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.UserGroup{},
+		&models.UserGroupMember{},
+		&models.ContestInvitation{},
+		&models.Team{},
+		&models.TeamMember{},
 	); err != nil {
 		log.Printf("Failed to migrate database: %v", err)
 		return err
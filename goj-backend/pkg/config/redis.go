@@ -196,6 +196,10 @@ func cleanupExpiredCache(ctx context.Context) error {
 		if err != nil {
 			continue
 		}
+		// 未设置过期时间的键（如评测队列）不属于缓存，跳过
+		if ttl == -1 {
+			continue
+		}
 		// 如果键已过期或接近过期，删除它
 		if ttl < 0 || ttl < time.Minute {
 			if err := RDB.Del(ctx, key).Err(); err != nil {
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// WebSocketConfig WebSocket 连接配置
+type WebSocketConfig struct {
+	MaxConns        int // 单个 API 实例的最大连接数，0 表示不限制
+	MaxConnsPerUser int // 每个用户的最大连接数（多标签页），0 表示不限制
+}
+
+var WebSocket WebSocketConfig
+
+// InitWebSocketConfig 初始化 WebSocket 配置
+func InitWebSocketConfig() {
+	WebSocket.MaxConns = 10000
+	if limit := os.Getenv("WS_MAX_CONNS"); limit != "" {
+		if val, err := strconv.Atoi(limit); err == nil {
+			WebSocket.MaxConns = val
+		}
+	}
+
+	WebSocket.MaxConnsPerUser = 5
+	if limit := os.Getenv("WS_MAX_CONNS_PER_USER"); limit != "" {
+		if val, err := strconv.Atoi(limit); err == nil {
+			WebSocket.MaxConnsPerUser = val
+		}
+	}
+
+	log.Printf("[Config] WebSocket limits: max conns %d, per user %d",
+		WebSocket.MaxConns, WebSocket.MaxConnsPerUser)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
 	"log"
 	"math/rand"
@@ -323,9 +324,9 @@ func GetJudgeStatus(c *gin.Context) {
 	rdb := config.RDB
 	ctx := context.Background()
 
-	queueLen, _ := rdb.LLen(ctx, "judge:queue").Result()
+	queueLen, _ := rdb.LLen(ctx, manager.JudgeQueueKey).Result()
 	processingLen, _ := rdb.LLen(ctx, "judge:processing").Result()
-	resultsLen, _ := rdb.LLen(ctx, "judge:results").Result()
+	resultsLen, _ := rdb.LLen(ctx, manager.ResultQueueKey).Result()
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
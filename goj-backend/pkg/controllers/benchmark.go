@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJudgeBenchmarks 获取各评测节点的速度测试结果
+func GetJudgeBenchmarks(c *gin.Context) {
+	results, err := manager.GetBenchmarks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取测试结果失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"nodes":         results,
+			"clusterFactor": manager.ClusterFactor(),
+		},
+	})
+}
+
+// RunJudgeBenchmark 通知所有评测节点重新测试
+func RunJudgeBenchmark(c *gin.Context) {
+	if err := manager.RequestBenchmark(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "发送测试请求失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已通知评测节点重新测试",
+		"data":    nil,
+	})
+}
+
+// SuggestTimeLimit 根据管理员提交的标程用时建议时间限制
+func SuggestTimeLimit(c *gin.Context) {
+	problemID := c.Param("id")
+
+	var problem models.Problem
+	if err := config.DB.Select("id, time_limit, calibration_factor").First(&problem, "id = ?", problemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "题目不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	multiplier, err := strconv.ParseFloat(c.DefaultQuery("multiplier", "3"), 64)
+	if err != nil || multiplier <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的倍数",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 以管理员的通过提交作为标程
+	var stats struct {
+		MaxTime int
+		Count   int
+	}
+	if err := config.DB.Model(&models.Submission{}).
+		Select("COALESCE(MAX(time_used), 0) AS max_time, COUNT(*) AS count").
+		Where("problem_id = ? AND status = ?", problemID, types.StatusAccepted).
+		Where("user_id IN (?)", config.DB.Model(&models.User{}).Select("id").Where("role = ?", "admin")).
+		Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "统计标程用时失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	if stats.Count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "没有管理员的通过提交，无法给出建议",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 向上取整到 100ms
+	suggested := int64(math.Ceil(float64(stats.MaxTime)*multiplier/100) * 100)
+	if suggested < 100 {
+		suggested = 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"suggestedTimeLimit": suggested,
+			"currentTimeLimit":   problem.TimeLimit,
+			"referenceTime":      stats.MaxTime,
+			"referenceCount":     stats.Count,
+			"multiplier":         multiplier,
+			"calibrationFactor":  problem.CalibrationFactor,
+			"clusterFactor":      manager.ClusterFactor(),
+		},
+	})
+}
@@ -3,12 +3,16 @@ package controllers
 import (
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // 获取比赛列表的请求参数
@@ -27,6 +31,98 @@ type AddContestRequest struct {
 	EndTime     string   `json:"endTime" binding:"required"`
 	Role        string   `json:"role" binding:"required"`
 	Problems    []string `json:"problems" binding:"required"`
+	JudgePolicy string   `json:"judgePolicy"` // 为空时使用题目的评测策略
+	RuleType    string   `json:"ruleType"`    // 赛制，创建时为空使用 ICPC，更新时为空表示不修改
+	Mode        string   `json:"mode"`        // 参赛方式，创建时为空为个人赛，更新时为空表示不修改
+
+	RegisterStartTime string `json:"registerStartTime"` // 为空时创建后即可报名
+	RegisterEndTime   string `json:"registerEndTime"`   // 为空时比赛结束前均可报名
+
+	Password       string `json:"password"`       // 私有比赛密码，更新时为空表示不修改
+	RemovePassword bool   `json:"removePassword"` // 更新时清除密码
+	AllowedGroups  []uint `json:"allowedGroups"`  // 允许访问私有比赛的用户组
+
+	FreezeTime string `json:"freezeTime"` // 封榜时间，为空时不封榜
+
+	ProblemConfigs []ContestProblemConfig `json:"problemConfigs"` // 题目设置，未设置的题目使用默认标号
+}
+
+// 比赛题目设置，按题目ID对应
+type ContestProblemConfig struct {
+	ProblemID string `json:"problemId"`
+	Label     string `json:"label"`    // 为空时按顺序使用 A、B、C...
+	Title     string `json:"title"`    // 为空时使用题目标题
+	MaxScore  int    `json:"maxScore"` // 为 0 时使用赛制的默认分值
+	Color     string `json:"color"`    // 气球颜色
+}
+
+// 按题目顺序生成比赛题目记录，返回错误提示
+func buildContestProblems(contestID string, problemIDs []string, configs []ContestProblemConfig) ([]models.ContestProblem, string) {
+	if len(problemIDs) == 0 {
+		return nil, "请选择比赛题目"
+	}
+
+	configMap := make(map[string]ContestProblemConfig, len(configs))
+	for _, setting := range configs {
+		configMap[setting.ProblemID] = setting
+	}
+
+	problems := make([]models.ContestProblem, 0, len(problemIDs))
+	seenProblems := make(map[string]bool)
+	seenLabels := make(map[string]bool)
+	for i, problemID := range problemIDs {
+		if seenProblems[problemID] {
+			return nil, "题目 " + problemID + " 重复"
+		}
+		seenProblems[problemID] = true
+
+		setting := configMap[problemID]
+		label := strings.ToUpper(strings.TrimSpace(setting.Label))
+		if label == "" {
+			label = models.ProblemLabel(i)
+		}
+		if len(label) > 10 || seenLabels[label] {
+			return nil, "题目标号 " + label + " 无效或重复"
+		}
+		seenLabels[label] = true
+
+		if setting.MaxScore < 0 || len(setting.Color) > 20 || len(setting.Title) > 255 {
+			return nil, "题目 " + problemID + " 的设置无效"
+		}
+
+		problems = append(problems, models.ContestProblem{
+			ContestID: contestID,
+			ProblemID: problemID,
+			Label:     label,
+			Position:  i,
+			Title:     strings.TrimSpace(setting.Title),
+			MaxScore:  setting.MaxScore,
+			Color:     setting.Color,
+		})
+	}
+	return problems, ""
+}
+
+// 计算比赛密码的哈希，为空时返回空字符串
+func hashContestPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed), err
+}
+
+// 解析可选的时间，为空时返回 nil
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02T15:04:05Z", value)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
 }
 
 // GetContests 获取比赛列表
@@ -45,9 +141,14 @@ func GetContests(c *gin.Context) {
 
 	query := config.DB.Model(&models.Contest{})
 
-	// 非管理员只能看到公开比赛
+	// 非管理员只能看到公开比赛，以及已加入或所在用户组被允许的私有比赛
 	if role != "admin" {
-		query = query.Where("role = ?", "public")
+		userID := c.GetUint("userID")
+		query = query.Where(`role = ? OR id IN (
+			SELECT contest_id FROM contest_participants WHERE user_id = ? AND deleted_at IS NULL
+		) OR EXISTS (
+			SELECT 1 FROM user_group_members WHERE user_id = ? AND FIND_IN_SET(group_id, contests.allowed_groups)
+		)`, "public", userID, userID)
 	}
 
 	// 搜索条件
@@ -116,6 +217,33 @@ func CreateContest(c *gin.Context) {
 		return
 	}
 
+	if !types.IsValidPolicy(req.JudgePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的评测策略",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !scoreboard.IsValidRule(req.RuleType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的赛制",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !models.IsValidContestMode(req.Mode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的参赛方式",
+			"data":    nil,
+		})
+		return
+	}
+
 	// 开启事务
 	tx := config.DB.Begin()
 	if tx.Error != nil {
@@ -192,6 +320,60 @@ func CreateContest(c *gin.Context) {
 	// 将时间转换为UTC
 	endTime = endTime.UTC()
 
+	registerStart, err := parseOptionalTime(req.RegisterStartTime)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "报名开始时间格式错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+	registerEnd, err := parseOptionalTime(req.RegisterEndTime)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "报名截止时间格式错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	freezeTime, err := parseOptionalTime(req.FreezeTime)
+	if err != nil || (freezeTime != nil && (freezeTime.Before(startTime) || freezeTime.After(endTime))) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "封榜时间需在比赛时间内",
+			"data":    nil,
+		})
+		return
+	}
+
+	password, err := hashContestPassword(req.Password)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "处理比赛密码失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	contestProblems, msg := buildContestProblems(contestID, req.Problems, req.ProblemConfigs)
+	if msg != "" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": msg,
+			"data":    nil,
+		})
+		return
+	}
+
 	// 创建比赛记录
 	contest := models.Contest{
 		ID:          contestID,
@@ -201,6 +383,15 @@ func CreateContest(c *gin.Context) {
 		EndTime:     endTime,
 		Role:        req.Role,
 		Problems:    strings.Join(req.Problems, ","),
+		JudgePolicy: req.JudgePolicy,
+		RuleType:    req.RuleType,
+		Mode:        req.Mode,
+
+		RegisterStart: registerStart,
+		RegisterEnd:   registerEnd,
+		Password:      password,
+		AllowedGroups: joinGroupIDs(req.AllowedGroups),
+		FreezeTime:    freezeTime,
 	}
 
 	if err := tx.Create(&contest).Error; err != nil {
@@ -213,6 +404,16 @@ func CreateContest(c *gin.Context) {
 		return
 	}
 
+	if err := tx.Create(&contestProblems).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存比赛题目失败",
+			"data":    nil,
+		})
+		return
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -245,6 +446,47 @@ func UpdateContest(c *gin.Context) {
 		return
 	}
 
+	if !types.IsValidPolicy(req.JudgePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的评测策略",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !scoreboard.IsValidRule(req.RuleType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的赛制",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !models.IsValidContestMode(req.Mode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的参赛方式",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 已有报名时不能切换个人赛和团队赛，参赛记录的结构不同
+	if req.Mode != "" {
+		var current models.Contest
+		if err := config.DB.Select("id, mode, participant_count").First(&current, "id = ?", contestID).Error; err == nil &&
+			current.Mode != req.Mode && current.ParticipantCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "已有参赛者报名，不能修改参赛方式",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	// 解析时间
 	startTime, err := time.Parse("2006-01-02T15:04:05Z", req.StartTime)
 	if err != nil {
@@ -272,17 +514,90 @@ func UpdateContest(c *gin.Context) {
 	// 将时间转换为UTC
 	endTime = endTime.UTC()
 
-	// 更新比赛
-	contest := models.Contest{
-		Title:       req.Title,
-		Description: req.Description,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Role:        req.Role,
-		Problems:    strings.Join(req.Problems, ","),
+	registerStart, err := parseOptionalTime(req.RegisterStartTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "报名开始时间格式错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+	registerEnd, err := parseOptionalTime(req.RegisterEndTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "报名截止时间格式错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	freezeTime, err := parseOptionalTime(req.FreezeTime)
+	if err != nil || (freezeTime != nil && (freezeTime.Before(startTime) || freezeTime.After(endTime))) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "封榜时间需在比赛时间内",
+			"data":    nil,
+		})
+		return
 	}
 
-	if err := config.DB.Model(&models.Contest{}).Where("id = ?", contestID).Updates(&contest).Error; err != nil {
+	// 更新比赛，评测策略、报名时间、用户组和封榜时间允许清空
+	fields := map[string]interface{}{
+		"title":          req.Title,
+		"description":    req.Description,
+		"start_time":     startTime,
+		"end_time":       endTime,
+		"role":           req.Role,
+		"problems":       strings.Join(req.Problems, ","),
+		"judge_policy":   req.JudgePolicy,
+		"freeze_time":    freezeTime,
+		"register_start": registerStart,
+		"register_end":   registerEnd,
+		"allowed_groups": joinGroupIDs(req.AllowedGroups),
+	}
+	if req.RuleType != "" {
+		fields["rule_type"] = req.RuleType
+	}
+	if req.Mode != "" {
+		fields["mode"] = req.Mode
+	}
+	if req.RemovePassword {
+		fields["password"] = ""
+	} else if req.Password != "" {
+		password, err := hashContestPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "处理比赛密码失败",
+				"data":    nil,
+			})
+			return
+		}
+		fields["password"] = password
+	}
+
+	contestProblems, msg := buildContestProblems(contestID, req.Problems, req.ProblemConfigs)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": msg,
+			"data":    nil,
+		})
+		return
+	}
+
+	// 题目设置整体替换
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Contest{}).Where("id = ?", contestID).Updates(fields).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("contest_id = ?", contestID).Delete(&models.ContestProblem{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&contestProblems).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "更新比赛失败",
@@ -291,6 +606,9 @@ func UpdateContest(c *gin.Context) {
 		return
 	}
 
+	// 赛制、封榜时间和题目分值会影响榜单
+	ClearContestRankCache(contestID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "更新成功",
@@ -335,6 +653,79 @@ func DeleteContest(c *gin.Context) {
 		return
 	}
 
+	// 删除邀请码
+	if err := tx.Delete(&models.ContestInvitation{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除邀请码失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 删除比赛题目设置
+	if err := tx.Delete(&models.ContestProblem{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除比赛题目失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 删除比赛提问
+	if err := tx.Delete(&models.ContestClarification{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除比赛提问失败",
+			"data":    nil,
+		})
+		return
+	}
+	if err := tx.Delete(&models.ContestClarificationRead{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除比赛提问失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 删除比赛公告
+	if err := tx.Delete(&models.ContestAnnouncement{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除比赛公告失败",
+			"data":    nil,
+		})
+		return
+	}
+	if err := tx.Delete(&models.ContestAnnouncementRead{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除比赛公告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 删除虚拟参赛记录
+	if err := tx.Delete(&models.VirtualParticipation{}, "contest_id = ?", contestID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除虚拟参赛记录失败",
+			"data":    nil,
+		})
+		return
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
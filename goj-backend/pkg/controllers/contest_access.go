@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 邀请码字符集，去掉了容易混淆的字符
+const invitationCharset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var errInvalidInviteCode = errors.New("invalid invite code")
+
+// 将用户组ID拼接为逗号分隔的字符串
+func joinGroupIDs(ids []uint) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+// canAccessContest 私有比赛只对管理员、参赛者和白名单用户组成员开放
+func canAccessContest(c *gin.Context, contest *models.Contest) bool {
+	return contest.CanAccess(config.DB, c.GetUint("userID"), c.GetString("role"))
+}
+
+// requireContestAccess 获取比赛并检查访问权限，失败时直接写入响应
+func requireContestAccess(c *gin.Context, contestID string) (*models.Contest, bool) {
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return nil, false
+	}
+
+	if !canAccessContest(c, &contest) {
+		// 返回加入比赛所需的信息，便于前端提示输入密码或邀请码
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "无权访问该私有比赛",
+			"data": gin.H{
+				"id":          contest.ID,
+				"title":       contest.Title,
+				"private":     true,
+				"hasPassword": contest.Password != "",
+			},
+		})
+		return nil, false
+	}
+	return &contest, true
+}
+
+// JoinContest 通过密码或邀请码加入私有比赛
+func JoinContest(c *gin.Context) {
+	contestID := c.Param("id")
+	userID := c.GetUint("userID")
+
+	var req struct {
+		Password   string `json:"password"`
+		InviteCode string `json:"inviteCode"`
+		TeamID     uint   `json:"teamId"` // 团队赛中由队长带领队伍加入
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Password == "" && req.InviteCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请输入密码或邀请码",
+			"data":    nil,
+		})
+		return
+	}
+
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	if getContestParticipant(contestID, userID) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "已报名该比赛",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !contest.RegistrationOpen(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不在报名时间内",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 没有邀请码时校验密码
+	if req.InviteCode == "" &&
+		(contest.Password == "" || bcrypt.CompareHashAndPassword([]byte(contest.Password), []byte(req.Password)) != nil) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "比赛密码错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	var team *models.Team
+	if contest.TeamMode() {
+		var ok bool
+		if team, ok = requireContestTeam(c, req.TeamID); !ok {
+			return
+		}
+	}
+
+	var registered []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if req.InviteCode != "" {
+			// 条件更新保证邀请码只能被使用一次
+			now := time.Now()
+			result := tx.Model(&models.ContestInvitation{}).
+				Where("contest_id = ? AND code = ? AND used_by IS NULL", contestID, strings.ToUpper(strings.TrimSpace(req.InviteCode))).
+				Updates(map[string]interface{}{
+					"used_by": userID,
+					"used_at": &now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errInvalidInviteCode
+			}
+		}
+		if team != nil {
+			var err error
+			if registered, err = addContestTeam(tx, contestID, team); err != nil {
+				return err
+			}
+		} else if _, err := addContestParticipant(tx, contestID, userID); err != nil {
+			return err
+		}
+		return refreshParticipantCount(tx, contestID)
+	})
+	if errors.Is(err, errTeamMemberRegistered) {
+		respondTeamMemberRegistered(c, registered)
+		return
+	}
+	if errors.Is(err, errInvalidInviteCode) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "邀请码无效或已被使用",
+			"data":    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "加入比赛失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	ClearContestRankCache(contestID)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "加入比赛成功",
+		"data":    nil,
+	})
+}
+
+// 生成一个邀请码
+func generateInvitationCode(length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(invitationCharset)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = invitationCharset[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// CreateContestInvitations 批量生成比赛邀请码
+func CreateContestInvitations(c *gin.Context) {
+	contestID := c.Param("contestId")
+
+	var req struct {
+		Count int `json:"count" binding:"required,min=1,max=1000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	var contest models.Contest
+	if err := config.DB.Select("id").First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	invitations := make([]models.ContestInvitation, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		code, err := generateInvitationCode(10)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "生成邀请码失败",
+				"data":    nil,
+			})
+			return
+		}
+		invitations = append(invitations, models.ContestInvitation{
+			ContestID: contestID,
+			Code:      code,
+		})
+	}
+
+	if err := config.DB.Create(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存邀请码失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	codes := make([]string, 0, len(invitations))
+	for _, invitation := range invitations {
+		codes = append(codes, invitation.Code)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "生成成功",
+		"data": gin.H{
+			"codes": codes,
+		},
+	})
+}
+
+// GetContestInvitations 获取比赛的邀请码及使用情况
+func GetContestInvitations(c *gin.Context) {
+	var invitations []struct {
+		models.ContestInvitation
+		Username string `json:"username"`
+	}
+	if err := config.DB.Table("contest_invitations").
+		Select("contest_invitations.*, users.username").
+		Joins("LEFT JOIN users ON users.id = contest_invitations.used_by").
+		Where("contest_invitations.contest_id = ?", c.Param("contestId")).
+		Order("contest_invitations.id").
+		Scan(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取邀请码失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	used := 0
+	for _, invitation := range invitations {
+		if invitation.UsedBy != nil {
+			used++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"invitations": invitations,
+			"total":       len(invitations),
+			"used":        used,
+		},
+	})
+}
+
+// DeleteContestInvitation 删除未使用的邀请码
+func DeleteContestInvitation(c *gin.Context) {
+	result := config.DB.
+		Where("id = ? AND contest_id = ? AND used_by IS NULL", c.Param("inviteId"), c.Param("contestId")).
+		Delete(&models.ContestInvitation{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除邀请码失败",
+			"data":    nil,
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "邀请码不存在或已被使用",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 推送给参赛者的公告事件
+const (
+	announcementCreated = "created"
+	announcementUpdated = "updated"
+	announcementDeleted = "deleted"
+)
+
+// AnnouncementInfo 公告列表项
+type AnnouncementInfo struct {
+	models.ContestAnnouncement
+	ProblemLabel string `json:"problemLabel"`
+	Read         bool   `json:"read"`
+}
+
+// ContestAnnouncementRequest 发布或修改公告的请求
+type ContestAnnouncementRequest struct {
+	ProblemID string `json:"problemId"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+}
+
+// 用户最后一次查看比赛公告的时间，从未查看时为零值
+func announcementReadAt(contestID string, userID uint) time.Time {
+	var read models.ContestAnnouncementRead
+	if err := config.DB.Where("contest_id = ? AND user_id = ?", contestID, userID).
+		First(&read).Error; err != nil {
+		return time.Time{}
+	}
+	return read.ReadAt
+}
+
+// 获取比赛公告并标记是否已读，按发布时间倒序
+func listContestAnnouncements(contest *models.Contest, userID uint, problemID string, unreadOnly bool) ([]AnnouncementInfo, error) {
+	readAt := announcementReadAt(contest.ID, userID)
+	query := config.DB.Where("contest_id = ?", contest.ID)
+	if problemID != "" {
+		query = query.Where("problem_id = ?", problemID)
+	}
+	if unreadOnly {
+		query = query.Where("updated_at > ?", readAt)
+	}
+
+	var announcements []models.ContestAnnouncement
+	if err := query.Order("created_at DESC").Find(&announcements).Error; err != nil {
+		return nil, err
+	}
+
+	labels := contestProblemLabels(contest)
+	list := make([]AnnouncementInfo, 0, len(announcements))
+	for _, announcement := range announcements {
+		list = append(list, AnnouncementInfo{
+			ContestAnnouncement: announcement,
+			ProblemLabel:        labels[announcement.ProblemID],
+			Read:                !announcement.UpdatedAt.After(readAt),
+		})
+	}
+	return list, nil
+}
+
+// 推送公告给正在浏览比赛页面的用户，不在线的参赛者重连后通过未读列表获取
+func notifyAnnouncement(contest *models.Contest, announcement *models.ContestAnnouncement, event string) {
+	ws := handler.GetWebSocketManager()
+	if ws == nil {
+		return
+	}
+
+	ws.BroadcastToTopic(handler.TopicContestAnnouncements(contest.ID), handler.WebSocketMessage{
+		Type: "contest_announcement",
+		Data: gin.H{
+			"event":        event,
+			"contestId":    contest.ID,
+			"contestTitle": contest.Title,
+			"announcement": AnnouncementInfo{
+				ContestAnnouncement: *announcement,
+				ProblemLabel:        contestProblemLabels(contest)[announcement.ProblemID],
+			},
+		},
+	})
+}
+
+// 校验公告内容，返回错误信息
+func validateAnnouncement(contest *models.Contest, req *ContestAnnouncementRequest) string {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || utf8.RuneCountInString(req.Title) > 100 {
+		return "公告标题不能为空且不超过 100 个字符"
+	}
+	if req.ProblemID != "" {
+		if _, ok := contestProblemLabels(contest)[req.ProblemID]; !ok {
+			return "题目不在比赛中"
+		}
+	}
+	return ""
+}
+
+// GetContestAnnouncements 获取比赛公告，可按题目筛选
+func GetContestAnnouncements(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	announcements, err := listContestAnnouncements(contest, c.GetUint("userID"), c.Query("problemId"), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取公告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	unread := 0
+	for _, announcement := range announcements {
+		if !announcement.Read {
+			unread++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"announcements": announcements,
+			"unread":        unread,
+		},
+	})
+}
+
+// GetUnreadContestAnnouncements 获取上次查看后发布或修改的公告
+func GetUnreadContestAnnouncements(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	announcements, err := listContestAnnouncements(contest, c.GetUint("userID"), "", true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取公告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"announcements": announcements,
+			"unread":        len(announcements),
+		},
+	})
+}
+
+// MarkContestAnnouncementsRead 将比赛公告标记为已读
+func MarkContestAnnouncementsRead(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+
+	if err := config.DB.Where("contest_id = ? AND user_id = ?", contest.ID, userID).
+		Assign(map[string]interface{}{"read_at": time.Now()}).
+		FirstOrCreate(&models.ContestAnnouncementRead{ContestID: contest.ID, UserID: userID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "标记已读失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已标记为已读",
+		"data":    nil,
+	})
+}
+
+// CreateContestAnnouncement 发布比赛公告并推送给参赛者
+func CreateContestAnnouncement(c *gin.Context) {
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", c.Param("contestId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req ContestAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if message := validateAnnouncement(&contest, &req); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	announcement := models.ContestAnnouncement{
+		ContestID: contest.ID,
+		ProblemID: req.ProblemID,
+		Title:     req.Title,
+		Content:   req.Content,
+		CreatedBy: c.GetUint("userID"),
+	}
+	if err := config.DB.Create(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "发布公告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	notifyAnnouncement(&contest, &announcement, announcementCreated)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "发布成功",
+		"data":    announcement,
+	})
+}
+
+// UpdateContestAnnouncement 修改比赛公告，修改后对参赛者重新显示为未读
+func UpdateContestAnnouncement(c *gin.Context) {
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", c.Param("contestId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	var announcement models.ContestAnnouncement
+	if err := config.DB.Where("id = ? AND contest_id = ?", c.Param("announcementId"), contest.ID).
+		First(&announcement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "公告不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req ContestAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if message := validateAnnouncement(&contest, &req); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	announcement.ProblemID = req.ProblemID
+	announcement.Title = req.Title
+	announcement.Content = req.Content
+	if err := config.DB.Save(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "修改公告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	notifyAnnouncement(&contest, &announcement, announcementUpdated)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "修改成功",
+		"data":    announcement,
+	})
+}
+
+// DeleteContestAnnouncement 删除比赛公告
+func DeleteContestAnnouncement(c *gin.Context) {
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", c.Param("contestId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	var announcement models.ContestAnnouncement
+	if err := config.DB.Where("id = ? AND contest_id = ?", c.Param("announcementId"), contest.ID).
+		First(&announcement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "公告不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := config.DB.Delete(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除公告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	notifyAnnouncement(&contest, &announcement, announcementDeleted)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,498 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	maxClarificationLength = 2000 // 提问和回答的最大长度（字符）
+
+	// 推送给客户端的提问事件
+	clarificationAsked    = "asked"
+	clarificationAnswered = "answered"
+)
+
+// ClarificationInfo 提问列表项
+type ClarificationInfo struct {
+	models.ContestClarification
+	Username     string `json:"username"`
+	ProblemLabel string `json:"problemLabel"`
+	Mine         bool   `json:"mine"`
+}
+
+// 用户可见的提问：管理员可以看到全部，选手只能看到自己的提问和已公开的回答
+func visibleClarifications(contestID string, userID uint, admin bool) *gorm.DB {
+	query := config.DB.Model(&models.ContestClarification{}).Where("contest_id = ?", contestID)
+	if !admin {
+		query = query.Where("user_id = ? OR (public = ? AND answered_at IS NOT NULL)", userID, true)
+	}
+	return query
+}
+
+// 用户最后一次查看比赛提问的时间，从未查看时为零值
+func clarificationReadAt(contestID string, userID uint) time.Time {
+	var read models.ContestClarificationRead
+	if err := config.DB.Where("contest_id = ? AND user_id = ?", contestID, userID).
+		First(&read).Error; err != nil {
+		return time.Time{}
+	}
+	return read.ReadAt
+}
+
+// 未读数：管理员为上次查看后的新提问，选手为上次查看后收到的回答
+func clarificationUnread(contestID string, userID uint, admin bool) int64 {
+	readAt := clarificationReadAt(contestID, userID)
+	query := visibleClarifications(contestID, userID, admin)
+	if admin {
+		query = query.Where("created_at > ?", readAt)
+	} else {
+		query = query.Where("answered_at > ?", readAt)
+	}
+	var count int64
+	query.Count(&count)
+	return count
+}
+
+// 比赛题目ID到标号的映射
+func contestProblemLabels(contest *models.Contest) map[string]string {
+	labels := make(map[string]string)
+	problems, err := models.LoadContestProblems(config.DB, contest)
+	if err != nil {
+		return labels
+	}
+	for _, problem := range problems {
+		labels[problem.ProblemID] = problem.Label
+	}
+	return labels
+}
+
+// 通过 WebSocket 通知提问的变化，只推送ID，客户端收到后重新获取列表
+func notifyClarification(clarification *models.ContestClarification, event string) {
+	ws := handler.GetWebSocketManager()
+	if ws == nil {
+		return
+	}
+	msg := handler.WebSocketMessage{
+		Type: "contest_clarification",
+		Data: gin.H{
+			"event":           event,
+			"contestId":       clarification.ContestID,
+			"clarificationId": clarification.ID,
+			"problemId":       clarification.ProblemID,
+			"public":          clarification.Public,
+		},
+	}
+
+	ws.BroadcastToTopic(handler.TopicContestJudges(clarification.ContestID), msg)
+	if event != clarificationAnswered {
+		return
+	}
+	if clarification.Public {
+		ws.BroadcastToTopic(handler.TopicContestClarifications(clarification.ContestID), msg)
+	}
+	// 提问者可能没有订阅比赛主题，单独通知
+	ws.SendToUser(clarification.UserID, msg)
+}
+
+// GetContestClarifications 获取比赛中可见的提问及回答
+func GetContestClarifications(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+	admin := c.GetString("role") == "admin"
+
+	query := visibleClarifications(contest.ID, userID, admin)
+	if problemID := c.Query("problemId"); problemID != "" {
+		query = query.Where("problem_id = ?", problemID)
+	}
+	var clarifications []models.ContestClarification
+	if err := query.Order("created_at DESC").Find(&clarifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取提问失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 只有管理员能看到其他选手的用户名
+	userIDs := make([]uint, 0, len(clarifications))
+	for _, clarification := range clarifications {
+		if admin || clarification.UserID == userID {
+			userIDs = append(userIDs, clarification.UserID)
+		}
+	}
+	usernames := make(map[uint]string)
+	if len(userIDs) > 0 {
+		var users []models.User
+		config.DB.Select("id, username").Where("id IN ?", userIDs).Find(&users)
+		for _, user := range users {
+			usernames[user.ID] = user.Username
+		}
+	}
+
+	labels := contestProblemLabels(contest)
+	list := make([]ClarificationInfo, 0, len(clarifications))
+	for _, clarification := range clarifications {
+		info := ClarificationInfo{
+			ContestClarification: clarification,
+			Username:             usernames[clarification.UserID],
+			ProblemLabel:         labels[clarification.ProblemID],
+			Mine:                 clarification.UserID == userID,
+		}
+		if !admin && !info.Mine {
+			info.UserID = 0
+			info.AnsweredBy = nil
+		}
+		list = append(list, info)
+	}
+
+	data := gin.H{
+		"clarifications": list,
+		"unread":         clarificationUnread(contest.ID, userID, admin),
+	}
+	if admin {
+		data["presetAnswers"] = models.ClarificationPresetAnswers
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    data,
+	})
+}
+
+// GetContestClarificationUnread 获取比赛提问的未读数
+func GetContestClarificationUnread(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	admin := c.GetString("role") == "admin"
+
+	data := gin.H{
+		"unread": clarificationUnread(contest.ID, c.GetUint("userID"), admin),
+	}
+	if admin {
+		var pending int64
+		config.DB.Model(&models.ContestClarification{}).
+			Where("contest_id = ? AND answered_at IS NULL", contest.ID).
+			Count(&pending)
+		data["pending"] = pending
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    data,
+	})
+}
+
+// MarkContestClarificationsRead 将比赛提问标记为已读
+func MarkContestClarificationsRead(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+
+	if err := config.DB.Where("contest_id = ? AND user_id = ?", contest.ID, userID).
+		Assign(map[string]interface{}{"read_at": time.Now()}).
+		FirstOrCreate(&models.ContestClarificationRead{ContestID: contest.ID, UserID: userID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "标记已读失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已标记为已读",
+		"data":    nil,
+	})
+}
+
+// CreateContestClarification 参赛者在比赛进行中提问
+func CreateContestClarification(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+
+	var req struct {
+		ProblemID string `json:"problemId"`
+		Question  string `json:"question"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" || utf8.RuneCountInString(req.Question) > maxClarificationLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("提问内容不能为空且不超过 %d 个字符", maxClarificationLength),
+			"data":    nil,
+		})
+		return
+	}
+
+	now := time.Now()
+	if now.Before(contest.StartTime) || now.After(contest.EndTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "只能在比赛进行中提问",
+			"data":    nil,
+		})
+		return
+	}
+
+	if c.GetString("role") != "admin" && getContestParticipant(contest.ID, userID) == nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "未报名该比赛",
+			"data":    nil,
+		})
+		return
+	}
+
+	if req.ProblemID != "" {
+		if _, ok := contestProblemLabels(contest)[req.ProblemID]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "题目不在比赛中",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
+	clarification := models.ContestClarification{
+		ContestID: contest.ID,
+		ProblemID: req.ProblemID,
+		UserID:    userID,
+		Question:  req.Question,
+	}
+	if err := config.DB.Create(&clarification).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "提问失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	notifyClarification(&clarification, clarificationAsked)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "提问成功",
+		"data":    clarification,
+	})
+}
+
+// AnswerContestClarification 裁判回答提问，可以只回复提问者或公开给全部参赛者，重复回答时覆盖
+func AnswerContestClarification(c *gin.Context) {
+	var req struct {
+		Answer string `json:"answer"`
+		Public bool   `json:"public"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	req.Answer = strings.TrimSpace(req.Answer)
+	if req.Answer == "" || utf8.RuneCountInString(req.Answer) > maxClarificationLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("回答内容不能为空且不超过 %d 个字符", maxClarificationLength),
+			"data":    nil,
+		})
+		return
+	}
+
+	var clarification models.ContestClarification
+	if err := config.DB.Where("id = ? AND contest_id = ?", c.Param("clarificationId"), c.Param("contestId")).
+		First(&clarification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "提问不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	now := time.Now()
+	answeredBy := c.GetUint("userID")
+	if err := config.DB.Model(&clarification).Updates(map[string]interface{}{
+		"answer":      req.Answer,
+		"public":      req.Public,
+		"answered_by": answeredBy,
+		"answered_at": &now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "回答失败",
+			"data":    nil,
+		})
+		return
+	}
+	clarification.Answer = req.Answer
+	clarification.Public = req.Public
+	clarification.AnsweredBy = &answeredBy
+	clarification.AnsweredAt = &now
+
+	notifyClarification(&clarification, clarificationAnswered)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "回答成功",
+		"data":    clarification,
+	})
+}
+
+// ExportContestClarifications 导出比赛的全部提问记录
+func ExportContestClarifications(c *gin.Context) {
+	contestID := c.Param("contestId")
+
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+		})
+		return
+	}
+
+	var clarifications []models.ContestClarification
+	if err := config.DB.Where("contest_id = ?", contestID).Order("created_at").Find(&clarifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取提问失败",
+		})
+		return
+	}
+
+	// 提问者和回答者的用户名
+	userIDs := make([]uint, 0, len(clarifications)*2)
+	for _, clarification := range clarifications {
+		userIDs = append(userIDs, clarification.UserID)
+		if clarification.AnsweredBy != nil {
+			userIDs = append(userIDs, *clarification.AnsweredBy)
+		}
+	}
+	usernames := make(map[uint]string)
+	if len(userIDs) > 0 {
+		var users []models.User
+		config.DB.Select("id, username").Where("id IN ?", userIDs).Find(&users)
+		for _, user := range users {
+			usernames[user.ID] = user.Username
+		}
+	}
+	labels := contestProblemLabels(&contest)
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Sheet1"
+	headers := []string{"编号", "提问者", "题目", "提问时间", "提问内容", "回答", "回答者", "回答时间", "公开"}
+	for i, header := range headers {
+		f.SetCellValue(sheetName, fmt.Sprintf("%c1", 'A'+i), header)
+	}
+
+	for i, clarification := range clarifications {
+		row := i + 2
+		problem := "全部"
+		if clarification.ProblemID != "" {
+			problem = labels[clarification.ProblemID]
+			if problem == "" {
+				problem = clarification.ProblemID
+			}
+		}
+		answeredBy, answeredAt, public := "", "", "否"
+		if clarification.AnsweredBy != nil {
+			answeredBy = usernames[*clarification.AnsweredBy]
+		}
+		if clarification.AnsweredAt != nil {
+			answeredAt = clarification.AnsweredAt.Format("2006-01-02 15:04:05")
+		}
+		if clarification.Public {
+			public = "是"
+		}
+
+		values := []interface{}{
+			clarification.ID,
+			usernames[clarification.UserID],
+			problem,
+			clarification.CreatedAt.Format("2006-01-02 15:04:05"),
+			clarification.Question,
+			clarification.Answer,
+			answeredBy,
+			answeredAt,
+			public,
+		}
+		for j, value := range values {
+			f.SetCellValue(sheetName, fmt.Sprintf("%c%d", 'A'+j, row), value)
+		}
+	}
+
+	// 调整列宽，提问和回答内容较长
+	f.SetColWidth(sheetName, "A", "I", 15)
+	f.SetColWidth(sheetName, "E", "F", 50)
+
+	// 生成文件名
+	timestamp := time.Now().Format("20060102150405")
+	filename := fmt.Sprintf("contest_%s_clarifications_%s.xlsx", contestID, timestamp)
+	filepath := fmt.Sprintf("temp/%s", filename)
+
+	// 确保temp目录存在
+	if err := os.MkdirAll("temp", 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建临时目录失败",
+		})
+		return
+	}
+
+	if err := f.SaveAs(filepath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成Excel文件失败",
+		})
+		return
+	}
+
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Type", "application/octet-stream")
+	c.File(filepath)
+
+	// 异步删除临时文件
+	go func() {
+		time.Sleep(time.Second * 5)
+		os.Remove(filepath)
+	}()
+}
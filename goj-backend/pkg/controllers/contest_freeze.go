@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/events"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// buildRankBoard 按比赛赛制计算榜单，已报名但没有提交的参赛者也在榜单上。
+// frozenAt 不为空时，之后提交的结果不公布，只记为待定次数
+func buildRankBoard(contest *models.Contest, submissions []ContestSubmission, frozenAt *time.Time) *scoreboard.Board {
+	board := scoreboard.NewBoard(contest, frozenAt)
+	seedRankWithParticipants(board, contest.ID)
+	for i := range submissions {
+		board.Apply(&submissions[i])
+	}
+	return board
+}
+
+// 非管理员在封榜期间看到的是封榜时刻的榜单，返回封榜时间。
+// 赛制在比赛结束前隐藏结果时，视为从比赛开始即封榜
+func viewerFreezeTime(c *gin.Context, contest *models.Contest) *time.Time {
+	if c.GetString("role") == "admin" {
+		return nil
+	}
+	now := time.Now()
+	if scoreboard.ResultsHidden(contest, now) {
+		return &contest.StartTime
+	}
+	if !contest.Frozen(now) {
+		return nil
+	}
+	return contest.FreezeTime
+}
+
+// 是否对当前用户隐藏比赛提交的评测结果：赛制在比赛结束前隐藏结果时隐藏所有提交，
+// 封榜期间隐藏其他用户在封榜后的提交。虚拟提交在比赛结束后才产生，不计入正式榜单，
+// 按实际提交时间与封榜时间比较没有意义，不隐藏
+func contestResultHidden(c *gin.Context, contest *models.Contest, sub *models.Submission) bool {
+	if c.GetString("role") == "admin" || sub.VirtualID != 0 {
+		return false
+	}
+	now := time.Now()
+	if scoreboard.ResultsHidden(contest, now) {
+		return true
+	}
+	return contest.Frozen(now) && sub.UserID != c.GetUint("userID") && !sub.SubmitTime.Before(*contest.FreezeTime)
+}
+
+// 隐藏提交的评测结果，只保留代码等提交信息
+func maskSubmissionResult(sub *models.Submission) {
+	sub.Status = scoreboard.StatusHidden
+	sub.TimeUsed = 0
+	sub.MemoryUsed = 0
+	sub.ErrorInfo = ""
+	sub.TestcasesStatus = nil
+	sub.TestcasesInfo = nil
+	sub.TestCaseResults = ""
+}
+
+// loadContestSubmissions 按评测完成顺序获取比赛的有效提交，比赛还没有提交时返回空列表
+func loadContestSubmissions(contestID string) ([]ContestSubmission, error) {
+	var contestStatus struct {
+		SubmissionIDs json.RawMessage `gorm:"column:submission_ids"`
+	}
+	if err := config.DB.Table("contest_submission_status").
+		Where("contest_id = ?", contestID).
+		First(&contestStatus).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var submissionIDs []uint
+	if err := json.Unmarshal(contestStatus.SubmissionIDs, &submissionIDs); err != nil {
+		return nil, err
+	}
+	if len(submissionIDs) == 0 {
+		return nil, nil
+	}
+
+	var submissions []ContestSubmission
+	err := config.DB.Model(&models.Submission{}).
+		Select(`
+			submissions.*,
+			users.username,
+			users.avatar,
+			users.bio
+		`).
+		Joins("LEFT JOIN users ON users.id = submissions.user_id").
+		Where("submissions.id IN ?", submissionIDs).
+		Order(fmt.Sprintf("FIELD(submissions.id, %s)", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(submissionIDs)), ","), "[]"))).
+		Find(&submissions).Error
+	return submissions, err
+}
+
+// 排名列表中参赛者的名次
+func rankOf(ranks []*ContestRankData, entrant uint) int {
+	for i, rank := range ranks {
+		if rank.Entrant() == entrant {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// UnfreezeContest 解除封榜，公布最终结果
+func UnfreezeContest(c *gin.Context) {
+	contestID := c.Param("contestId")
+
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	if contest.FreezeTime == nil || contest.Unfrozen {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "比赛未封榜",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := config.DB.Model(&contest).Update("unfrozen", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "解除封榜失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	ClearContestRankCache(contestID)
+	handler.PublishScoreboardUpdate(contest.ID)
+	events.Publish(events.ContestUnfrozen, events.ContestData{
+		ContestID: contest.ID,
+		Title:     contest.Title,
+		StartTime: contest.StartTime,
+		EndTime:   contest.EndTime,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已解除封榜",
+		"data":    nil,
+	})
+}
+
+// ResolverStep 滚榜时揭晓的一个待定格子
+type ResolverStep struct {
+	UserID     uint   `json:"userId"`
+	TeamID     uint   `json:"teamId,omitempty"`
+	Username   string `json:"username"`
+	ProblemID  string `json:"problemId"`
+	Accepted   bool   `json:"accepted"`
+	Attempts   int    `json:"attempts"`
+	Score      int    `json:"score"`
+	Solved     int    `json:"solved"`
+	Penalty    int    `json:"penalty"`
+	TotalScore int    `json:"totalScore"`
+	RankBefore int    `json:"rankBefore"`
+	RankAfter  int    `json:"rankAfter"`
+}
+
+// GetContestResolver 生成滚榜数据：封榜时的榜单，以及从最后一名开始逐个揭晓待定格子的顺序
+func GetContestResolver(c *gin.Context) {
+	contestID := c.Param("contestId")
+
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	if contest.FreezeTime == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "比赛未设置封榜时间",
+			"data":    nil,
+		})
+		return
+	}
+
+	submissions, err := loadContestSubmissions(contestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取提交详情失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	board := buildRankBoard(&contest, submissions, contest.FreezeTime)
+	ranks := board.Ranked()
+	frozen := board.Frozen
+
+	// 封榜时的榜单需要深拷贝，后续揭晓会修改排名数据
+	initial, _ := json.Marshal(ranks)
+
+	problemIDs, contestProblems := contestProblemList(&contest)
+	problemOrder := make(map[string]int)
+	for i, problemID := range problemIDs {
+		problemOrder[problemID] = i
+	}
+
+	steps := make([]ResolverStep, 0)
+	for {
+		// 从排名最后的参赛者开始，揭晓其题号最小的待定题目
+		var target *ContestRankData
+		for i := len(ranks) - 1; i >= 0; i-- {
+			if len(frozen[ranks[i].Entrant()]) > 0 {
+				target = ranks[i]
+				break
+			}
+		}
+		if target == nil {
+			break
+		}
+
+		pending := make([]string, 0, len(frozen[target.Entrant()]))
+		for problemID := range frozen[target.Entrant()] {
+			pending = append(pending, problemID)
+		}
+		sort.Slice(pending, func(i, j int) bool { return problemOrder[pending[i]] < problemOrder[pending[j]] })
+		problemID := pending[0]
+
+		rankBefore := rankOf(ranks, target.Entrant())
+		board.Reveal(target.Entrant(), problemID)
+
+		scoreboard.Sort(ranks, board.Rule)
+		steps = append(steps, ResolverStep{
+			UserID:     target.UserID,
+			TeamID:     target.TeamID,
+			Username:   target.Username,
+			ProblemID:  problemID,
+			Accepted:   target.Problems[problemID] == "Accepted",
+			Attempts:   target.Attempts[problemID],
+			Score:      target.Scores[problemID],
+			Solved:     target.Solved,
+			Penalty:    target.Penalty,
+			TotalScore: target.TotalScore,
+			RankBefore: rankBefore,
+			RankAfter:  rankOf(ranks, target.Entrant()),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"problems":        problemIDs,
+			"contestProblems": contestProblems,
+			"type":            scoreboard.RuleName(&contest),
+			"freezeTime":      contest.FreezeTime,
+			"initial":         json.RawMessage(initial),
+			"steps":           steps,
+			"final":           ranks,
+		},
+	})
+}
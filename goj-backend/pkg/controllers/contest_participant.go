@@ -0,0 +1,573 @@
+package controllers
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ContestParticipantInfo 参赛者列表项
+type ContestParticipantInfo struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	Bio       string    `json:"bio"`
+	Status    string    `json:"status"`
+	TeamID    uint      `json:"teamId"`
+	TeamName  string    `json:"teamName"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// 团队赛不能由管理员直接添加个人参赛者
+var errTeamContest = errors.New("team contest")
+
+// 比赛参赛者查询（包含用户信息），按报名时间排序
+func contestParticipantQuery(contestID string) *gorm.DB {
+	return config.DB.Table("contest_participants").
+		Select(`
+			contest_participants.id,
+			contest_participants.user_id,
+			users.username,
+			users.avatar,
+			users.bio,
+			contest_participants.status,
+			contest_participants.team_id,
+			teams.name AS team_name,
+			contest_participants.created_at
+		`).
+		Joins("JOIN users ON users.id = contest_participants.user_id").
+		Joins("LEFT JOIN teams ON teams.id = contest_participants.team_id").
+		Where("contest_participants.contest_id = ? AND contest_participants.deleted_at IS NULL", contestID).
+		Order("contest_participants.created_at")
+}
+
+// 获取比赛的全部参赛者
+func getContestParticipants(contestID string) ([]ContestParticipantInfo, error) {
+	var participants []ContestParticipantInfo
+	err := contestParticipantQuery(contestID).Scan(&participants).Error
+	return participants, err
+}
+
+// 获取用户的参赛记录，未报名时返回 nil
+func getContestParticipant(contestID string, userID uint) *models.ContestParticipant {
+	var participant models.ContestParticipant
+	if err := config.DB.Where("contest_id = ? AND user_id = ?", contestID, userID).
+		First(&participant).Error; err != nil {
+		return nil
+	}
+	return &participant
+}
+
+// 添加参赛者，已报名的用户跳过，返回是否新增
+func addContestParticipant(tx *gorm.DB, contestID string, userID uint) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.ContestParticipant{}).
+		Where("contest_id = ? AND user_id = ?", contestID, userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	return true, tx.Create(&models.ContestParticipant{
+		ContestID: contestID,
+		UserID:    userID,
+		Status:    models.ParticipantRegistered,
+	}).Error
+}
+
+// 重新统计比赛的参赛人数，团队赛统计队伍数
+func refreshParticipantCount(tx *gorm.DB, contestID string) error {
+	var contest models.Contest
+	if err := tx.Select("id, mode").First(&contest, "id = ?", contestID).Error; err != nil {
+		return err
+	}
+	query := tx.Model(&models.ContestParticipant{}).Where("contest_id = ?", contestID)
+	if contest.TeamMode() {
+		query = query.Distinct("team_id")
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Contest{}).Where("id = ?", contestID).
+		Update("participant_count", count).Error
+}
+
+// GetContestRegistration 获取当前用户的报名状态
+func GetContestRegistration(c *gin.Context) {
+	contestID := c.Param("id")
+
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	status := ""
+	var team *models.Team
+	if participant := getContestParticipant(contestID, c.GetUint("userID")); participant != nil {
+		status = contest.ParticipantStatus(participant.Status, time.Now())
+		if participant.TeamID != 0 {
+			team = &models.Team{}
+			if err := config.DB.First(team, participant.TeamID).Error; err != nil {
+				team = nil
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"registered":        status != "",
+			"status":            status,
+			"registrationOpen":  contest.RegistrationOpen(time.Now()),
+			"registerStartTime": contest.RegisterStart,
+			"registerEndTime":   contest.RegisterEnd,
+			"mode":              contest.Mode,
+			"team":              team,
+		},
+	})
+}
+
+// RegisterContest 报名比赛
+func RegisterContest(c *gin.Context) {
+	contestID := c.Param("id")
+	userID := c.GetUint("userID")
+
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 私有比赛只有白名单用户组可以直接报名，其他用户需要密码或邀请码
+	if !canAccessContest(c, &contest) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "私有比赛需要密码或邀请码才能加入",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !contest.RegistrationOpen(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不在报名时间内",
+			"data":    nil,
+		})
+		return
+	}
+
+	if contest.TeamMode() {
+		registerContestTeam(c, &contest)
+		return
+	}
+
+	tx := config.DB.Begin()
+	added, err := addContestParticipant(tx, contestID, userID)
+	if err == nil && added {
+		err = refreshParticipantCount(tx, contestID)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "报名失败",
+			"data":    nil,
+		})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "提交事务失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !added {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "已报名该比赛",
+			"data":    nil,
+		})
+		return
+	}
+
+	ClearContestRankCache(contestID)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "报名成功",
+		"data":    nil,
+	})
+}
+
+// 队长带领队伍报名团队赛
+func registerContestTeam(c *gin.Context, contest *models.Contest) {
+	var req struct {
+		TeamID uint `json:"teamId"`
+	}
+	c.ShouldBindJSON(&req)
+	team, ok := requireContestTeam(c, req.TeamID)
+	if !ok {
+		return
+	}
+
+	var registered []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if registered, err = addContestTeam(tx, contest.ID, team); err != nil {
+			return err
+		}
+		return refreshParticipantCount(tx, contest.ID)
+	})
+	if errors.Is(err, errTeamMemberRegistered) {
+		respondTeamMemberRegistered(c, registered)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "报名失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	ClearContestRankCache(contest.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "报名成功",
+		"data":    nil,
+	})
+}
+
+// UnregisterContest 取消报名，比赛中已提交过的参赛者不能取消。团队赛由队长取消整支队伍的报名
+func UnregisterContest(c *gin.Context) {
+	contestID := c.Param("id")
+	userID := c.GetUint("userID")
+
+	participant := getContestParticipant(contestID, userID)
+	if participant == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "未报名该比赛",
+			"data":    nil,
+		})
+		return
+	}
+
+	if participant.Status != models.ParticipantRegistered {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "已参加比赛，不能取消报名",
+			"data":    nil,
+		})
+		return
+	}
+
+	if participant.TeamID != 0 {
+		var team models.Team
+		if err := config.DB.Select("captain_id").First(&team, participant.TeamID).Error; err != nil ||
+			team.CaptainID != userID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "只有队长可以取消队伍的报名",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
+	if err := removeContestParticipants(contestID, []uint{userID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "取消报名失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已取消报名",
+		"data":    nil,
+	})
+}
+
+// 删除参赛记录并更新参赛人数，团队赛中删除用户所在的整支队伍
+func removeContestParticipants(contestID string, userIDs []uint) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var teamIDs []uint
+		if err := tx.Model(&models.ContestParticipant{}).
+			Where("contest_id = ? AND user_id IN ? AND team_id <> 0", contestID, userIDs).
+			Pluck("team_id", &teamIDs).Error; err != nil {
+			return err
+		}
+		// 物理删除，便于之后重新报名
+		query := tx.Unscoped().Where("contest_id = ? AND user_id IN ?", contestID, userIDs)
+		if len(teamIDs) > 0 {
+			query = tx.Unscoped().
+				Where("contest_id = ? AND (user_id IN ? OR team_id IN ?)", contestID, userIDs, teamIDs)
+		}
+		if err := query.Delete(&models.ContestParticipant{}).Error; err != nil {
+			return err
+		}
+		return refreshParticipantCount(tx, contestID)
+	})
+	if err == nil {
+		ClearContestRankCache(contestID)
+	}
+	return err
+}
+
+// GetContestParticipantList 获取比赛参赛者列表
+func GetContestParticipantList(c *gin.Context) {
+	contestID := c.Param("id")
+
+	contest, ok := requireContestAccess(c, contestID)
+	if !ok {
+		return
+	}
+
+	page, pageSize := getPaginationParams(c)
+	var total int64
+	participants := make([]ContestParticipantInfo, 0)
+	if err := config.DB.Model(&models.ContestParticipant{}).
+		Where("contest_id = ?", contestID).Count(&total).Error; err != nil ||
+		contestParticipantQuery(contestID).Limit(pageSize).Offset((page-1)*pageSize).
+			Scan(&participants).Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取参赛者失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	now := time.Now()
+	for i := range participants {
+		participants[i].Status = contest.ParticipantStatus(participants[i].Status, now)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"participants": participants,
+			"total":        total,
+		},
+	})
+}
+
+// 按用户名批量添加参赛者，返回新增数量和不存在的用户名
+func addParticipantsByUsername(contestID string, usernames []string) (int, []string, error) {
+	var contest models.Contest
+	if err := config.DB.Select("id, mode").First(&contest, "id = ?", contestID).Error; err != nil {
+		return 0, nil, err
+	}
+	if contest.TeamMode() {
+		return 0, nil, errTeamContest
+	}
+
+	added := 0
+	notFound := make([]string, 0)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, username := range usernames {
+			var user models.User
+			if err := tx.Select("id").Where("username = ?", username).First(&user).Error; err != nil {
+				notFound = append(notFound, username)
+				continue
+			}
+			ok, err := addContestParticipant(tx, contestID, user.ID)
+			if err != nil {
+				return err
+			}
+			if ok {
+				added++
+			}
+		}
+		return refreshParticipantCount(tx, contestID)
+	})
+	if err == nil {
+		ClearContestRankCache(contestID)
+	}
+	return added, notFound, err
+}
+
+// 去除空白和重复的用户名
+func normalizeUsernames(usernames []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		username = strings.TrimSpace(username)
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		result = append(result, username)
+	}
+	return result
+}
+
+// 返回批量添加参赛者的结果
+func respondParticipantsAdded(c *gin.Context, added int, notFound []string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "比赛不存在",
+			"data":    nil,
+		})
+		return
+	}
+	if errors.Is(err, errTeamContest) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "团队赛由队长带领队伍报名，不能直接添加参赛者",
+			"data":    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "添加参赛者失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "添加成功",
+		"data": gin.H{
+			"added":    added,
+			"notFound": notFound,
+		},
+	})
+}
+
+// AddContestParticipants 管理员按用户名添加参赛者
+func AddContestParticipants(c *gin.Context) {
+	var req struct {
+		Usernames []string `json:"usernames" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	added, notFound, err := addParticipantsByUsername(c.Param("contestId"), normalizeUsernames(req.Usernames))
+	respondParticipantsAdded(c, added, notFound, err)
+}
+
+// ImportContestParticipants 管理员从文件导入参赛者，每行一个用户名，逗号分隔时取第一列
+func ImportContestParticipants(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请上传文件",
+			"data":    nil,
+		})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "读取文件失败",
+			"data":    nil,
+		})
+		return
+	}
+	defer src.Close()
+
+	var usernames []string
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		usernames = append(usernames, strings.Split(scanner.Text(), ",")[0])
+	}
+	if err := scanner.Err(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "文件格式错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	added, notFound, err := addParticipantsByUsername(c.Param("contestId"), normalizeUsernames(usernames))
+	respondParticipantsAdded(c, added, notFound, err)
+}
+
+// RemoveContestParticipant 管理员移除参赛者
+func RemoveContestParticipant(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := removeContestParticipants(c.Param("contestId"), []uint{uint(userID)}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "移除参赛者失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除成功",
+		"data":    nil,
+	})
+}
+
+// 将参赛者加入排名，没有提交的参赛者也会出现在榜单上。团队赛加入报名的队伍
+func seedRankWithParticipants(board *scoreboard.Board, contestID string) {
+	if board.Contest.TeamMode() {
+		board.SeedTeams()
+		return
+	}
+	participants, err := getContestParticipants(contestID)
+	if err != nil {
+		return
+	}
+	for _, participant := range participants {
+		board.Seed(participant.UserID, participant.Username, participant.Avatar, participant.Bio)
+	}
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 判断题目是否在逗号分隔的题目列表中
+func containsProblem(problems, problemID string) bool {
+	for _, id := range strings.Split(problems, ",") {
+		if id == problemID {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取用户在比赛中的虚拟参赛记录，没有时返回 nil
+func getVirtualParticipation(contestID string, userID uint) *models.VirtualParticipation {
+	var virtual models.VirtualParticipation
+	if err := config.DB.Where("contest_id = ? AND user_id = ?", contestID, userID).
+		First(&virtual).Error; err != nil {
+		return nil
+	}
+	return &virtual
+}
+
+// 获取用户正在进行的虚拟参赛，不在计时窗口内时返回 nil
+func activeVirtualParticipation(contestID string, userID uint, now time.Time) *models.VirtualParticipation {
+	virtual := getVirtualParticipation(contestID, userID)
+	if virtual == nil || !virtual.Running(now) {
+		return nil
+	}
+	return virtual
+}
+
+// 虚拟参赛的状态
+func virtualParticipationData(virtual *models.VirtualParticipation, now time.Time) gin.H {
+	elapsed := virtual.Elapsed(now)
+	return gin.H{
+		"participation": virtual,
+		"running":       virtual.Running(now),
+		"elapsed":       int64(elapsed.Seconds()),
+		"remaining":     int64((virtual.EndTime.Sub(virtual.StartTime) - elapsed).Seconds()),
+	}
+}
+
+// buildVirtualBoard 计算虚拟榜单：原比赛参赛者截至相同比赛时间的成绩，加上虚拟参赛者的成绩。
+// 虚拟参赛进行中时沿用原比赛的封榜和隐藏结果规则，但虚拟参赛者能看到自己封榜后的结果
+func buildVirtualBoard(contest *models.Contest, virtual *models.VirtualParticipation, now time.Time) (*scoreboard.Board, bool, error) {
+	asOf := contest.StartTime.Add(virtual.Elapsed(now))
+	running := virtual.Running(now)
+
+	var cutoff *time.Time
+	hidden := false
+	if running {
+		if scoreboard.RuleOf(contest).HidesResults() {
+			cutoff = &contest.StartTime
+			hidden = true
+		} else if contest.FreezeTime != nil && !asOf.Before(*contest.FreezeTime) {
+			cutoff = contest.FreezeTime
+		}
+	}
+
+	official, err := loadContestSubmissions(contest.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var user models.User
+	if err := config.DB.Select("id, username, avatar, bio").First(&user, virtual.UserID).Error; err != nil {
+		return nil, false, err
+	}
+
+	var submissions []ContestSubmission
+	if err := config.DB.Model(&models.Submission{}).
+		Select(`
+			submissions.*,
+			users.username,
+			users.avatar,
+			users.bio
+		`).
+		Joins("LEFT JOIN users ON users.id = submissions.user_id").
+		Where("submissions.virtual_id = ? AND submissions.judge_time IS NOT NULL", virtual.ID).
+		Order("submissions.judge_time, submissions.id").
+		Find(&submissions).Error; err != nil {
+		return nil, false, err
+	}
+
+	board := scoreboard.NewBoard(contest, cutoff)
+	seedRankWithParticipants(board, contest.ID)
+	for i := range official {
+		if official[i].SubmitTime.After(asOf) {
+			continue
+		}
+		board.Apply(&official[i])
+	}
+
+	row := board.Seed(user.ID, user.Username, user.Avatar, user.Bio)
+	row.Virtual = true
+	for i := range submissions {
+		// 换算到原比赛的时间线上
+		submissions[i].SubmitTime = contest.StartTime.Add(submissions[i].SubmitTime.Sub(virtual.StartTime))
+		board.Apply(&submissions[i])
+	}
+	if cutoff != nil && !hidden {
+		for problemID := range row.Pending {
+			board.Reveal(user.ID, problemID)
+		}
+	}
+	return board, hidden, nil
+}
+
+// GetVirtualParticipation 获取当前用户在比赛中的虚拟参赛状态
+func GetVirtualParticipation(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+	now := time.Now()
+
+	virtual := getVirtualParticipation(contest.ID, userID)
+	if virtual == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "获取成功",
+			"data": gin.H{
+				"participation": nil,
+				"canStart":      !contest.TeamMode() && now.After(contest.EndTime) && getContestParticipant(contest.ID, userID) == nil,
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    virtualParticipationData(virtual, now),
+	})
+}
+
+// StartVirtualParticipation 开始虚拟参加已结束的比赛，计时窗口与原比赛时长相同
+func StartVirtualParticipation(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+	now := time.Now()
+
+	if contest.TeamMode() {
+		// 虚拟榜单按个人计分，无法与队伍成绩比较
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "团队赛不支持虚拟参赛",
+			"data":    nil,
+		})
+		return
+	}
+	if !now.After(contest.EndTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "比赛结束后才能虚拟参赛",
+			"data":    nil,
+		})
+		return
+	}
+	if getContestParticipant(contest.ID, userID) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "已参加过该比赛，不能虚拟参赛",
+			"data":    nil,
+		})
+		return
+	}
+	if getVirtualParticipation(contest.ID, userID) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "已虚拟参加过该比赛",
+			"data":    nil,
+		})
+		return
+	}
+
+	virtual := models.VirtualParticipation{
+		ContestID: contest.ID,
+		UserID:    userID,
+		StartTime: now,
+		EndTime:   now.Add(contest.EndTime.Sub(contest.StartTime)),
+	}
+	if err := config.DB.Create(&virtual).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "开始虚拟参赛失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "虚拟参赛已开始",
+		"data":    virtualParticipationData(&virtual, now),
+	})
+}
+
+// GetVirtualContestRank 获取虚拟榜单，管理员可以通过 userId 查看其他用户的虚拟榜单
+func GetVirtualContestRank(c *gin.Context) {
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+	if c.GetString("role") == "admin" && c.Query("userId") != "" {
+		if id, err := strconv.ParseUint(c.Query("userId"), 10, 64); err == nil {
+			userID = uint(id)
+		}
+	}
+	now := time.Now()
+
+	virtual := getVirtualParticipation(contest.ID, userID)
+	if virtual == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "没有虚拟参赛记录",
+			"data":    nil,
+		})
+		return
+	}
+
+	board, hidden, err := buildVirtualBoard(contest, virtual, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取虚拟榜单失败",
+			"data":    nil,
+		})
+		return
+	}
+	ranks := board.Ranked()
+	for i, rank := range ranks {
+		rank.Rank = i + 1
+	}
+
+	problemIDs, contestProblems := contestProblemList(contest)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"problems":        problemIDs,
+			"contestProblems": contestProblems,
+			"ranks":           ranks,
+			"total":           len(ranks),
+			"penalty":         contest.PenaltyTime,
+			"type":            scoreboard.RuleName(contest),
+			"startTime":       contest.StartTime,
+			"frozen":          board.Cutoff != nil,
+			"freezeTime":      contest.FreezeTime,
+			"hidden":          hidden,
+			"virtual":         virtualParticipationData(virtual, now),
+		},
+	})
+}
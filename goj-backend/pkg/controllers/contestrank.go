@@ -3,12 +3,15 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/events"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
+	"log"
 	"math"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
@@ -22,299 +25,146 @@ import (
 type StringArray = models.StringArray
 
 // ContestRankData 比赛排名数据结构
-type ContestRankData struct {
-	UserID     uint              `json:"userId"`
-	Username   string            `json:"username"`
-	Avatar     string            `json:"avatar"`
-	Bio        string            `json:"bio"`      // 添加 Bio 字段
-	Problems   map[string]string `json:"problems"`   // 题目状态 map[problemId]status
-	Scores     map[string]int    `json:"scores"`     // IOI模式得分 map[problemId]score
-	Attempts   map[string]int    `json:"attempts"`   // 尝试次数 map[problemId]attempts
-	Solved     int               `json:"solved"`     // ACM模式解题数
-	Penalty    int               `json:"penalty"`    // ACM模式罚时
-	TotalScore int               `json:"totalScore"` // IOI模式总分
-}
+type ContestRankData = scoreboard.Row
 
-// 添加计算总分的函数
-func calculateTotalScore(scores map[string]int) int {
-	total := 0
-	for _, score := range scores {
-		total += score
-	}
-	return total
-}
-
-// 修改缓存时间为1分钟
-const (
-	ContestRankCacheKey    = "contest_rank:%s:%s" // 格式: contest_rank:contestId:rankType
-	ContestRankCachePeriod = 60 * time.Second     // 缓存时间改为1分钟
-)
+// 比赛提交记录查询结构体
+type ContestSubmission = scoreboard.Submission
 
-// 修改提交记录查询结构体
-type ContestSubmission struct {
-	ID              uint        `json:"id"`
-	UserID          uint        `json:"userId"`
-	Username        string      `json:"username"`
-	Avatar          string      `json:"avatar"`
-	Bio             string      `json:"bio"`
-	ProblemID       string      `json:"problemId"`
-	Status          string      `json:"status"`
-	SubmitTime      time.Time   `json:"submitTime"`
-	TimeUsed        int         `json:"timeUsed"`
-	MemoryUsed      int         `json:"memoryUsed"`
-	TestcasesStatus StringArray `json:"testcasesStatus"`
-	TestcasesInfo   StringArray `json:"testcasesInfo"`
-}
-
-// GetContestRank 获取比赛排名
+// GetContestRank 获取比赛排名，传入 page 时分页返回，传入 username 时按用户名筛选
 func GetContestRank(c *gin.Context) {
 	contestID := c.Param("id")
-	rankType := c.DefaultQuery("type", "acm")
-
-	// 构造缓存键
-	cacheKey := fmt.Sprintf(ContestRankCacheKey, contestID, rankType)
-
-	// 尝试从缓存获取数据
-	if cachedData, err := config.RDB.Get(c, cacheKey).Result(); err == nil {
-		var rankData gin.H
-		if err := json.Unmarshal([]byte(cachedData), &rankData); err == nil {
-			c.JSON(http.StatusOK, gin.H{"code": 200, "data": rankData})
-			return
-		}
-	}
 
-	// 如果缓存不存在或已过期，则重新计算排名
-	var contest models.Contest
-	if err := config.DB.First(&contest, contestID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "比赛不存在"})
+	// 私有比赛的榜单同样需要访问权限
+	contestPtr, ok := requireContestAccess(c, contestID)
+	if !ok {
 		return
 	}
+	contest := *contestPtr
 
-	// 获取比赛的提交ID列表
-	var contestStatus struct {
-		SubmissionIDs json.RawMessage `gorm:"column:submission_ids"`
+	// 封榜期间非管理员看到封榜时的榜单
+	frozenAt := viewerFreezeTime(c, &contest)
+
+	offset, limit := 0, 0
+	if c.Query("page") != "" {
+		page, pageSize := getPaginationParams(c)
+		offset, limit = (page-1)*pageSize, pageSize
 	}
-	if err := config.DB.Table("contest_submission_status").
-		Where("contest_id = ?", contestID).
-		First(&contestStatus).Error; err != nil {
-		// 如果没有提交记录，返回空的排名数据并缓存
-		rankData := gin.H{
-			"problems":  strings.Split(contest.Problems, ","),
-			"ranks":     []*ContestRankData{},
-			"penalty":   contest.PenaltyTime,
-			"type":      rankType,
-			"startTime": contest.StartTime,
+
+	var ranks []*ContestRankData
+	var total int64
+	var err error
+	if username := strings.ToLower(strings.TrimSpace(c.Query("username"))); username != "" {
+		// 按用户名筛选时读取完整榜单，名次保持为完整榜单中的名次
+		var all []*ContestRankData
+		all, _, err = loadContestRanks(c, &contest, frozenAt, 0, 0)
+		ranks = make([]*ContestRankData, 0)
+		for _, rank := range all {
+			if rankMatches(rank, username) {
+				ranks = append(ranks, rank)
+			}
 		}
-		
-		// 缓存空数据
-		if jsonData, err := json.Marshal(rankData); err == nil {
-			config.RDB.Set(c, cacheKey, jsonData, ContestRankCachePeriod)
+		total = int64(len(ranks))
+		if limit > 0 {
+			if offset > len(ranks) {
+				offset = len(ranks)
+			}
+			ranks = ranks[offset:]
+			if len(ranks) > limit {
+				ranks = ranks[:limit]
+			}
 		}
-		
-		c.JSON(http.StatusOK, gin.H{"code": 200, "data": rankData})
-		return
-	}
-
-	// 解析提交ID列表
-	var submissionIDs []uint
-	if err := json.Unmarshal(contestStatus.SubmissionIDs, &submissionIDs); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "解析提交记录失败"})
-		return
+	} else {
+		ranks, total, err = loadContestRanks(c, &contest, frozenAt, offset, limit)
 	}
-
-	// 按顺序获取所有提交记录（包含测试点信息）
-	var submissions []ContestSubmission
-	if err := config.DB.Model(&models.Submission{}).
-		Select(`
-			submissions.*,
-			users.username,
-			users.avatar,
-			users.bio
-		`).
-		Joins("LEFT JOIN users ON users.id = submissions.user_id").
-		Where("submissions.id IN ?", submissionIDs).
-		Order(fmt.Sprintf("FIELD(submissions.id, %s)", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(submissionIDs)), ","), "[]"))).
-		Find(&submissions).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取提交详情失败"})
 		return
 	}
 
-	// 在获取提交记录后添加更详细的调试信息
-	for _, sub := range submissions {
-		fmt.Printf("\n=== Submission Details ===\n")
-		fmt.Printf("ID: %d\n", sub.ID)
-		fmt.Printf("UserID: %d\n", sub.UserID)
-		fmt.Printf("ProblemID: %s\n", sub.ProblemID)
-		fmt.Printf("Status: %s\n", sub.Status)
-		fmt.Printf("TestcasesStatus type: %T\n", sub.TestcasesStatus)
-		fmt.Printf("TestcasesStatus: %+v\n", sub.TestcasesStatus)
-		fmt.Printf("TestcasesInfo: %+v\n", sub.TestcasesInfo)
-		fmt.Printf("Raw TestcasesStatus length: %d\n", len(sub.TestcasesStatus))
-		fmt.Printf("========================\n")
-	}
-
-	// 计算排名
-	rankMap := make(map[uint]*ContestRankData)
-	for _, sub := range submissions {
-		userData := initUserRankData(rankMap, &sub)
-		if rankType == "acm" {
-			// ACM模式：使用比赛开始时间计算罚时
-			handleACMSubmission(userData, &sub, contest.PenaltyTime, contest.StartTime)
-		} else {
-			// IOI模式：使用测试点信息计算分数
-			handleIOISubmission(userData, &sub)
-		}
-	}
-
-	// 获取排序后的排名列表
-	ranks := getRankedList(rankMap, rankType)
-
-	// 构造返回数据
-	rankData := gin.H{
-		"problems":  strings.Split(contest.Problems, ","),
-		"ranks":     ranks,
-		"penalty":   contest.PenaltyTime,
-		"type":      rankType,
-		"startTime": contest.StartTime,
-	}
-
-	// 将计算结果存入缓存
-	if jsonData, err := json.Marshal(rankData); err == nil {
-		config.RDB.Set(c, cacheKey, jsonData, ContestRankCachePeriod)
-	}
-
-	c.JSON(http.StatusOK, gin.H{"code": 200, "data": rankData})
+	problemIDs, contestProblems := contestProblemList(&contest)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{
+		"problems":        problemIDs,
+		"contestProblems": contestProblems,
+		"ranks":           ranks,
+		"total":           total,
+		"penalty":         contest.PenaltyTime,
+		"type":            scoreboard.RuleName(&contest),
+		"startTime":       contest.StartTime,
+		"frozen":          frozenAt != nil,
+		"freezeTime":      contest.FreezeTime,
+		"hidden":          frozenAt != nil && scoreboard.ResultsHidden(&contest, time.Now()),
+	}})
 }
 
-// 初始化用户排名数据
-func initUserRankData(rankMap map[uint]*ContestRankData, sub *ContestSubmission) *ContestRankData {
-	if _, exists := rankMap[sub.UserID]; !exists {
-		rankMap[sub.UserID] = &ContestRankData{
-			UserID:     sub.UserID,
-			Username:   sub.Username,
-			Avatar:     sub.Avatar,
-			Bio:        sub.Bio,           // 添加 Bio 信息
-			Problems:   make(map[string]string),
-			Scores:     make(map[string]int),
-			Attempts:   make(map[string]int),
-			Solved:     0,
-			Penalty:    0,
-			TotalScore: 0,
+// 榜单项的用户名包含关键字，团队赛中匹配队名或队员用户名
+func rankMatches(rank *ContestRankData, keyword string) bool {
+	if strings.Contains(strings.ToLower(rank.Username), keyword) {
+		return true
+	}
+	for _, member := range rank.Members {
+		if strings.Contains(strings.ToLower(member), keyword) {
+			return true
 		}
 	}
-	return rankMap[sub.UserID]
+	return false
 }
 
-// 修改ACM提交处理函数，使用比赛开始时间计算罚时
-func handleACMSubmission(userData *ContestRankData, sub *ContestSubmission, penaltyTime int, contestStart time.Time) {
-	if userData.Problems[sub.ProblemID] != "Accepted" {
-		userData.Attempts[sub.ProblemID]++
-		if sub.Status == "Accepted" {
-			userData.Problems[sub.ProblemID] = "Accepted"
-			userData.Solved++
-			// 使用比赛开始时间计算罚时（分钟）
-			timePenalty := int(sub.SubmitTime.Sub(contestStart).Minutes())
-			userData.Penalty += timePenalty + (userData.Attempts[sub.ProblemID]-1)*penaltyTime
-		}
+// 按比赛中的顺序获取题目ID和题目设置
+func contestProblemList(contest *models.Contest) ([]string, []models.ContestProblem) {
+	contestProblems, err := models.LoadContestProblems(config.DB, contest)
+	if err != nil {
+		// 读取失败时退回到不含设置的题目列表
+		contestProblems = models.DefaultContestProblems(contest)
 	}
+	problemIDs := make([]string, 0, len(contestProblems))
+	for _, problem := range contestProblems {
+		problemIDs = append(problemIDs, problem.ProblemID)
+	}
+	return problemIDs, contestProblems
 }
 
-// 修改IOI提交处理函数，直接查询数据库获取测试点信息
-func handleIOISubmission(userData *ContestRankData, sub *ContestSubmission) {
-	fmt.Printf("\n=== Processing IOI Submission ===\n")
-	fmt.Printf("Processing submission for user %s (ID: %d)\n", userData.Username, userData.UserID)
-	fmt.Printf("Problem ID: %s\n", sub.ProblemID)
-	
-	// 直接从数据库查询完整的提交信息
-	var submission models.Submission
-	if err := config.DB.First(&submission, sub.ID).Error; err != nil {
-		fmt.Printf("Error fetching submission details: %v\n", err)
-		return
+// loadContestRanks 读取从第 offset+1 名开始的 limit 个成绩，limit 为 0 时读取全部。
+// 榜单由评测结果增量维护在 Redis 中，不存在时从数据库全量重建
+func loadContestRanks(ctx context.Context, contest *models.Contest, frozenAt *time.Time, offset, limit int) ([]*ContestRankData, int64, error) {
+	ranks, total, ok, err := scoreboard.Page(ctx, contest.ID, frozenAt, offset, limit)
+	if err == nil && ok {
+		return ranks, total, nil
 	}
-	
-	currentScore := 0
-	if len(submission.TestcasesStatus) > 0 {
-		fmt.Printf("TestcasesStatus found with length: %d\n", len(submission.TestcasesStatus))
-		fmt.Printf("TestcasesStatus content: %v\n", submission.TestcasesStatus)
-		
-		// 计算通过的测试点数量
-		passedCount := 0
-		for i, status := range submission.TestcasesStatus {
-			fmt.Printf("Testcase %d status: %s\n", i+1, status)
-			if status == "Accepted" {
-				passedCount++
-			}
-		}
-		
-		// 按通过测试点数量比例计算分数
-		currentScore = (passedCount * 100) / len(submission.TestcasesStatus)
-		fmt.Printf("Passed testcases: %d/%d\n", passedCount, len(submission.TestcasesStatus))
-		fmt.Printf("Calculated score: %d\n", currentScore)
-	} else {
-		fmt.Printf("No testcases status found for this submission\n")
-	}
-
-	// 检查现有分数
-	fmt.Printf("Current score for problem %s: %d\n", sub.ProblemID, userData.Scores[sub.ProblemID])
-	
-	// 更新最高分
-	if currentScore > userData.Scores[sub.ProblemID] {
-		fmt.Printf("Updating score from %d to %d\n", userData.Scores[sub.ProblemID], currentScore)
-		userData.Scores[sub.ProblemID] = currentScore
-		userData.TotalScore = calculateTotalScore(userData.Scores)
-		fmt.Printf("New total score: %d\n", userData.TotalScore)
-	} else {
-		fmt.Printf("Score not updated (current: %d, new: %d)\n", userData.Scores[sub.ProblemID], currentScore)
+
+	// 先读取版本，重建期间有新提交时不保存重建结果
+	version, versionErr := scoreboard.Version(ctx, contest.ID)
+	submissions, err := loadContestSubmissions(contest.ID)
+	if err != nil {
+		return nil, 0, err
 	}
-	
-	fmt.Printf("=== End Processing ===\n\n")
-}
 
-// 获取排序后的排名列表
-func getRankedList(rankMap map[uint]*ContestRankData, rankType string) []*ContestRankData {
-	fmt.Printf("\n=== Generating Rank List ===\n")
-	fmt.Printf("Total participants: %d\n", len(rankMap))
-	fmt.Printf("Rank type: %s\n", rankType)
-	
-	ranks := make([]*ContestRankData, 0, len(rankMap))
-	for userID, rank := range rankMap {
-		fmt.Printf("\nUser %s (ID: %d):\n", rank.Username, userID)
-		fmt.Printf("Scores: %v\n", rank.Scores)
-		fmt.Printf("Total Score: %d\n", rank.TotalScore)
-		ranks = append(ranks, rank)
-	}
-
-	// 排序
-	sort.Slice(ranks, func(i, j int) bool {
-		if rankType == "acm" {
-			if ranks[i].Solved != ranks[j].Solved {
-				return ranks[i].Solved > ranks[j].Solved
-			}
-			return ranks[i].Penalty < ranks[j].Penalty
+	// 已报名但没有提交的参赛者也在榜单上
+	board := buildRankBoard(contest, submissions, frozenAt)
+	if versionErr == nil {
+		if err := scoreboard.Save(ctx, board, version); err != nil && !errors.Is(err, scoreboard.ErrStaleBoard) {
+			log.Printf("保存比赛 %s 的榜单失败: %v", contest.ID, err)
 		}
-		return ranks[i].TotalScore > ranks[j].TotalScore
-	})
+	}
 
-	fmt.Printf("\nFinal Ranking:\n")
+	ranks = board.Ranked()
 	for i, rank := range ranks {
-		fmt.Printf("%d. %s - Score: %d\n", i+1, rank.Username, rank.TotalScore)
+		rank.Rank = i + 1
 	}
-	fmt.Printf("=== End Ranking ===\n\n")
-
-	return ranks
+	total = int64(len(ranks))
+	if offset > len(ranks) {
+		offset = len(ranks)
+	}
+	ranks = ranks[offset:]
+	if limit > 0 && len(ranks) > limit {
+		ranks = ranks[:limit]
+	}
+	return ranks, total, nil
 }
 
 // GetContest 获取单个比赛详情
 func GetContest(c *gin.Context) {
-	contestID := c.Param("id")
-
-	var contest models.Contest
-	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "比赛不存在",
-			"data":    nil,
-		})
+	contest, ok := requireContestAccess(c, c.Param("id"))
+	if !ok {
 		return
 	}
 
@@ -327,6 +177,7 @@ func GetContest(c *gin.Context) {
 	} else {
 		contest.Status = "running"
 	}
+	_, contest.ContestProblems = contestProblemList(contest)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -348,7 +199,6 @@ func UpdateContestRating(c *gin.Context) {
 	}
 
 	contestID := c.Param("id")
-	rankType := c.DefaultQuery("type", "acm")
 
 	// 1. 获取比赛信息
 	var contest models.Contest
@@ -387,6 +237,8 @@ func UpdateContestRating(c *gin.Context) {
 			submissions.problem_id,
 			submissions.status,
 			submissions.submit_time,
+			submissions.testcases_status,
+			submissions.team_id,
 			users.username,
 			users.rating
 		`).
@@ -398,47 +250,59 @@ func UpdateContestRating(c *gin.Context) {
 		return
 	}
 
-	// 3. 计算用户排名
-	rankMap := make(map[uint]*ContestRankData)
-	for _, sub := range submissions {
-		userData := initUserRankData(rankMap, &sub)
-		if rankType == "acm" {
-			handleACMSubmission(userData, &sub, contest.PenaltyTime, contest.StartTime)
-		} else {
-			handleIOISubmission(userData, &sub)
-		}
+	// 3. 按比赛赛制计算排名，团队赛按队伍排名，只有提交过的参赛者参与rating计算
+	board := scoreboard.NewBoard(&contest, nil)
+	for i := range submissions {
+		board.Apply(&submissions[i])
 	}
 
-	ranks := getRankedList(rankMap, rankType)
+	ranks := board.Ranked()
 
 	// 4. 构造用户排名数据
 	type UserRank struct {
 		UserID   uint   `json:"userId"`
+		TeamID   uint   `json:"teamId"`
+		Entrant  uint   `json:"-"` // 团队赛为队伍ID，个人赛为用户ID
 		Username string `json:"username"`
 		Rating   int64  `json:"rating"`
 		Rank     int    `json:"rank"`
 		Solved   int    `json:"solved"`
+		Score    int    `json:"score"`
 	}
 
+	icpc := scoreboard.RuleName(&contest) == scoreboard.RuleICPC
 	var users []UserRank
 	for i, rank := range ranks {
+		score := rank.TotalScore
+		if icpc {
+			score = rank.Solved
+		}
 		users = append(users, UserRank{
 			UserID:   rank.UserID,
+			TeamID:   rank.TeamID,
+			Entrant:  rank.Entrant(),
 			Username: rank.Username,
 			Rating:   0, // 将在后面查询
 			Rank:     i + 1,
 			Solved:   rank.Solved,
+			Score:    score,
 		})
 	}
 
-	// 5. 获取用户当前rating
+	// 团队赛更新队伍的rating，参赛记录按队伍对应
+	ratingTable, participantColumn := "users", "user_id"
+	if contest.TeamMode() {
+		ratingTable, participantColumn = "teams", "team_id"
+	}
+
+	// 5. 获取当前rating
 	for i := range users {
 		var user struct {
 			Rating int64
 		}
-		if err := config.DB.Table("users").
+		if err := config.DB.Table(ratingTable).
 			Select("rating").
-			Where("id = ?", users[i].UserID).
+			Where("id = ?", users[i].Entrant).
 			First(&user).Error; err != nil {
 			continue
 		}
@@ -446,6 +310,7 @@ func UpdateContestRating(c *gin.Context) {
 	}
 
 	// 6. 更新rating
+	ratingChanges := make([]events.RatingChange, 0, len(users))
 	tx := config.DB.Begin()
 	for i := range users {
 		// 计算种子分（根据现有rating）
@@ -479,6 +344,7 @@ func UpdateContestRating(c *gin.Context) {
 		// 记录rating变化历史
 		ratingHistory := models.RatingHistory{
 			UserID:    users[i].UserID,
+			TeamID:    users[i].TeamID,
 			ContestID: contestID,
 			OldRating: users[i].Rating,
 			NewRating: newRating,
@@ -496,8 +362,8 @@ func UpdateContestRating(c *gin.Context) {
 		}
 
 		// 更新用户rating
-		if err := tx.Model(&models.User{}).
-			Where("id = ?", users[i].UserID).
+		if err := tx.Table(ratingTable).
+			Where("id = ?", users[i].Entrant).
 			Update("rating", newRating).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -506,6 +372,29 @@ func UpdateContestRating(c *gin.Context) {
 			})
 			return
 		}
+
+		// 记录名次和成绩，供个人主页查看，团队赛中记入每名队员
+		if err := tx.Model(&models.ContestParticipant{}).
+			Where("contest_id = ? AND "+participantColumn+" = ?", contestID, users[i].Entrant).
+			Updates(map[string]interface{}{
+				"rank":  users[i].Rank,
+				"score": users[i].Score,
+			}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "更新参赛成绩失败",
+			})
+			return
+		}
+
+		ratingChanges = append(ratingChanges, events.RatingChange{
+			UserID:    users[i].UserID,
+			TeamID:    users[i].TeamID,
+			OldRating: users[i].Rating,
+			NewRating: newRating,
+			Rank:      users[i].Rank,
+		})
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -516,6 +405,11 @@ func UpdateContestRating(c *gin.Context) {
 		return
 	}
 
+	events.Publish(events.RatingUpdated, events.RatingData{
+		ContestID: contestID,
+		Changes:   ratingChanges,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "rating更新成功",
@@ -560,6 +454,8 @@ func UpdateContestPenalty(c *gin.Context) {
 		return
 	}
 
+	ClearContestRankCache(contestID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "更新成功",
@@ -569,69 +465,20 @@ func UpdateContestPenalty(c *gin.Context) {
 // 添加新的导出函数
 func ExportContestRank(c *gin.Context) {
 	contestID := c.Param("id")
-	rankType := c.DefaultQuery("type", "acm")
 
 	// 获取比赛信息
-	var contest models.Contest
-	if err := config.DB.First(&contest, contestID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "比赛不存在"})
-		return
-	}
-
-	// 获取排名数据（复用现有逻辑）
-	var contestStatus struct {
-		SubmissionIDs json.RawMessage `gorm:"column:submission_ids"`
-	}
-	if err := config.DB.Table("contest_submission_status").
-		Where("contest_id = ?", contestID).
-		First(&contestStatus).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取比赛提交记录失败"})
-		return
-	}
-
-	var submissionIDs []uint
-	if err := json.Unmarshal(contestStatus.SubmissionIDs, &submissionIDs); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "解析提交记录失败"})
+	contest, ok := requireContestAccess(c, contestID)
+	if !ok {
 		return
 	}
 
-	var submissions []ContestSubmission
-	if err := config.DB.Table("submissions").
-		Select(`
-			submissions.id,
-			submissions.user_id,
-			submissions.problem_id,
-			submissions.status,
-			submissions.submit_time,
-			submissions.time_used,
-			submissions.memory_used,
-			submissions.testcases_status,
-			submissions.testcases_info,
-			users.username,
-			users.avatar,
-			users.bio
-		`).
-		Joins("LEFT JOIN users ON users.id = submissions.user_id").
-		Where("submissions.id IN ?", submissionIDs).
-		Order(fmt.Sprintf("FIELD(submissions.id, %s)", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(submissionIDs)), ","), "[]"))).
-		Find(&submissions).Error; err != nil {
+	// 获取排名数据（复用现有逻辑），封榜期间非管理员导出封榜时的榜单
+	ranks, _, err := loadContestRanks(c, contest, viewerFreezeTime(c, contest), 0, 0)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取提交详情失败"})
 		return
 	}
-
-	// 计算排名
-	rankMap := make(map[uint]*ContestRankData)
-	for _, sub := range submissions {
-		userData := initUserRankData(rankMap, &sub)
-		if rankType == "acm" {
-			handleACMSubmission(userData, &sub, contest.PenaltyTime, contest.StartTime)
-		} else {
-			handleIOISubmission(userData, &sub)
-		}
-	}
-
-	// 获取排序后的排名列表
-	ranks := getRankedList(rankMap, rankType)
+	icpc := scoreboard.RuleName(contest) == scoreboard.RuleICPC
 
 	// 生成Excel文件
 	f := excelize.NewFile()
@@ -644,15 +491,19 @@ func ExportContestRank(c *gin.Context) {
 
 	// 设置表头
 	headers := []string{"排名", "用户名", "个人简介"}  // 添加"个人简介"列
-	if rankType == "acm" {
+	if contest.TeamMode() {
+		// 团队赛按队伍导出，第三列为队员
+		headers = []string{"排名", "队伍", "队员"}
+	}
+	if icpc {
 		headers = append(headers, "解题数", "罚时")
 	} else {
 		headers = append(headers, "总分")
 	}
-	// 添加题目列
-	problems := strings.Split(contest.Problems, ",")
-	for _, problem := range problems {
-		headers = append(headers, fmt.Sprintf("题目%s", problem))
+	// 添加题目列，使用比赛中的题目标号
+	problems, contestProblems := contestProblemList(contest)
+	for _, problem := range contestProblems {
+		headers = append(headers, fmt.Sprintf("题目%s", problem.Label))
 	}
 
 	// 写入表头
@@ -680,9 +531,13 @@ func ExportContestRank(c *gin.Context) {
 		// 写入基本信息
 		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), i+1)
 		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), rank.Username)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), rank.Bio)  // 添加 Bio 信息
+		if contest.TeamMode() {
+			f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), strings.Join(rank.Members, "、"))
+		} else {
+			f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), rank.Bio)  // 添加 Bio 信息
+		}
 
-		if rankType == "acm" {
+		if icpc {
 			// ACM模式下的列偏移量需要+1，因为多了bio列
 			f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), rank.Solved)
 			f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("%d:%02d", rank.Penalty/60, rank.Penalty%60))
@@ -691,6 +546,8 @@ func ExportContestRank(c *gin.Context) {
 				status := "-"
 				if rank.Problems[problemId] == "Accepted" {
 					status = fmt.Sprintf("AC(%d)", rank.Attempts[problemId])
+				} else if rank.Problems[problemId] == scoreboard.StatusPending {
+					status = fmt.Sprintf("?%d", rank.Attempts[problemId]+rank.Pending[problemId])
 				} else if rank.Attempts[problemId] > 0 {
 					status = fmt.Sprintf("-%d", rank.Attempts[problemId])
 				}
@@ -701,7 +558,10 @@ func ExportContestRank(c *gin.Context) {
 			f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), rank.TotalScore)
 			// 写入每题分数
 			for j, problemId := range problems {
-				score := rank.Scores[problemId]
+				var score interface{} = rank.Scores[problemId]
+				if rank.Problems[problemId] == scoreboard.StatusPending {
+					score = "?"
+				}
 				f.SetCellValue(sheetName, fmt.Sprintf("%c%d", 'E'+j, row), score)  // 列号从E开始
 			}
 		}
@@ -755,12 +615,7 @@ func ExportContestRank(c *gin.Context) {
 	}()
 }
 
-// 修改清除缓存的函数
+// 清除比赛榜单缓存，包括封榜视图，下次读取时重建
 func ClearContestRankCache(contestID string) {
-	// 同时清除 ACM 和 IOI 模式的缓存
-	cacheKeyACM := fmt.Sprintf(ContestRankCacheKey, contestID, "acm")
-	cacheKeyIOI := fmt.Sprintf(ContestRankCacheKey, contestID, "ioi")
-	
-	config.RDB.Del(context.Background(), cacheKeyACM)
-	config.RDB.Del(context.Background(), cacheKeyIOI)
+	scoreboard.Invalidate(context.Background(), contestID)
 }
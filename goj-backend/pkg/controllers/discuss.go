@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
 	"net/http"
 
@@ -108,6 +109,21 @@ func GetDiscussion(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// 推送全站公告，客户端订阅 announcements 主题
+func notifySiteAnnouncement(discussion *models.Discussion) {
+	ws := handler.GetWebSocketManager()
+	if ws == nil {
+		return
+	}
+	ws.BroadcastToTopic(handler.TopicAnnouncements, handler.WebSocketMessage{
+		Type: "site_announcement",
+		Data: gin.H{
+			"id":    discussion.ID,
+			"title": discussion.Title,
+		},
+	})
+}
+
 // 创建讨论
 func CreateDiscussion(c *gin.Context) {
 	var input struct {
@@ -174,6 +190,11 @@ func CreateDiscussion(c *gin.Context) {
 		return
 	}
 
+	// 管理员发布的公告推送给全站在线用户
+	if discussion.Category == "notice" && c.GetString("role") == "admin" {
+		notifySiteAnnouncement(&discussion)
+	}
+
 	c.JSON(http.StatusCreated, discussion)
 }
 
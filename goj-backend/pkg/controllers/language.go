@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var languageKeyPattern = regexp.MustCompile(`^[a-z0-9_+-]{1,20}$`)
+
+// LanguageRequest 创建或修改语言的请求
+type LanguageRequest struct {
+	Key    string            `json:"key"`
+	Config config.LangConfig `json:"config" binding:"required"`
+}
+
+// SmokeTestRequest 编译测试请求，Code 为空时使用语言的默认模板
+type SmokeTestRequest struct {
+	Code  string `json:"code"`
+	Input string `json:"input"`
+}
+
+// GetLanguages 获取启用的语言列表（公开）
+func GetLanguages(c *gin.Context) {
+	list := make([]gin.H, 0)
+	for _, key := range config.ActiveLanguageKeys() {
+		lang, _ := config.GetLanguage(key)
+		list = append(list, gin.H{
+			"key":      key,
+			"name":     lang.Name,
+			"version":  lang.Version,
+			"template": lang.Template,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    list,
+	})
+}
+
+// GetAdminLanguages 获取全部语言配置，包括未启用的
+func GetAdminLanguages(c *gin.Context) {
+	var records []models.Language
+	if err := config.DB.Order("`key` ASC").Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取语言列表失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	list := make([]gin.H, 0, len(records))
+	for _, record := range records {
+		var lang config.LangConfig
+		json.Unmarshal([]byte(record.Config), &lang)
+		list = append(list, gin.H{
+			"key":       record.Key,
+			"active":    record.Active,
+			"revision":  record.Revision,
+			"updatedAt": record.UpdatedAt,
+			"config":    lang,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    list,
+	})
+}
+
+// CreateLanguage 新增语言，新语言默认不启用，需要通过编译测试后启用
+func CreateLanguage(c *gin.Context) {
+	var req LanguageRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !languageKeyPattern.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if err := req.Config.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "语言配置不完整: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.Language{}).Where("`key` = ?", req.Key).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "语言已存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	data, _ := json.Marshal(req.Config)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Language{Key: req.Key, Config: string(data), Revision: 1}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LanguageRevision{
+			LanguageKey: req.Key,
+			Revision:    1,
+			Config:      string(data),
+			CreatedBy:   c.GetUint("userID"),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存语言失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建成功，请通过编译测试后启用",
+		"data":    gin.H{"key": req.Key},
+	})
+}
+
+// UpdateLanguage 修改语言配置并生成新版本。已启用的语言必须先通过编译测试，
+// 通过后立即生效并通知所有评测机
+func UpdateLanguage(c *gin.Context) {
+	key := c.Param("key")
+	var req LanguageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if err := req.Config.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "语言配置不完整: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	var record models.Language
+	if err := config.DB.First(&record, "`key` = ?", key).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "语言不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	if record.Active {
+		result, ok := runSmokeTest(c, req.Config, req.Config.Template, "")
+		if !ok {
+			return
+		}
+		if !result.Passed {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "编译测试未通过，配置未保存",
+				"data":    result,
+			})
+			return
+		}
+	}
+
+	data, _ := json.Marshal(req.Config)
+	revision := record.Revision + 1
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&record).Updates(map[string]interface{}{
+			"config":   string(data),
+			"revision": revision,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LanguageRevision{
+			LanguageKey: key,
+			Revision:    revision,
+			Config:      string(data),
+			CreatedBy:   c.GetUint("userID"),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存语言失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	if record.Active {
+		reloadLanguages()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新成功",
+		"data":    gin.H{"revision": revision},
+	})
+}
+
+// TestLanguage 用语言当前的配置编译运行一段代码
+func TestLanguage(c *gin.Context) {
+	lang, ok := loadLanguageConfig(c)
+	if !ok {
+		return
+	}
+
+	var req SmokeTestRequest
+	c.ShouldBindJSON(&req)
+	if req.Code == "" {
+		req.Code = lang.Template
+	}
+
+	result, ok := runSmokeTest(c, lang, req.Code, req.Input)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "测试完成",
+		"data":    result,
+	})
+}
+
+// ActivateLanguage 编译测试通过后启用语言
+func ActivateLanguage(c *gin.Context) {
+	lang, ok := loadLanguageConfig(c)
+	if !ok {
+		return
+	}
+
+	var req SmokeTestRequest
+	c.ShouldBindJSON(&req)
+	if req.Code == "" {
+		req.Code = lang.Template
+	}
+
+	result, ok := runSmokeTest(c, lang, req.Code, req.Input)
+	if !ok {
+		return
+	}
+	if !result.Passed {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "编译测试未通过，无法启用",
+			"data":    result,
+		})
+		return
+	}
+
+	setLanguageActive(c, true, result)
+}
+
+// DeactivateLanguage 停用语言
+func DeactivateLanguage(c *gin.Context) {
+	if _, ok := loadLanguageConfig(c); !ok {
+		return
+	}
+	setLanguageActive(c, false, nil)
+}
+
+// DeleteLanguage 删除未启用的语言
+func DeleteLanguage(c *gin.Context) {
+	key := c.Param("key")
+	var record models.Language
+	if err := config.DB.First(&record, "`key` = ?", key).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "语言不存在",
+			"data":    nil,
+		})
+		return
+	}
+	if record.Active {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请先停用该语言",
+			"data":    nil,
+		})
+		return
+	}
+
+	config.DB.Delete(&record)
+	config.DB.Where("language_key = ?", key).Delete(&models.LanguageRevision{})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+		"data":    nil,
+	})
+}
+
+// GetLanguageRevisions 获取语言配置的历史版本
+func GetLanguageRevisions(c *gin.Context) {
+	var revisions []models.LanguageRevision
+	if err := config.DB.Where("language_key = ?", c.Param("key")).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取历史版本失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    revisions,
+	})
+}
+
+// loadLanguageConfig 读取路径参数对应的语言配置
+func loadLanguageConfig(c *gin.Context) (config.LangConfig, bool) {
+	var lang config.LangConfig
+	var record models.Language
+	if err := config.DB.First(&record, "`key` = ?", c.Param("key")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "语言不存在",
+			"data":    nil,
+		})
+		return lang, false
+	}
+	if err := json.Unmarshal([]byte(record.Config), &lang); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "语言配置格式错误",
+			"data":    nil,
+		})
+		return lang, false
+	}
+	return lang, true
+}
+
+// runSmokeTest 在评测机上运行编译测试，评测机不可用时直接返回错误响应
+func runSmokeTest(c *gin.Context, lang config.LangConfig, code, input string) (*manager.SmokeTestResult, bool) {
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "没有可用于测试的代码，请填写代码模板",
+			"data":    nil,
+		})
+		return nil, false
+	}
+
+	result, err := manager.SmokeTest(config.Judge.JudgeAddr, lang, code, input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "编译测试失败: " + err.Error(),
+			"data":    nil,
+		})
+		return nil, false
+	}
+	return result, true
+}
+
+// setLanguageActive 修改启用状态并通知所有进程
+func setLanguageActive(c *gin.Context, active bool, result *manager.SmokeTestResult) {
+	if err := config.DB.Model(&models.Language{}).
+		Where("`key` = ?", c.Param("key")).
+		Update("active", active).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新语言状态失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	reloadLanguages()
+
+	message := "已停用"
+	if active {
+		message = "已启用"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    result,
+	})
+}
+
+func reloadLanguages() {
+	if err := config.ReloadLanguages(); err != nil {
+		log.Printf("[Language] Failed to reload languages: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/plagiarism"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePlagiarismReportRequest 创建查重报告请求
+type CreatePlagiarismReportRequest struct {
+	Scope     string  `json:"scope" binding:"required"` // contest, problem
+	ScopeID   string  `json:"scopeId" binding:"required"`
+	Threshold float64 `json:"threshold"` // 0~1，不填使用默认值
+}
+
+// CreatePlagiarismReport 为比赛或题目创建查重报告
+func CreatePlagiarismReport(c *gin.Context) {
+	var req CreatePlagiarismReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	report, err := plagiarism.CreateReport(req.Scope, req.ScopeID, req.Threshold, c.GetUint("userID"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "创建查重报告失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查重报告已开始生成",
+		"data":    report,
+	})
+}
+
+// GetPlagiarismReports 获取查重报告列表
+func GetPlagiarismReports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := config.DB.Model(&models.PlagiarismReport{})
+	if scope := c.Query("scope"); scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if scopeID := c.Query("scopeId"); scopeID != "" {
+		query = query.Where("scope_id = ?", scopeID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var reports []models.PlagiarismReport
+	if err := query.Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取查重报告失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"reports": reports,
+			"total":   total,
+		},
+	})
+}
+
+// GetPlagiarismPairs 获取报告中的可疑提交对，按相似度降序
+func GetPlagiarismPairs(c *gin.Context) {
+	reportID := c.Param("id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+	if page < 1 {
+		page = 1
+	}
+
+	var report models.PlagiarismReport
+	if err := config.DB.First(&report, "id = ?", reportID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "查重报告不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	query := config.DB.Model(&models.PlagiarismPair{}).Where("report_id = ?", report.ID)
+	if problemID := c.Query("problemId"); problemID != "" {
+		query = query.Where("problem_id = ?", problemID)
+	}
+	if minScore, err := strconv.ParseFloat(c.Query("minScore"), 64); err == nil {
+		query = query.Where("score >= ?", minScore)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	// 列表中不返回匹配区间，查看详情时再获取
+	var pairs []models.PlagiarismPair
+	if err := query.Omit("matches").
+		Order("score DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&pairs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取可疑提交失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"report": report,
+			"pairs":  pairs,
+			"total":  total,
+		},
+	})
+}
+
+// GetPlagiarismPair 获取一对提交的并排对比，包含双方代码和匹配的行区间
+func GetPlagiarismPair(c *gin.Context) {
+	var pair models.PlagiarismPair
+	if err := config.DB.First(&pair, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "记录不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	var submissions []models.Submission
+	if err := config.DB.Where("id IN ?", []uint{pair.SubmissionA, pair.SubmissionB}).
+		Find(&submissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取提交记录失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	side := func(id uint) gin.H {
+		for _, s := range submissions {
+			if s.ID == id {
+				return gin.H{
+					"submissionId": s.ID,
+					"userId":       s.UserID,
+					"username":     s.Username,
+					"language":     s.Language,
+					"status":       s.Status,
+					"submitTime":   s.SubmitTime,
+					"code":         s.Code,
+				}
+			}
+		}
+		return nil
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"pair":    pair,
+			"left":    side(pair.SubmissionA),
+			"right":   side(pair.SubmissionB),
+			"matches": pair.Matches,
+		},
+	})
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
 	"log"
 	"net/http"
 	"os"
@@ -42,6 +45,78 @@ type AddProblemRequest struct {
 	MemoryLimit int      `json:"memoryLimit" binding:"required,min=16,max=1024"`
 	UseSPJ      bool     `json:"useSPJ"`
 	SPJCode     string   `json:"spjCode"`
+	JudgePolicy string   `json:"judgePolicy"` // run_all, stop_on_failure, stop_per_subtask
+
+	Subtasks []types.Subtask `json:"subtasks"` // 子任务分组，未分组的测试点各自成为一个子任务
+
+	MaxSourceBytes int `json:"maxSourceBytes" binding:"min=0"` // 代码长度上限(字节)，0 表示使用全局上限
+
+	Type        string `json:"type"`        // standard, two_phase, remote
+	ManagerCode string `json:"managerCode"` // 通信题的交互库代码(C++)
+
+	RemoteOJ        string `json:"remoteOJ"`        // 远程题目所在的 OJ
+	RemoteProblemID string `json:"remoteProblemId"` // 远程 OJ 上的题号
+
+	Templates map[string]string `json:"templates"` // 各语言的初始代码，未设置的语言使用语言默认模板
+}
+
+// problemTemplates 返回题目每种语言的初始代码：优先使用题目设置的，否则使用语言默认模板
+func problemTemplates(languages string, starter map[string]string) map[string]string {
+	templates := make(map[string]string)
+	for _, lang := range strings.Split(languages, ",") {
+		if code := starter[lang]; code != "" {
+			templates[lang] = code
+		} else if langConfig, ok := config.GetLanguage(lang); ok && langConfig.Template != "" {
+			templates[lang] = langConfig.Template
+		}
+	}
+	return templates
+}
+
+// validateProblemLanguages 检查题目的语言列表是否都是已启用的语言
+func validateProblemLanguages(languages []string) error {
+	if len(languages) == 0 {
+		return fmt.Errorf("请至少选择一种语言")
+	}
+	for _, lang := range languages {
+		if _, ok := config.GetLanguage(lang); !ok {
+			return fmt.Errorf("语言未启用: %s", lang)
+		}
+	}
+	return nil
+}
+
+// validateSubtasks 检查子任务分组：名称不能为空或重复，每个测试点最多属于一个子任务
+func validateSubtasks(subtasks []types.Subtask) error {
+	names := make(map[string]bool)
+	cases := make(map[string]string)
+	for _, subtask := range subtasks {
+		if subtask.Name == "" {
+			return fmt.Errorf("子任务名称不能为空")
+		}
+		if names[subtask.Name] {
+			return fmt.Errorf("子任务名称重复: %s", subtask.Name)
+		}
+		names[subtask.Name] = true
+		for _, name := range subtask.Cases {
+			if owner, ok := cases[name]; ok {
+				return fmt.Errorf("测试点 %s 同时属于子任务 %s 和 %s", name, owner, subtask.Name)
+			}
+			cases[name] = subtask.Name
+		}
+	}
+	return nil
+}
+
+// problemSubtasks 读取题目目录中 problem.json 已保存的子任务分组
+func problemSubtasks(problemDir string) []types.Subtask {
+	var saved struct {
+		Subtasks []types.Subtask `json:"subtasks"`
+	}
+	if data, err := os.ReadFile(filepath.Join(problemDir, "problem.json")); err == nil {
+		json.Unmarshal(data, &saved)
+	}
+	return saved.Subtasks
 }
 
 // GetProblems 获取题目列表
@@ -220,6 +295,72 @@ func AddProblem(c *gin.Context) {
 		return
 	}
 
+	if !types.IsValidPolicy(req.JudgePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的评测策略",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !types.IsValidProblemType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的题目类型",
+			"data":    nil,
+		})
+		return
+	}
+	if req.Type == "" {
+		req.Type = types.ProblemTypeStandard
+	}
+	if req.Type == types.ProblemTypeRemote && (req.RemoteOJ == "" || req.RemoteProblemID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "远程题目需要指定远程OJ和题号",
+			"data":    nil,
+		})
+		return
+	}
+	if req.Type == types.ProblemTypeTwoPhase && req.ManagerCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "通信题需要提供交互库代码",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := validateProblemLanguages(req.Languages); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := validateSubtasks(req.Subtasks); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	for lang := range req.Templates {
+		if !isLanguageSupported(lang, strings.Join(req.Languages, ",")) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "初始代码的语言不在题目语言列表中: " + lang,
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	// 开启事务
 	tx := config.DB.Begin()
 	if tx.Error != nil {
@@ -279,6 +420,15 @@ func AddProblem(c *gin.Context) {
 		TimeLimit:   int64(req.TimeLimit),
 		MemoryLimit: int64(req.MemoryLimit),
 		UseSPJ:      req.UseSPJ,
+		JudgePolicy: req.JudgePolicy,
+
+		MaxSourceBytes: req.MaxSourceBytes,
+		Type:           req.Type,
+
+		RemoteOJ:        req.RemoteOJ,
+		RemoteProblemID: req.RemoteProblemID,
+
+		CalibrationFactor: manager.ClusterFactor(), // 记录设置时间限制时的评测机速度
 	}
 
 	if err := tx.Create(&problem).Error; err != nil {
@@ -316,6 +466,15 @@ func AddProblem(c *gin.Context) {
 		TimeLimit   int      `json:"timeLimit"`
 		MemoryLimit int      `json:"memoryLimit"`
 		UseSPJ      bool     `json:"useSPJ"`
+		JudgePolicy string   `json:"judgePolicy,omitempty"`
+
+		MaxSourceBytes int               `json:"maxSourceBytes,omitempty"`
+		Type           string            `json:"type,omitempty"`
+		Templates      map[string]string `json:"templates,omitempty"`
+		Subtasks       []types.Subtask   `json:"subtasks,omitempty"`
+
+		RemoteOJ        string `json:"remoteOJ,omitempty"`
+		RemoteProblemID string `json:"remoteProblemId,omitempty"`
 	}{
 		ID:          problemID,
 		Title:       req.Title,
@@ -328,6 +487,15 @@ func AddProblem(c *gin.Context) {
 		UseSPJ:      req.UseSPJ,
 		TimeLimit:   req.TimeLimit,
 		MemoryLimit: req.MemoryLimit,
+		JudgePolicy: req.JudgePolicy,
+
+		MaxSourceBytes: req.MaxSourceBytes,
+		Type:           req.Type,
+		Templates:      req.Templates,
+		Subtasks:       req.Subtasks,
+
+		RemoteOJ:        req.RemoteOJ,
+		RemoteProblemID: req.RemoteProblemID,
 	}
 
 	jsonData, err := json.MarshalIndent(fullProblem, "", "  ")
@@ -365,6 +533,19 @@ func AddProblem(c *gin.Context) {
 		}
 	}
 
+	if req.Type == types.ProblemTypeTwoPhase && req.ManagerCode != "" {
+		managerPath := filepath.Join(problemDir, "manager.cpp")
+		if err := os.WriteFile(managerPath, []byte(req.ManagerCode), 0644); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "保存交互库代码失败",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -583,8 +764,10 @@ func GetProblemDetail(c *gin.Context) {
 	}
 
 	var fullProblem struct {
-		Content string   `json:"content"`
-		Tags    []string `json:"tags"`
+		Content   string            `json:"content"`
+		Tags      []string          `json:"tags"`
+		Templates map[string]string `json:"templates"`
+		Subtasks  []types.Subtask   `json:"subtasks"`
 	}
 
 	if err := json.Unmarshal(data, &fullProblem); err != nil {
@@ -608,12 +791,19 @@ func GetProblemDetail(c *gin.Context) {
 		"tags":            fullProblem.Tags,
 		"role":            problem.Role,
 		"languages":       strings.Split(problem.Languages, ","),
+		"templates":       problemTemplates(problem.Languages, fullProblem.Templates),
 		"timeLimit":       problem.TimeLimit,
 		"memoryLimit":     problem.MemoryLimit,
 		"acceptedCount":   problem.AcceptedCount,
 		"submissionCount": problem.SubmissionCount,
 		"status":          status,
 		"useSPJ":          problem.UseSPJ,
+		"judgePolicy":     problem.JudgePolicy,
+		"subtasks":        fullProblem.Subtasks,
+		"maxSourceBytes":  maxSourceBytes(problem),
+		"type":            problem.Type,
+		"remoteOJ":        problem.RemoteOJ,
+		"remoteProblemId": problem.RemoteProblemID,
 	}
 	log.Printf("Debug - Final status in response: %s", status)
 
@@ -637,6 +827,75 @@ func UpdateProblem(c *gin.Context) {
 		return
 	}
 
+	if !types.IsValidPolicy(req.JudgePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的评测策略",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !types.IsValidProblemType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的题目类型",
+			"data":    nil,
+		})
+		return
+	}
+	if req.Type == "" {
+		req.Type = types.ProblemTypeStandard
+	}
+	if req.Type == types.ProblemTypeRemote && (req.RemoteOJ == "" || req.RemoteProblemID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "远程题目需要指定远程OJ和题号",
+			"data":    nil,
+		})
+		return
+	}
+	if req.Type == types.ProblemTypeTwoPhase && req.ManagerCode == "" {
+		// 未修改交互库时沿用已有的代码
+		if _, err := os.Stat(filepath.Join("data", "problems", problemID, "manager.cpp")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "通信题需要提供交互库代码",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
+	if err := validateProblemLanguages(req.Languages); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := validateSubtasks(req.Subtasks); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	for lang := range req.Templates {
+		if !isLanguageSupported(lang, strings.Join(req.Languages, ",")) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "初始代码的语言不在题目语言列表中: " + lang,
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	// 开启事务
 	tx := config.DB.Begin()
 	if tx.Error != nil {
@@ -659,9 +918,43 @@ func UpdateProblem(c *gin.Context) {
 		TimeLimit:   int64(req.TimeLimit),
 		MemoryLimit: int64(req.MemoryLimit),
 		UseSPJ:      req.UseSPJ,
+		JudgePolicy: req.JudgePolicy,
+
+		MaxSourceBytes: req.MaxSourceBytes,
+		Type:           req.Type,
+
+		RemoteOJ:        req.RemoteOJ,
+		RemoteProblemID: req.RemoteProblemID,
 	}
 
-	if err := tx.Model(&models.Problem{}).Where("id = ?", problemID).Updates(&problem).Error; err != nil {
+	// 时间限制修改后按当前评测机速度重新标定
+	var current models.Problem
+	if err := tx.Select("time_limit").First(&current, "id = ?", problemID).Error; err == nil &&
+		current.TimeLimit != problem.TimeLimit {
+		if err := tx.Model(&models.Problem{}).Where("id = ?", problemID).
+			Update("calibration_factor", manager.ClusterFactor()).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "更新题目失败",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
+	// 显式列出更新的字段，零值（如取消 SPJ、清空代码长度上限）也会写入；
+	// 编辑页未提交评测策略时保留原有设置
+	columns := []string{
+		"title", "difficulty", "role", "tag", "source", "languages",
+		"time_limit", "memory_limit", "use_spj", "max_source_bytes",
+		"type", "remote_oj", "remote_problem_id",
+	}
+	if req.JudgePolicy != "" {
+		columns = append(columns, "judge_policy")
+	}
+	if err := tx.Model(&models.Problem{}).Where("id = ?", problemID).
+		Select(columns).Updates(&problem).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -673,6 +966,11 @@ func UpdateProblem(c *gin.Context) {
 
 	// 更新JSON文件
 	problemDir := filepath.Join("data", "problems", problemID)
+
+	// 请求中没有子任务分组时沿用已有的分组
+	if req.Subtasks == nil {
+		req.Subtasks = problemSubtasks(problemDir)
+	}
 	fullProblem := struct {
 		ID          string   `json:"id"`
 		Title       string   `json:"title"`
@@ -685,6 +983,15 @@ func UpdateProblem(c *gin.Context) {
 		TimeLimit   int      `json:"timeLimit"`
 		MemoryLimit int      `json:"memoryLimit"`
 		UseSPJ      bool     `json:"useSPJ"`
+		JudgePolicy string   `json:"judgePolicy,omitempty"`
+
+		MaxSourceBytes int               `json:"maxSourceBytes,omitempty"`
+		Type           string            `json:"type,omitempty"`
+		Templates      map[string]string `json:"templates,omitempty"`
+		Subtasks       []types.Subtask   `json:"subtasks,omitempty"`
+
+		RemoteOJ        string `json:"remoteOJ,omitempty"`
+		RemoteProblemID string `json:"remoteProblemId,omitempty"`
 	}{
 		ID:          problemID,
 		Title:       req.Title,
@@ -697,6 +1004,15 @@ func UpdateProblem(c *gin.Context) {
 		TimeLimit:   req.TimeLimit,
 		MemoryLimit: req.MemoryLimit,
 		UseSPJ:      req.UseSPJ,
+		JudgePolicy: req.JudgePolicy,
+
+		MaxSourceBytes: req.MaxSourceBytes,
+		Type:           req.Type,
+		Templates:      req.Templates,
+		Subtasks:       req.Subtasks,
+
+		RemoteOJ:        req.RemoteOJ,
+		RemoteProblemID: req.RemoteProblemID,
 	}
 
 	jsonData, err := json.MarshalIndent(fullProblem, "", "  ")
@@ -734,6 +1050,19 @@ func UpdateProblem(c *gin.Context) {
 		}
 	}
 
+	if req.Type == types.ProblemTypeTwoPhase && req.ManagerCode != "" {
+		managerPath := filepath.Join(problemDir, "manager.cpp")
+		if err := os.WriteFile(managerPath, []byte(req.ManagerCode), 0644); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "保存交互库代码失败",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1047,14 +1376,9 @@ func GetContestProblem(c *gin.Context) {
 	contestID := c.Param("contestId")
 	problemID := c.Param("problemId")
 
-	// 检查比赛是否存在
-	var contest models.Contest
-	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "比赛不存在",
-			"data":    nil,
-		})
+	// 检查比赛是否存在及访问权限
+	contest, ok := requireContestAccess(c, contestID)
+	if !ok {
 		return
 	}
 
@@ -1069,17 +1393,17 @@ func GetContestProblem(c *gin.Context) {
 		return
 	}
 
-	// 检查题目是否属于该比赛
-	problemIDs := strings.Split(contest.Problems, ",")
-	found := false
-	for _, id := range problemIDs {
-		if id == problemID {
-			found = true
+	// 检查题目是否属于该比赛，同时获取题目在比赛中的设置
+	contestProblems, _ := models.LoadContestProblems(config.DB, contest)
+	var contestProblem *models.ContestProblem
+	for i := range contestProblems {
+		if contestProblems[i].ProblemID == problemID {
+			contestProblem = &contestProblems[i]
 			break
 		}
 	}
 
-	if !found {
+	if contestProblem == nil {
 		c.JSON(http.StatusForbidden, gin.H{
 			"code":    403,
 			"message": "该题目不属于此比赛",
@@ -1138,8 +1462,9 @@ func GetContestProblem(c *gin.Context) {
 	}
 
 	var fullProblem struct {
-		Content string   `json:"content"`
-		Tags    []string `json:"tags"`
+		Content   string            `json:"content"`
+		Tags      []string          `json:"tags"`
+		Templates map[string]string `json:"templates"`
 	}
 
 	if err := json.Unmarshal(data, &fullProblem); err != nil {
@@ -1153,16 +1478,24 @@ func GetContestProblem(c *gin.Context) {
 	}
 	log.Printf("Debug - Successfully parsed problem data")
 
+	if contestProblem.Title != "" {
+		problem.Title = contestProblem.Title
+	}
+
 	// 组合返回数据
 	response := gin.H{
 		"id":              problem.ID,
 		"title":           problem.Title,
+		"label":           contestProblem.Label,
+		"maxScore":        contestProblem.MaxScore,
+		"color":           contestProblem.Color,
 		"content":         fullProblem.Content,
 		"difficulty":      problem.Difficulty,
 		"source":          problem.Source,
 		"tags":            fullProblem.Tags,
 		"role":            problem.Role,
 		"languages":       strings.Split(problem.Languages, ","),
+		"templates":       problemTemplates(problem.Languages, fullProblem.Templates),
 		"timeLimit":       problem.TimeLimit,
 		"memoryLimit":     problem.MemoryLimit,
 		"acceptedCount":   problem.AcceptedCount,
@@ -1189,19 +1522,26 @@ func GetContestProblems(c *gin.Context) {
 		userID = id.(uint)
 	}
 
-	// 获取比赛信息
-	var contest models.Contest
-	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "比赛不存在",
+	// 获取比赛信息并检查访问权限
+	contest, ok := requireContestAccess(c, contestID)
+	if !ok {
+		return
+	}
+
+	// 按比赛中的顺序获取题目
+	contestProblems, err := models.LoadContestProblems(config.DB, contest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取题目列表失败",
 			"data":    nil,
 		})
 		return
 	}
-
-	// 获取所有题目
-	problemIDs := strings.Split(contest.Problems, ",")
+	problemIDs := make([]string, 0, len(contestProblems))
+	for _, cp := range contestProblems {
+		problemIDs = append(problemIDs, cp.ProblemID)
+	}
 	var problems []models.Problem
 	if err := config.DB.Where("id IN ?", problemIDs).Find(&problems).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1244,24 +1584,51 @@ func GetContestProblems(c *gin.Context) {
 				statusMap[p.ID] = "unattempted"
 			}
 		}
+
+		// 赛制在比赛结束前隐藏结果时，不显示题目是否通过
+		if c.GetString("role") != "admin" && scoreboard.ResultsHidden(contest, time.Now()) {
+			for problemID, status := range statusMap {
+				if status == string(models.StatusAccepted) {
+					statusMap[problemID] = string(models.StatusAttempted)
+				}
+			}
+		}
 	}
 
-	// 构建带状态的题目列表
+	// 构建带状态的题目列表，按比赛中的顺序排列
 	type ProblemWithStatus struct {
 		models.Problem
-		Status string `json:"status"`
+		Status   string `json:"status"`
+		Label    string `json:"label"`
+		MaxScore int    `json:"maxScore"`
+		Color    string `json:"color"`
 	}
 
-	var problemsWithStatus []ProblemWithStatus
+	problemMap := make(map[string]models.Problem, len(problems))
 	for _, p := range problems {
+		problemMap[p.ID] = p
+	}
+
+	var problemsWithStatus []ProblemWithStatus
+	for _, cp := range contestProblems {
+		p, exists := problemMap[cp.ProblemID]
+		if !exists {
+			continue
+		}
+		if cp.Title != "" {
+			p.Title = cp.Title
+		}
 		status := statusMap[p.ID]
 		if status == "" {
 			status = "unattempted"
 		}
 		log.Printf("Debug - Final status for problem %s: %s", p.ID, status)
 		problemsWithStatus = append(problemsWithStatus, ProblemWithStatus{
-			Problem: p,
-			Status:  status,
+			Problem:  p,
+			Status:   status,
+			Label:    cp.Label,
+			MaxScore: cp.MaxScore,
+			Color:    cp.Color,
 		})
 	}
 
@@ -1278,6 +1645,46 @@ func GetContestProblems(c *gin.Context) {
 	})
 }
 
+// GetProblemManagerCode 获取通信题的交互库代码
+func GetProblemManagerCode(c *gin.Context) {
+	problemID := c.Param("id")
+
+	var problem models.Problem
+	if err := config.DB.First(&problem, "id = ?", problemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "题目不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	if problem.Type != types.ProblemTypeTwoPhase {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "该题目不是通信题",
+			"data":    nil,
+		})
+		return
+	}
+
+	managerCode, err := os.ReadFile(filepath.Join("data", "problems", problemID, "manager.cpp"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "交互库代码文件不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    string(managerCode),
+	})
+}
+
 // GetProblemSPJCode 获取题目的SPJ代码
 func GetProblemSPJCode(c *gin.Context) {
 	problemID := c.Param("id")
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
 	"io"
 	"net/http"
@@ -179,12 +180,37 @@ func importProblem(tx *gorm.DB, zipPath string, importedCount *int) error {
 		TimeLimit   int      `json:"timeLimit"`
 		MemoryLimit int      `json:"memoryLimit"`
 		UseSPJ      bool     `json:"useSPJ"`
+		JudgePolicy string   `json:"judgePolicy,omitempty"`
+
+		MaxSourceBytes int               `json:"maxSourceBytes,omitempty"`
+		Type           string            `json:"type,omitempty"`
+		Templates      map[string]string `json:"templates,omitempty"`
+		Subtasks       []types.Subtask   `json:"subtasks,omitempty"`
+
+		RemoteOJ        string `json:"remoteOJ,omitempty"`
+		RemoteProblemID string `json:"remoteProblemId,omitempty"`
 	}
 
 	if err := json.Unmarshal(jsonData, &problemInfo); err != nil {
 		return fmt.Errorf("解析题目信息失败: %v", err)
 	}
 
+	// 只保留已启用的语言
+	activeLanguages := make([]string, 0, len(problemInfo.Languages))
+	for _, lang := range problemInfo.Languages {
+		if _, ok := config.GetLanguage(lang); ok {
+			activeLanguages = append(activeLanguages, lang)
+		}
+	}
+	if len(activeLanguages) == 0 {
+		return fmt.Errorf("题目 %s 没有可用的语言", problemInfo.Title)
+	}
+	problemInfo.Languages = activeLanguages
+
+	if err := validateSubtasks(problemInfo.Subtasks); err != nil {
+		return fmt.Errorf("题目 %s 的子任务分组无效: %v", problemInfo.Title, err)
+	}
+
 	// 生成新的题目ID
 	var seq struct {
 		ID uint
@@ -221,6 +247,17 @@ func importProblem(tx *gorm.DB, zipPath string, importedCount *int) error {
 		TimeLimit:   int64(problemInfo.TimeLimit),
 		MemoryLimit: int64(problemInfo.MemoryLimit),
 		UseSPJ:      problemInfo.UseSPJ,
+
+		MaxSourceBytes: problemInfo.MaxSourceBytes,
+
+		RemoteOJ:        problemInfo.RemoteOJ,
+		RemoteProblemID: problemInfo.RemoteProblemID,
+	}
+	if types.IsValidPolicy(problemInfo.JudgePolicy) {
+		problem.JudgePolicy = problemInfo.JudgePolicy
+	}
+	if problemInfo.Type != "" && types.IsValidProblemType(problemInfo.Type) {
+		problem.Type = problemInfo.Type
 	}
 
 	// 保存到数据库
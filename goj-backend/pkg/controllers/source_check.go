@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 编译后的检查规则缓存，语言配置热更新后按新的表达式重新编译
+var filterPatterns sync.Map // pattern -> *regexp.Regexp
+
+// maxSourceBytes 题目的代码长度上限，未单独设置时使用全局上限
+func maxSourceBytes(problem models.Problem) int {
+	if problem.MaxSourceBytes > 0 {
+		return problem.MaxSourceBytes
+	}
+	return config.Judge.MaxSourceBytes
+}
+
+// checkSource 按语言的检查规则检查代码，返回被拒绝的原因，通过时返回空字符串
+func checkSource(lang config.LangConfig, code string, inContest bool) string {
+	var stripped *string // 去掉注释和字符串后的代码，用到时才生成
+	for _, filter := range lang.Filters {
+		if filter.ContestOnly && !inContest {
+			continue
+		}
+
+		var re *regexp.Regexp
+		if cached, ok := filterPatterns.Load(filter.Pattern); ok {
+			re = cached.(*regexp.Regexp)
+		} else {
+			compiled, err := regexp.Compile(filter.Pattern)
+			if err != nil {
+				continue
+			}
+			filterPatterns.Store(filter.Pattern, compiled)
+			re = compiled
+		}
+
+		target := code
+		if filter.Scope == config.FilterScopeCode {
+			if stripped == nil {
+				text := stripLiterals(code, hashComments(lang.Filename))
+				stripped = &text
+			}
+			target = *stripped
+		}
+
+		if loc := re.FindStringIndex(target); loc != nil {
+			message := filter.Message
+			if message == "" {
+				message = "代码包含禁止使用的内容"
+			}
+			return fmt.Sprintf("%s (第 %d 行)", message, lineOf(code, loc[0]))
+		}
+	}
+	return ""
+}
+
+// lineOf 返回偏移量所在的行号
+func lineOf(code string, offset int) int {
+	line := 1
+	for i := 0; i < offset && i < len(code); i++ {
+		if code[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
+
+// hashComments 按源文件扩展名判断是否使用 # 注释，其余语言按 C 风格的 // 和 /* */ 处理
+func hashComments(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".py", ".rb", ".sh", ".pl":
+		return true
+	}
+	return false
+}
+
+// stripLiterals 把注释和字符串字面量的内容替换为空格，保留换行和字符串的引号，
+// 结果与原代码等长，匹配到的偏移量可以直接换算行号
+func stripLiterals(code string, hash bool) string {
+	out := []byte(code)
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	for i := 0; i < len(code); {
+		switch {
+		case hash && code[i] == '#', !hash && strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				end = len(code) - i
+			}
+			blank(i, i+end)
+			i += end
+		case !hash && strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				blank(i, len(code))
+				return string(out)
+			}
+			blank(i, i+end+4)
+			i += end + 4
+		case hash && (strings.HasPrefix(code[i:], `"""`) || strings.HasPrefix(code[i:], "'''")):
+			quote := code[i : i+3]
+			end := strings.Index(code[i+3:], quote)
+			if end < 0 {
+				blank(i+3, len(code))
+				return string(out)
+			}
+			blank(i+3, i+3+end)
+			i += end + 6
+		case !hash && code[i] == '\'' && i > 0 && code[i-1] >= '0' && code[i-1] <= '9':
+			// C++14 的数字分隔符，如 1'000'000
+			i++
+		case code[i] == '"' || code[i] == '\'' || (!hash && code[i] == '`'):
+			quote := code[i]
+			j := i + 1
+			for j < len(code) && code[j] != quote {
+				// 反引号字符串不处理转义；普通字符串不跨行
+				if quote != '`' && code[j] == '\\' {
+					j++
+				} else if quote != '`' && code[j] == '\n' {
+					break
+				}
+				j++
+			}
+			blank(i+1, j)
+			i = j + 1
+		default:
+			i++
+		}
+	}
+	return string(out)
+}
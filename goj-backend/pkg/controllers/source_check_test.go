@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+)
+
+func TestStripLiterals(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		hash bool
+		want string
+	}{
+		{"行注释", "int a; // asm\nint b;", false, "int a;       \nint b;"},
+		{"块注释跨行", "a /* x\ny */ b", false, "a     \n     b"},
+		{"未闭合的块注释", "a /* asm", false, "a       "},
+		{"字符串", `puts("asm");`, false, `puts("   ");`},
+		{"转义引号", `s = "a\"asm";`, false, `s = "      ";`},
+		{"字符字面量", `c = '"'; asm`, false, `c = ' '; asm`},
+		{"数字分隔符", "x = 1'000; asm", false, "x = 1'000; asm"},
+		{"反引号字符串", "s := `asm\n`", false, "s := `   \n`"},
+		{"井号注释", "import sys # import os\n", true, "import sys" + strings.Repeat(" ", 12) + "\n"},
+		{"三引号字符串", "'''\nimport os\n'''\nx", true, "'''\n         \n'''\nx"},
+		{"C 语言中的井号不是注释", "#include <stdio.h>", false, "#include <stdio.h>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripLiterals(tt.code, tt.hash)
+			if got != tt.want {
+				t.Errorf("stripLiterals(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+			if len(got) != len(tt.code) {
+				t.Errorf("length changed: %d -> %d", len(tt.code), len(got))
+			}
+		})
+	}
+}
+
+func TestCheckSourceScope(t *testing.T) {
+	asm := config.SourceFilter{Pattern: `\basm\b`, Message: "禁止使用内联汇编", Scope: config.FilterScopeCode}
+	include := config.SourceFilter{Pattern: `#\s*include\s*[<"]\s*/`, Message: "禁止包含绝对路径的文件"}
+	contestOnly := config.SourceFilter{Pattern: `\bsystem\s*\(`, Message: "禁止调用系统命令", ContestOnly: true}
+	lang := config.LangConfig{Filename: "Main.cpp", Filters: []config.SourceFilter{asm, include, contestOnly}}
+
+	tests := []struct {
+		name      string
+		code      string
+		inContest bool
+		want      string
+	}{
+		{"注释中的关键字", "// asm\nint main() {}", false, ""},
+		{"字符串中的关键字", "int main() { puts(\"asm\"); }", false, ""},
+		{"代码中的关键字", "int main() {\n  asm(\"nop\");\n}", false, "禁止使用内联汇编 (第 2 行)"},
+		{"默认范围匹配字符串内容", "#include \"/etc/passwd\"", false, "禁止包含绝对路径的文件 (第 1 行)"},
+		{"比赛外不检查", "int main() { system(\"ls\"); }", false, ""},
+		{"比赛中检查", "int main() { system(\"ls\"); }", true, "禁止调用系统命令 (第 1 行)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkSource(lang, tt.code, tt.inContest); got != tt.want {
+				t.Errorf("checkSource(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
@@ -68,6 +69,65 @@ func CreateSubmission(c *gin.Context) {
 		return
 	}
 
+	// 检查代码长度
+	if limit := maxSourceBytes(problem); limit > 0 && len(req.Code) > limit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("代码长度超过限制 (%d 字节，上限 %d 字节)", len(req.Code), limit),
+			"data":    nil,
+		})
+		return
+	}
+
+	// 比赛提交只接受有权访问的参赛者，管理员除外。
+	// 比赛结束后在虚拟参赛时间内的提交记为虚拟提交，团队赛中的提交计入参赛者所在的队伍
+	var contest models.Contest
+	var virtualID, teamID uint
+	if req.ContestID != "" {
+		contestPtr, ok := requireContestAccess(c, req.ContestID)
+		if !ok {
+			return
+		}
+		contest = *contestPtr
+
+		if virtual := activeVirtualParticipation(req.ContestID, userID, time.Now()); virtual != nil {
+			if !containsProblem(contest.Problems, req.ProblemID) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    400,
+					"message": "题目不在比赛中",
+					"data":    nil,
+				})
+				return
+			}
+			virtualID = virtual.ID
+		} else if c.GetString("role") != "admin" {
+			participant := getContestParticipant(req.ContestID, userID)
+			if participant == nil {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "未报名该比赛",
+					"data":    nil,
+				})
+				return
+			}
+
+			teamID = participant.TeamID
+
+			// 比赛进行中首次提交时标记为正在参赛，团队赛中整支队伍一起标记
+			now := time.Now()
+			if participant.Status == models.ParticipantRegistered &&
+				now.After(contest.StartTime) && now.Before(contest.EndTime) {
+				if teamID != 0 {
+					config.DB.Model(&models.ContestParticipant{}).
+						Where("contest_id = ? AND team_id = ?", req.ContestID, teamID).
+						Update("status", models.ParticipantParticipating)
+				} else {
+					config.DB.Model(participant).Update("status", models.ParticipantParticipating)
+				}
+			}
+		}
+	}
+
 	// 添加数据库连接检查
 	if err := config.DB.Raw("SELECT 1").Error; err != nil {
 		log.Printf("[Submission] Database connection error: %v", err)
@@ -84,6 +144,8 @@ func CreateSubmission(c *gin.Context) {
 		UserID:     userID,
 		ProblemID:  req.ProblemID,
 		ContestID:  req.ContestID,
+		VirtualID:  virtualID,
+		TeamID:     teamID,
 		Language:   req.Language,
 		Code:       req.Code,
 		Status:     types.StatusPending,
@@ -103,6 +165,27 @@ func CreateSubmission(c *gin.Context) {
 
 	log.Printf("[Submission] Successfully saved submission with ID: %d", submission.ID)
 
+	// 编译前检查，未通过的提交直接给出结果，不进入评测队列
+	langConfig, _ := config.GetLanguage(req.Language)
+	if reason := checkSource(langConfig, req.Code, req.ContestID != ""); reason != "" {
+		now := time.Now()
+		config.DB.Model(&submission).Updates(map[string]interface{}{
+			"status":     types.StatusSourceRejected,
+			"error_info": reason,
+			"judge_time": &now,
+		})
+
+		log.Printf("[Submission] Submission %d rejected by source filter: %s", submission.ID, reason)
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "代码未通过检查: " + reason,
+			"data": gin.H{
+				"id": submission.ID,
+			},
+		})
+		return
+	}
+
 	// 创建评测任务
 	task := &types.JudgeTask{
 		ID:          submission.ID,
@@ -113,6 +196,18 @@ func CreateSubmission(c *gin.Context) {
 		TimeLimit:   problem.TimeLimit,
 		MemoryLimit: problem.MemoryLimit,
 		UseSPJ:      problem.UseSPJ,
+		Policy:      problem.JudgePolicy,
+		ProblemType: problem.Type,
+
+		RemoteOJ:        problem.RemoteOJ,
+		RemoteProblemID: problem.RemoteProblemID,
+
+		CalibrationFactor: problem.CalibrationFactor,
+	}
+
+	// 比赛可以覆盖题目的评测策略
+	if contest.JudgePolicy != "" {
+		task.Policy = contest.JudgePolicy
 	}
 
 	// 打印任务信息
@@ -145,8 +240,11 @@ func CreateSubmission(c *gin.Context) {
 	})
 }
 
-// 检查语言是否支持
+// 检查语言是否支持：需要在题目的语言列表中且当前已启用
 func isLanguageSupported(lang, supportedLangs string) bool {
+	if _, ok := config.GetLanguage(lang); !ok {
+		return false
+	}
 	langs := strings.Split(supportedLangs, ",")
 	for _, l := range langs {
 		if l == lang {
@@ -222,6 +320,33 @@ func GetSubmissions(c *gin.Context) {
 		return
 	}
 
+	// 按比赛赛制和封榜状态隐藏评测结果，按状态筛选时不返回被隐藏的提交
+	if c.GetString("role") != "admin" {
+		contests := make(map[string]*models.Contest)
+		visible := submissions[:0]
+		for i := range submissions {
+			sub := &submissions[i].Submission
+			if sub.ContestID != "" {
+				contest, ok := contests[sub.ContestID]
+				if !ok {
+					contest = &models.Contest{}
+					if err := config.DB.First(contest, "id = ?", sub.ContestID).Error; err != nil {
+						contest = nil
+					}
+					contests[sub.ContestID] = contest
+				}
+				if contest != nil && contestResultHidden(c, contest, sub) {
+					if status != "" {
+						continue
+					}
+					maskSubmissionResult(sub)
+				}
+			}
+			visible = append(visible, submissions[i])
+		}
+		submissions = visible
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "获取成功",
@@ -294,6 +419,15 @@ func GetSubmissionDetail(c *gin.Context) {
 		}
 	}
 
+	// 按比赛赛制和封榜状态隐藏评测结果
+	if submission.ContestID != "" {
+		var contest models.Contest
+		if err := config.DB.First(&contest, "id = ?", submission.ContestID).Error; err == nil &&
+			contestResultHidden(c, &contest, &submission.Submission) {
+			maskSubmissionResult(&submission.Submission)
+		}
+	}
+
 	// 构造返回数据
 	response := gin.H{
 		"id":              submission.ID,
@@ -328,3 +462,36 @@ func GetSubmissionDetail(c *gin.Context) {
 		"data": response,
 	})
 }
+
+// GetSubmissionTrace 获取提交的评测追踪（仅管理员）
+func GetSubmissionTrace(c *gin.Context) {
+	var record models.JudgeTrace
+	if err := config.DB.First(&record, "submission_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "该提交没有评测追踪记录",
+			"data":    nil,
+		})
+		return
+	}
+
+	trace, err := types.DecodeTrace(record.Data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "解析评测追踪失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"trace":     trace,
+			"size":      record.Size,
+			"createdAt": record.CreatedAt,
+		},
+	})
+}
@@ -0,0 +1,668 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 队伍请求结构
+type TeamRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+// TeamMemberInfo 队员信息
+type TeamMemberInfo struct {
+	UserID   uint   `json:"userId"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Rating   int64  `json:"rating"`
+	Status   string `json:"status"` // invited, joined
+}
+
+// TeamInfo 队伍详情
+type TeamInfo struct {
+	models.Team
+	Members []TeamMemberInfo `json:"members"`
+	Locked  bool             `json:"locked"` // 报名了尚未结束的比赛，不能调整队员
+}
+
+// 队伍已有队员报名比赛时返回的错误
+var errTeamMemberRegistered = errors.New("team member already registered")
+
+// 获取队伍的队员（包括尚未接受邀请的用户），队长在前
+func getTeamMembers(db *gorm.DB, team *models.Team) ([]TeamMemberInfo, error) {
+	members := make([]TeamMemberInfo, 0)
+	if err := db.Table("team_members").
+		Select("team_members.user_id, users.username, users.avatar, users.rating, team_members.status").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Where("team_members.team_id = ?", team.ID).
+		Order("team_members.created_at").
+		Scan(&members).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].UserID == team.CaptainID && members[j].UserID != team.CaptainID
+	})
+	return members, nil
+}
+
+// 队伍是否报名了尚未结束的比赛，此时队员名单已提交给比赛，不能调整
+func teamLocked(teamID uint) bool {
+	var count int64
+	config.DB.Table("contest_participants").
+		Joins("JOIN contests ON contests.id = contest_participants.contest_id").
+		Where("contest_participants.team_id = ? AND contest_participants.deleted_at IS NULL AND contests.end_time > ?", teamID, time.Now()).
+		Count(&count)
+	return count > 0
+}
+
+// 组装队伍详情
+func teamInfo(team *models.Team) (*TeamInfo, error) {
+	members, err := getTeamMembers(config.DB, team)
+	if err != nil {
+		return nil, err
+	}
+	return &TeamInfo{
+		Team:    *team,
+		Members: members,
+		Locked:  teamLocked(team.ID),
+	}, nil
+}
+
+// 获取队伍，不存在时返回错误响应
+func requireTeam(c *gin.Context, teamID string) (*models.Team, bool) {
+	var team models.Team
+	if err := config.DB.First(&team, "id = ?", teamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "队伍不存在",
+			"data":    nil,
+		})
+		return nil, false
+	}
+	return &team, true
+}
+
+// 获取当前用户担任队长的队伍，不是队长时返回错误响应
+func requireTeamCaptain(c *gin.Context, teamID string) (*models.Team, bool) {
+	team, ok := requireTeam(c, teamID)
+	if !ok {
+		return nil, false
+	}
+	if team.CaptainID != c.GetUint("userID") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "只有队长可以执行此操作",
+			"data":    nil,
+		})
+		return nil, false
+	}
+	return team, true
+}
+
+// 队伍已报名未结束的比赛时返回错误响应
+func rejectLockedTeam(c *gin.Context, team *models.Team) bool {
+	if !teamLocked(team.ID) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    400,
+		"message": "队伍已报名尚未结束的比赛，不能调整队员",
+		"data":    nil,
+	})
+	return true
+}
+
+// 整理并校验队伍信息，返回错误信息
+func validateTeamRequest(req *TeamRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Name == "" {
+		return "队名不能为空"
+	}
+	return ""
+}
+
+// 队名是否已被其他队伍使用
+func teamNameTaken(name string, excludeID uint) bool {
+	var count int64
+	config.DB.Model(&models.Team{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count)
+	return count > 0
+}
+
+// 获取用户在队伍中的记录，不在队伍中时返回 nil
+func getTeamMember(teamID, userID uint) *models.TeamMember {
+	var member models.TeamMember
+	if err := config.DB.Where("team_id = ? AND user_id = ?", teamID, userID).
+		First(&member).Error; err != nil {
+		return nil
+	}
+	return &member
+}
+
+// 通知用户收到队伍邀请，不在线的用户在我的队伍页面查看
+func notifyTeamInvitation(team *models.Team, userID uint) {
+	ws := handler.GetWebSocketManager()
+	if ws == nil {
+		return
+	}
+	ws.SendToUser(userID, handler.WebSocketMessage{
+		Type: "team_invitation",
+		Data: gin.H{
+			"teamId":   team.ID,
+			"teamName": team.Name,
+		},
+	})
+}
+
+// GetMyTeams 获取当前用户所在的队伍，包括收到邀请尚未接受的队伍
+func GetMyTeams(c *gin.Context) {
+	var teams []models.Team
+	if err := config.DB.
+		Joins("JOIN team_members ON team_members.team_id = teams.id").
+		Where("team_members.user_id = ?", c.GetUint("userID")).
+		Order("teams.id").
+		Find(&teams).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取队伍失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	list := make([]*TeamInfo, 0, len(teams))
+	for i := range teams {
+		info, err := teamInfo(&teams[i])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取队伍失败",
+				"data":    nil,
+			})
+			return
+		}
+		list = append(list, info)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"teams":      list,
+			"maxMembers": models.MaxTeamMembers,
+		},
+	})
+}
+
+// GetTeam 获取队伍详情
+func GetTeam(c *gin.Context) {
+	team, ok := requireTeam(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	info, err := teamInfo(team)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取队伍失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    info,
+	})
+}
+
+// CreateTeam 创建队伍，创建者为队长
+func CreateTeam(c *gin.Context) {
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if message := validateTeamRequest(&req); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+	if teamNameTaken(req.Name, 0) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "队名已被使用",
+			"data":    nil,
+		})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	team := models.Team{
+		Name:        req.Name,
+		Description: req.Description,
+		CaptainID:   userID,
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&team).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.TeamMember{TeamID: team.ID, UserID: userID, Status: models.TeamMemberJoined}).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建队伍失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建成功",
+		"data":    team,
+	})
+}
+
+// UpdateTeam 队长修改队名和简介
+func UpdateTeam(c *gin.Context) {
+	team, ok := requireTeamCaptain(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if message := validateTeamRequest(&req); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+	if teamNameTaken(req.Name, team.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "队名已被使用",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := config.DB.Model(team).Updates(map[string]interface{}{
+		"name":        req.Name,
+		"description": req.Description,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新队伍失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	// 榜单中缓存了队名
+	var contestIDs []string
+	config.DB.Model(&models.ContestParticipant{}).Where("team_id = ?", team.ID).
+		Distinct().Pluck("contest_id", &contestIDs)
+	for _, contestID := range contestIDs {
+		ClearContestRankCache(contestID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新成功",
+		"data":    team,
+	})
+}
+
+// DeleteTeam 队长解散队伍，参加过比赛的队伍保留以便查看历史成绩
+func DeleteTeam(c *gin.Context) {
+	team, ok := requireTeamCaptain(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.ContestParticipant{}).Where("team_id = ?", team.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "队伍已参加过比赛，不能解散",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(team).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "解散队伍失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已解散队伍",
+		"data":    nil,
+	})
+}
+
+// AddTeamMember 队长按用户名邀请队员，用户接受后才成为队员
+func AddTeamMember(c *gin.Context) {
+	team, ok := requireTeamCaptain(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if rejectLockedTeam(c, team) {
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Select("id").Where("username = ?", strings.TrimSpace(req.Username)).
+		First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "用户不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	var message string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var count, existing int64
+		if err := tx.Model(&models.TeamMember{}).Where("team_id = ?", team.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TeamMember{}).Where("team_id = ? AND user_id = ?", team.ID, user.ID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			message = "该用户已在队伍中或已被邀请"
+			return nil
+		}
+		// 待接受的邀请也占用名额
+		if count >= models.MaxTeamMembers {
+			message = "队伍人数已满"
+			return nil
+		}
+		return tx.Create(&models.TeamMember{TeamID: team.ID, UserID: user.ID, Status: models.TeamMemberInvited}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "邀请队员失败",
+			"data":    nil,
+		})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	notifyTeamInvitation(team, user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已发送邀请",
+		"data":    nil,
+	})
+}
+
+// AcceptTeamInvitation 用户接受队伍邀请
+func AcceptTeamInvitation(c *gin.Context) {
+	team, ok := requireTeam(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	member := getTeamMember(team.ID, c.GetUint("userID"))
+	if member == nil || member.Status != models.TeamMemberInvited {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "没有该队伍的邀请",
+			"data":    nil,
+		})
+		return
+	}
+	if rejectLockedTeam(c, team) {
+		return
+	}
+
+	if err := config.DB.Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id = ?", member.TeamID, member.UserID).
+		Update("status", models.TeamMemberJoined).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "加入队伍失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已加入队伍",
+		"data":    nil,
+	})
+}
+
+// RemoveTeamMember 队长移除队员或撤回邀请，队员也可以自行退出或拒绝邀请。队长需要先转让队长才能退出
+func RemoveTeamMember(c *gin.Context) {
+	team, ok := requireTeam(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+	userID := c.GetUint("userID")
+	if team.CaptainID != userID && uint(memberID) != userID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "只有队长可以移除队员",
+			"data":    nil,
+		})
+		return
+	}
+	if uint(memberID) == team.CaptainID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "队长不能退出队伍，请先转让队长或解散队伍",
+			"data":    nil,
+		})
+		return
+	}
+	member := getTeamMember(team.ID, uint(memberID))
+	if member == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "该用户不在队伍中",
+			"data":    nil,
+		})
+		return
+	}
+	// 邀请不影响已报名的比赛，随时可以拒绝或撤回
+	if member.Status == models.TeamMemberJoined && rejectLockedTeam(c, team) {
+		return
+	}
+
+	if err := config.DB.Where("team_id = ? AND user_id = ?", team.ID, memberID).
+		Delete(&models.TeamMember{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "移除队员失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除成功",
+		"data":    nil,
+	})
+}
+
+// TransferTeamCaptain 队长将队长转让给其他队员
+func TransferTeamCaptain(c *gin.Context) {
+	team, ok := requireTeamCaptain(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	if member := getTeamMember(team.ID, req.UserID); member == nil || member.Status != models.TeamMemberJoined {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "该用户不在队伍中",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := config.DB.Model(team).Update("captain_id", req.UserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "转让队长失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "转让成功",
+		"data":    nil,
+	})
+}
+
+// 为队伍已加入的每名队员添加团队赛参赛记录，未接受邀请的用户不报名。
+// 有队员已报名该比赛时返回 errTeamMemberRegistered 及其用户名
+func addContestTeam(tx *gorm.DB, contestID string, team *models.Team) ([]string, error) {
+	var userIDs []uint
+	if err := tx.Model(&models.TeamMember{}).
+		Where("team_id = ? AND status = ?", team.ID, models.TeamMemberJoined).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var registered []string
+	if err := tx.Table("contest_participants").
+		Joins("JOIN users ON users.id = contest_participants.user_id").
+		Where("contest_participants.contest_id = ? AND contest_participants.user_id IN ? AND contest_participants.deleted_at IS NULL", contestID, userIDs).
+		Pluck("users.username", &registered).Error; err != nil {
+		return nil, err
+	}
+	if len(registered) > 0 {
+		return registered, errTeamMemberRegistered
+	}
+
+	for _, userID := range userIDs {
+		if err := tx.Create(&models.ContestParticipant{
+			ContestID: contestID,
+			UserID:    userID,
+			TeamID:    team.ID,
+			Status:    models.ParticipantRegistered,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// 报名团队赛时校验队伍：当前用户必须是队长，返回错误响应
+func requireContestTeam(c *gin.Context, teamID uint) (*models.Team, bool) {
+	if teamID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "团队赛需要选择队伍报名",
+			"data":    nil,
+		})
+		return nil, false
+	}
+	return requireTeamCaptain(c, strconv.FormatUint(uint64(teamID), 10))
+}
+
+// 返回队员已报名比赛的错误
+func respondTeamMemberRegistered(c *gin.Context, usernames []string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    400,
+		"message": "队员 " + strings.Join(usernames, "、") + " 已报名该比赛",
+		"data":    nil,
+	})
+}
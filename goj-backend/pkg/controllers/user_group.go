@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 用户组请求结构
+type UserGroupRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description"`
+}
+
+// GetUserGroups 获取用户组列表
+func GetUserGroups(c *gin.Context) {
+	var groups []struct {
+		models.UserGroup
+		MemberCount int64 `json:"memberCount"`
+	}
+	if err := config.DB.Table("user_groups").
+		Select("user_groups.*, (SELECT COUNT(*) FROM user_group_members WHERE group_id = user_groups.id) AS member_count").
+		Order("user_groups.id").
+		Scan(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取用户组失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"groups": groups,
+		},
+	})
+}
+
+// CreateUserGroup 创建用户组
+func CreateUserGroup(c *gin.Context) {
+	var req UserGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	group := models.UserGroup{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := config.DB.Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建用户组失败，名称可能已存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建成功",
+		"data":    group,
+	})
+}
+
+// UpdateUserGroup 更新用户组
+func UpdateUserGroup(c *gin.Context) {
+	var req UserGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := config.DB.Model(&models.UserGroup{}).Where("id = ?", c.Param("id")).
+		Updates(map[string]interface{}{
+			"name":        req.Name,
+			"description": req.Description,
+		}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新用户组失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新成功",
+		"data":    nil,
+	})
+}
+
+// DeleteUserGroup 删除用户组及其成员
+func DeleteUserGroup(c *gin.Context) {
+	groupID := c.Param("id")
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.UserGroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.UserGroup{}, "id = ?", groupID).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除用户组失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+		"data":    nil,
+	})
+}
+
+// GetUserGroupMembers 获取用户组成员
+func GetUserGroupMembers(c *gin.Context) {
+	var members []struct {
+		UserID   uint   `json:"userId"`
+		Username string `json:"username"`
+		Avatar   string `json:"avatar"`
+	}
+	if err := config.DB.Table("user_group_members").
+		Select("user_group_members.user_id, users.username, users.avatar").
+		Joins("JOIN users ON users.id = user_group_members.user_id").
+		Where("user_group_members.group_id = ?", c.Param("id")).
+		Order("users.username").
+		Scan(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取成员失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"members": members,
+			"total":   len(members),
+		},
+	})
+}
+
+// AddUserGroupMembers 按用户名添加用户组成员
+func AddUserGroupMembers(c *gin.Context) {
+	var req struct {
+		Usernames []string `json:"usernames" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+
+	var group models.UserGroup
+	if err := config.DB.First(&group, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "用户组不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	usernames := normalizeUsernames(req.Usernames)
+	var users []models.User
+	if len(usernames) > 0 {
+		config.DB.Select("id, username").Where("username IN ?", usernames).Find(&users)
+	}
+
+	found := make(map[string]bool)
+	members := make([]models.UserGroupMember, 0, len(users))
+	for _, user := range users {
+		found[user.Username] = true
+		members = append(members, models.UserGroupMember{GroupID: group.ID, UserID: user.ID})
+	}
+	notFound := make([]string, 0)
+	for _, username := range usernames {
+		if !found[username] {
+			notFound = append(notFound, username)
+		}
+	}
+
+	if len(members) > 0 {
+		if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "添加成员失败",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "添加成功",
+		"data": gin.H{
+			"added":    len(members),
+			"notFound": notFound,
+		},
+	})
+}
+
+// RemoveUserGroupMember 移除用户组成员
+func RemoveUserGroupMember(c *gin.Context) {
+	if err := config.DB.Where("group_id = ? AND user_id = ?", c.Param("id"), c.Param("userId")).
+		Delete(&models.UserGroupMember{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "移除成员失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除成功",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/events"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest 创建或修改 Webhook 的请求
+type WebhookRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"` // 为空时自动生成
+	Events []string `json:"events"` // 为空表示订阅全部事件
+	Active *bool    `json:"active"`
+}
+
+// validate 检查地址和事件类型
+func (r *WebhookRequest) validate() string {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "无效的回调地址"
+	}
+	for _, t := range r.Events {
+		if !events.IsValidType(t) {
+			return "未知的事件类型: " + t
+		}
+	}
+	return ""
+}
+
+// GetWebhookEvents 获取可以订阅的事件类型
+func GetWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    events.Types,
+	})
+}
+
+// GetWebhooks 获取 Webhook 列表
+func GetWebhooks(c *gin.Context) {
+	var hooks []models.Webhook
+	if err := config.DB.Order("id ASC").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取Webhook列表失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    hooks,
+	})
+}
+
+// CreateWebhook 注册 Webhook
+func CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": msg,
+			"data":    nil,
+		})
+		return
+	}
+
+	if req.Secret == "" {
+		req.Secret = generateWebhookSecret()
+	}
+	hook := models.Webhook{
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    strings.Join(req.Events, ","),
+		Active:    req.Active == nil || *req.Active,
+		CreatedBy: c.GetUint("userID"),
+	}
+	if err := config.DB.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "保存Webhook失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建成功",
+		"data":    hook,
+	})
+}
+
+// UpdateWebhook 修改 Webhook，密钥为空时保持不变
+func UpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": msg,
+			"data":    nil,
+		})
+		return
+	}
+
+	var hook models.Webhook
+	if err := config.DB.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Webhook不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":   req.Name,
+		"url":    req.URL,
+		"events": strings.Join(req.Events, ","),
+	}
+	if req.Secret != "" {
+		updates["secret"] = req.Secret
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if err := config.DB.Model(&hook).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新Webhook失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新成功",
+		"data":    hook,
+	})
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func DeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+	if err := config.DB.Delete(&models.Webhook{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除Webhook失败",
+			"data":    nil,
+		})
+		return
+	}
+	config.DB.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+		"data":    nil,
+	})
+}
+
+// TestWebhook 向 Webhook 发送 ping 事件，同步返回对方的响应
+func TestWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := config.DB.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Webhook不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	code, body, err := events.SendPing(hook)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"code":    502,
+			"message": "请求失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "测试完成",
+		"data": gin.H{
+			"responseCode": code,
+			"responseBody": body,
+		},
+	})
+}
+
+// GetWebhookDeliveries 获取投递记录，可按 Webhook、事件类型和状态筛选
+func GetWebhookDeliveries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := config.DB.Model(&models.WebhookDelivery{})
+	if webhookID := c.Query("webhookId"); webhookID != "" {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if eventType := c.Query("eventType"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取投递记录失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"deliveries": deliveries,
+			"total":      total,
+		},
+	})
+}
+
+// RedeliverWebhookDelivery 重新投递一条记录，尝试次数清零
+func RedeliverWebhookDelivery(c *gin.Context) {
+	now := time.Now()
+	result := config.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ?", c.Param("id")).
+		Updates(map[string]interface{}{
+			"status":          events.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": &now,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "投递记录不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已加入重新投递队列",
+		"data":    nil,
+	})
+}
+
+func generateWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"log"
+	"time"
+)
+
+// StartContestWatcher 定时检查比赛的开始和结束，发布 contest.started / contest.ended 事件
+func StartContestWatcher() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			checkContests("start_time", ContestStarted)
+			checkContests("end_time", ContestEnded)
+		}
+	}()
+}
+
+// checkContests 为最近一小时内开始（或结束）的比赛发布事件，每场比赛只发布一次
+func checkContests(column, eventType string) {
+	now := time.Now()
+	var contests []models.Contest
+	if err := config.DB.Where(column+" <= ? AND "+column+" > ?", now, now.Add(-time.Hour)).
+		Find(&contests).Error; err != nil {
+		log.Printf("[Events] Failed to load contests: %v", err)
+		return
+	}
+
+	for _, contest := range contests {
+		// 多个 API 实例时只由一个实例发布
+		key := fmt.Sprintf("events:%s:%s", eventType, contest.ID)
+		ok, err := config.RDB.SetNX(context.Background(), key, 1, 48*time.Hour).Result()
+		if err != nil || !ok {
+			continue
+		}
+		if eventType == ContestEnded {
+			finishParticipants(contest.ID)
+		}
+		Publish(eventType, ContestData{
+			ContestID: contest.ID,
+			Title:     contest.Title,
+			StartTime: contest.StartTime,
+			EndTime:   contest.EndTime,
+		})
+	}
+}
+
+// finishParticipants 比赛结束后将参赛者状态置为 finished。读取时也会按结束时间判断，
+// 这里只是让数据库中的状态与之一致
+func finishParticipants(contestID string) {
+	if err := config.DB.Model(&models.ContestParticipant{}).
+		Where("contest_id = ? AND status <> ?", contestID, models.ParticipantFinished).
+		Update("status", models.ParticipantFinished).Error; err != nil {
+		log.Printf("[Events] Failed to finish participants of contest %s: %v", contestID, err)
+	}
+}
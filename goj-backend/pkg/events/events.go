@@ -0,0 +1,141 @@
+// Package events 站内事件总线。
+//
+// 事件由产生方通过 Publish 写入 Redis 队列 events:queue，API 服务中的分发器消费后
+// 投递给管理员注册的 Webhook。所有事件的格式为：
+//
+//	{"id": "事件ID", "type": "事件类型", "createdAt": "RFC3339 时间", "data": {...}}
+//
+// 事件类型及 data 字段：
+//
+//	submission.judged               评测完成：submissionId, userId, problemId, contestId, status, timeUsed, memoryUsed
+//	submission.accepted-first-time  用户首次通过某题：submissionId, userId, problemId, contestId
+//	contest.started                 比赛开始：contestId, title, startTime, endTime
+//	contest.ended                   比赛结束：contestId, title, startTime, endTime
+//	contest.unfrozen                比赛解除封榜，公布最终结果：contestId, title, startTime, endTime
+//	rating.updated                  比赛 rating 更新完成：contestId, changes[{userId, oldRating, newRating, rank}]
+//	ping                            测试 Webhook 时发送，不进入队列
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"log"
+	"time"
+)
+
+// QueueKey 事件队列
+const QueueKey = "events:queue"
+
+// 事件类型
+const (
+	SubmissionJudged            = "submission.judged"
+	SubmissionAcceptedFirstTime = "submission.accepted-first-time"
+	ContestStarted              = "contest.started"
+	ContestEnded                = "contest.ended"
+	ContestUnfrozen             = "contest.unfrozen"
+	RatingUpdated               = "rating.updated"
+	Ping                        = "ping"
+)
+
+// Types 可以订阅的事件类型
+var Types = []string{
+	SubmissionJudged,
+	SubmissionAcceptedFirstTime,
+	ContestStarted,
+	ContestEnded,
+	ContestUnfrozen,
+	RatingUpdated,
+}
+
+// IsValidType 检查事件类型是否可以订阅
+func IsValidType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event 事件
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SubmissionData submission.* 事件的数据
+type SubmissionData struct {
+	SubmissionID uint   `json:"submissionId"`
+	UserID       uint   `json:"userId"`
+	ProblemID    string `json:"problemId"`
+	ContestID    string `json:"contestId,omitempty"`
+	Status       string `json:"status,omitempty"`
+	TimeUsed     int    `json:"timeUsed,omitempty"`
+	MemoryUsed   int    `json:"memoryUsed,omitempty"`
+}
+
+// ContestData contest.* 事件的数据
+type ContestData struct {
+	ContestID string    `json:"contestId"`
+	Title     string    `json:"title"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// RatingChange rating.updated 事件中单个用户的变化，团队赛中为队伍的变化，此时 UserID 为 0
+type RatingChange struct {
+	UserID    uint  `json:"userId"`
+	TeamID    uint  `json:"teamId,omitempty"`
+	OldRating int64 `json:"oldRating"`
+	NewRating int64 `json:"newRating"`
+	Rank      int   `json:"rank"`
+}
+
+// RatingData rating.updated 事件的数据
+type RatingData struct {
+	ContestID string         `json:"contestId"`
+	Changes   []RatingChange `json:"changes"`
+}
+
+// NewEvent 构造事件
+func NewEvent(eventType string, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		ID:        newEventID(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      raw,
+	}, nil
+}
+
+// Publish 发布事件。事件总线是旁路功能，失败只记录日志，不影响调用方
+func Publish(eventType string, data interface{}) {
+	event, err := NewEvent(eventType, data)
+	if err != nil {
+		log.Printf("[Events] Failed to encode %s event: %v", eventType, err)
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[Events] Failed to encode %s event: %v", eventType, err)
+		return
+	}
+	if err := config.RDB.RPush(context.Background(), QueueKey, payload).Err(); err != nil {
+		log.Printf("[Events] Failed to publish %s event: %v", eventType, err)
+	}
+}
+
+func newEventID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%d-%s", time.Now().UnixMilli(), hex.EncodeToString(b))
+}
@@ -0,0 +1,246 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 投递状态
+const (
+	DeliveryPending = "pending"
+	DeliverySuccess = "success"
+	DeliveryFailed  = "failed"
+)
+
+const (
+	MaxAttempts       = 6                   // 最多投递次数，之后标记为失败
+	deliveryTimeout   = 10 * time.Second    // 单次请求超时
+	retryBaseDelay    = 30 * time.Second    // 第一次重试的等待时间，之后每次翻倍
+	retryMaxDelay     = time.Hour           // 重试等待时间上限
+	deliveryRetention = 30 * 24 * time.Hour // 投递记录保留时间
+	maxResponseBytes  = 2048                // 保存的响应内容长度
+)
+
+var httpClient = &http.Client{Timeout: deliveryTimeout}
+
+// Sign 计算请求体的签名，接收方用同一个密钥计算后与 X-GOJ-Signature 比较
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// StartDispatcher 消费事件队列并投递给 Webhook，同时定时重试失败的投递。只在 API 服务中调用
+func StartDispatcher() {
+	go func() {
+		ctx := context.Background()
+		for {
+			result, err := config.RDB.BLPop(ctx, 5*time.Second, QueueKey).Result()
+			if err != nil {
+				continue // 超时或 Redis 暂时不可用
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(result[1]), &event); err != nil {
+				log.Printf("[Events] Invalid event in queue: %v", err)
+				continue
+			}
+			dispatch(&event, []byte(result[1]))
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(retryBaseDelay)
+		defer ticker.Stop()
+		lastCleanup := time.Time{}
+		for range ticker.C {
+			retryDue()
+			if time.Since(lastCleanup) > time.Hour {
+				config.DB.Where("created_at < ?", time.Now().Add(-deliveryRetention)).
+					Delete(&models.WebhookDelivery{})
+				lastCleanup = time.Now()
+			}
+		}
+	}()
+
+	log.Printf("[Events] Webhook dispatcher started")
+}
+
+// dispatch 为订阅了该事件的 Webhook 创建投递记录并立即投递
+func dispatch(event *Event, payload []byte) {
+	var hooks []models.Webhook
+	if err := config.DB.Where("active = ?", true).Find(&hooks).Error; err != nil {
+		log.Printf("[Events] Failed to load webhooks: %v", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !subscribed(hook, event.Type) {
+			continue
+		}
+		// 截断到毫秒，避免数据库把时间向上取整后立即认领时还未到期
+		now := time.Now().Truncate(time.Millisecond)
+		delivery := models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := config.DB.Create(&delivery).Error; err != nil {
+			log.Printf("[Events] Failed to save delivery for webhook %d: %v", hook.ID, err)
+			continue
+		}
+		if claim(&delivery) {
+			go Deliver(hook, &delivery)
+		}
+	}
+}
+
+// subscribed Webhook 是否订阅了该事件
+func subscribed(hook models.Webhook, eventType string) bool {
+	if hook.Events == "" {
+		return true
+	}
+	for _, t := range strings.Split(hook.Events, ",") {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDue 重试到期的投递
+func retryDue() {
+	var deliveries []models.WebhookDelivery
+	if err := config.DB.Where("status = ? AND next_attempt_at <= ?", DeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(100).
+		Find(&deliveries).Error; err != nil {
+		log.Printf("[Events] Failed to load pending deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		var hook models.Webhook
+		if err := config.DB.First(&hook, delivery.WebhookID).Error; err != nil || !hook.Active {
+			config.DB.Model(delivery).Updates(map[string]interface{}{
+				"status":          DeliveryFailed,
+				"error_info":      "webhook 已删除或停用",
+				"next_attempt_at": nil,
+			})
+			continue
+		}
+		if claim(delivery) {
+			go Deliver(hook, delivery)
+		}
+	}
+}
+
+// claim 把已到期的投递的下次投递时间推后，多个 API 实例时只有更新成功的实例负责本次投递。
+// 按是否到期而不是按读到的时间值匹配，数据库保存的时间精度比 Go 低
+func claim(delivery *models.WebhookDelivery) bool {
+	now := time.Now()
+	lease := now.Add(2 * deliveryTimeout)
+	result := config.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, DeliveryPending, now).
+		Update("next_attempt_at", lease)
+	if result.Error != nil || result.RowsAffected != 1 {
+		return false
+	}
+	delivery.NextAttemptAt = &lease
+	return true
+}
+
+// Deliver 发送一次投递并记录结果，失败时按指数退避安排下次重试
+func Deliver(hook models.Webhook, delivery *models.WebhookDelivery) {
+	code, body, err := post(hook, delivery.EventID, delivery.EventType, []byte(delivery.Payload))
+
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":      attempts,
+		"response_code": code,
+		"response_body": body,
+		"error_info":    "",
+	}
+	switch {
+	case err == nil && code >= 200 && code < 300:
+		updates["status"] = DeliverySuccess
+		updates["next_attempt_at"] = nil
+	case attempts >= MaxAttempts:
+		updates["status"] = DeliveryFailed
+		updates["next_attempt_at"] = nil
+	default:
+		updates["next_attempt_at"] = time.Now().Add(backoff(attempts))
+	}
+	if err != nil {
+		updates["error_info"] = err.Error()
+	} else if code < 200 || code >= 300 {
+		updates["error_info"] = fmt.Sprintf("unexpected status code %d", code)
+	}
+
+	if err := config.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).
+		Updates(updates).Error; err != nil {
+		log.Printf("[Events] Failed to update delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// post 发送带签名的请求，返回状态码和截断后的响应内容
+func post(hook models.Webhook, eventID, eventType string, payload []byte) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GOJ-Webhook")
+	req.Header.Set("X-GOJ-Event", eventType)
+	req.Header.Set("X-GOJ-Delivery", eventID)
+	req.Header.Set("X-GOJ-Signature", Sign(hook.Secret, payload))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.StatusCode, string(body), nil
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func backoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// SendPing 向 Webhook 同步发送测试事件，不记录投递日志
+func SendPing(hook models.Webhook) (int, string, error) {
+	event, err := NewEvent(Ping, map[string]interface{}{"webhookId": hook.ID})
+	if err != nil {
+		return 0, "", err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, "", err
+	}
+	return post(hook, event.ID, event.Type, payload)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"log"
+	"time"
+)
+
+// ProgressChannel 评测进度的 Redis 发布订阅频道
+const ProgressChannel = "judge:progress"
+
+// PublishProgress 发布评测进度事件，评测机和 API 服务共用
+func PublishProgress(p *types.JudgeProgress) {
+	if p.Timestamp == 0 {
+		p.Timestamp = time.Now().UnixMilli()
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		logError("[Progress] Failed to marshal progress: %v", err)
+		return
+	}
+
+	if err := config.RDB.Publish(context.Background(), ProgressChannel, data).Err(); err != nil {
+		logError("[Progress] Failed to publish progress for submission %d: %v", p.SubmissionID, err)
+	}
+}
+
+// StartProgressSubscriber 订阅评测进度，推送给提交者和正在查看该提交的管理员
+func StartProgressSubscriber(ws *WebSocketManager) {
+	go func() {
+		pubsub := config.RDB.Subscribe(context.Background(), ProgressChannel)
+		defer pubsub.Close()
+
+		log.Printf("[Progress] Subscribed to %s", ProgressChannel)
+		for msg := range pubsub.Channel() {
+			var p types.JudgeProgress
+			if err := json.Unmarshal([]byte(msg.Payload), &p); err != nil {
+				logError("[Progress] Failed to unmarshal progress: %v", err)
+				continue
+			}
+			ws.DeliverProgress(&p)
+		}
+	}()
+}
@@ -1,13 +1,15 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/events"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/scoreboard"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
@@ -38,24 +40,18 @@ func logError(format string, v ...interface{}) {
 	}
 }
 
+// ResultHandler 在 API 服务侧处理评测机回传的结果：写入数据库并推送给用户
 type ResultHandler struct {
-	db        *gorm.DB
-	ws        *WebSocketManager
-	judgeAddr string   // 添加评测机地址
-	cachedIds []string // 添加缓存ID列表
+	db *gorm.DB
 }
 
-func NewResultHandler(ws *WebSocketManager, judgeAddr string) *ResultHandler {
+func NewResultHandler() *ResultHandler {
 	return &ResultHandler{
-		db:        config.DB,
-		ws:        ws,
-		judgeAddr: judgeAddr,
+		db: config.DB,
 	}
 }
 
 func (h *ResultHandler) HandleResult(result *types.JudgeResult) error {
-	defer h.cleanupCache() // 确保在处理完后清理缓存
-
 	logDebug("[ResultHandler] Processing result for submission %d", result.ID)
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -74,11 +70,12 @@ func (h *ResultHandler) HandleResult(result *types.JudgeResult) error {
 		return err
 	}
 
-	// 如果是比赛提交，尝试处理比赛相关数据
-	if submission.ContestID != "" {
+	// 如果是比赛提交，尝试处理比赛相关数据。虚拟提交保留比赛ID，但不计入正式榜单
+	hideResult := false
+	var contest models.Contest
+	if submission.ContestID != "" && submission.VirtualID == 0 {
 		isValidContestSubmission := false
 		// 检查比赛状态和题目
-		var contest models.Contest
 		if err := tx.Where("id = ?", submission.ContestID).First(&contest).Error; err == nil {
 			now := time.Now()
 			if now.After(contest.StartTime) && now.Before(contest.EndTime) {
@@ -100,6 +97,7 @@ func (h *ResultHandler) HandleResult(result *types.JudgeResult) error {
 
 						// 所有操作都成功才标记为有效
 						isValidContestSubmission = true
+						hideResult = scoreboard.ResultsHidden(&contest, now)
 						break
 					}
 				}
@@ -115,6 +113,7 @@ func (h *ResultHandler) HandleResult(result *types.JudgeResult) error {
 				tx.Rollback()
 				return fmt.Errorf("清除比赛信息失败: %v", err)
 			}
+			submission.ContestID = ""
 		}
 	}
 
@@ -137,37 +136,93 @@ func (h *ResultHandler) HandleResult(result *types.JudgeResult) error {
 	}
 
 	// 4. 更新用户题目状态
-	if err := h.updateUserProblemStatus(tx, submission.UserID, submission.ProblemID, result.Status); err != nil {
+	firstAccepted, err := h.updateUserProblemStatus(tx, submission.UserID, submission.ProblemID, result.Status)
+	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// // 推送评测状态更新
-	// if h.ws != nil {
-	// 	msg := map[string]interface{}{
-	// 		"type": "judge_status",
-	// 		"data": map[string]interface{}{
-	// 			"id":     result.ID,
-	// 			"status": result.Status,
-	// 		},
-	// 	}
-	// 	if err := h.ws.BroadcastToUser(result.UserID, msg); err != nil {
-	// 		logError("[ResultHandler] Failed to broadcast status: %v", err)
-	// 	}
-	// }
-
-	// // 推送最终结果
-	// if h.ws != nil {
-	// 	msg := map[string]interface{}{
-	// 		"type": "judge_result",
-	// 		"data": result,
-	// 	}
-	// 	if err := h.ws.BroadcastToUser(result.UserID, msg); err != nil {
-	// 		logError("[ResultHandler] Failed to broadcast result: %v", err)
-	// 	}
-	// }
-
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 增量更新比赛榜单
+	if submission.ContestID != "" && submission.VirtualID == 0 {
+		h.recordScoreboard(&contest, &submission, result)
+	}
+
+	// 评测追踪只对管理员可见，保存后从结果中移除
+	if len(result.Trace) > 0 {
+		if err := h.saveTrace(submission.ID, result.Trace); err != nil {
+			logError("[ResultHandler] Failed to save trace for submission %d: %v", submission.ID, err)
+		}
+		result.Trace = nil
+	}
+
+	// 落库后再推送最终结果，前端收到后重新拉取详情即可看到最新状态。
+	// 赛制在比赛结束前隐藏结果时只通知评测完成
+	progress := &types.JudgeProgress{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		Stage:        types.ProgressFinished,
+		Status:       result.Status,
+		TimeUsed:     result.TimeUsed,
+		MemoryUsed:   result.MemoryUsed,
+		Result:       result,
+	}
+	if hideResult {
+		progress.Status = scoreboard.StatusHidden
+		progress.TimeUsed = 0
+		progress.MemoryUsed = 0
+		progress.Result = nil
+	}
+	PublishProgress(progress)
+
+	// 发布事件，无效的比赛提交此时已清除比赛ID
+	eventData := events.SubmissionData{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		ProblemID:    submission.ProblemID,
+		ContestID:    submission.ContestID,
+		Status:       result.Status,
+		TimeUsed:     result.TimeUsed,
+		MemoryUsed:   result.MemoryUsed,
+	}
+	if submission.VirtualID != 0 {
+		// 虚拟提交对外视为普通提交
+		eventData.ContestID = ""
+	}
+	events.Publish(events.SubmissionJudged, eventData)
+	if firstAccepted {
+		events.Publish(events.SubmissionAcceptedFirstTime, eventData)
+	}
+
+	return nil
+}
+
+// recordScoreboard 将比赛提交计入 Redis 中的榜单，失败的视图会在读取时重建
+func (h *ResultHandler) recordScoreboard(contest *models.Contest, submission *models.Submission, result *types.JudgeResult) {
+	var user models.User
+	h.db.Select("username, avatar, bio").First(&user, submission.UserID)
+
+	sub := &scoreboard.Submission{
+		ID:              submission.ID,
+		UserID:          submission.UserID,
+		Username:        user.Username,
+		Avatar:          user.Avatar,
+		Bio:             user.Bio,
+		ProblemID:       submission.ProblemID,
+		Status:          result.Status,
+		SubmitTime:      submission.SubmitTime,
+		TimeUsed:        result.TimeUsed,
+		MemoryUsed:      result.MemoryUsed,
+		TestcasesStatus: result.TestcasesStatus, // 与落库的测试点状态一致，重建时得到相同结果
+		TeamID:          submission.TeamID,
+	}
+	if err := scoreboard.Record(context.Background(), contest, sub); err != nil {
+		logError("[ResultHandler] Failed to update scoreboard for contest %s: %v", contest.ID, err)
+	}
+	PublishScoreboardUpdate(contest.ID)
 }
 
 // processContestSubmission 处理比赛提交
@@ -270,8 +325,8 @@ func (h *ResultHandler) updateUserStats(tx *gorm.DB, userID uint) error {
 	`, userID, userID, userID).Error
 }
 
-// updateUserProblemStatus 更新用户题目状态
-func (h *ResultHandler) updateUserProblemStatus(tx *gorm.DB, userID uint, problemID string, status string) error {
+// updateUserProblemStatus 更新用户题目状态，返回是否为用户首次通过该题
+func (h *ResultHandler) updateUserProblemStatus(tx *gorm.DB, userID uint, problemID string, status string) (bool, error) {
 	var currentStatus models.UserProblemStatus
 	err := tx.Where("user_id = ? AND problem_id = ?", userID, problemID).
 		First(&currentStatus).Error
@@ -294,10 +349,11 @@ func (h *ResultHandler) updateUserProblemStatus(tx *gorm.DB, userID uint, proble
 			newStatus = models.StatusAttempted
 		}
 	} else {
-		return err
+		return false, err
 	}
 
-	return tx.Model(&models.UserProblemStatus{}).
+	firstAccepted := newStatus == models.StatusAccepted && currentStatus.Status != models.StatusAccepted
+	return firstAccepted, tx.Model(&models.UserProblemStatus{}).
 		Where("user_id = ? AND problem_id = ?", userID, problemID).
 		Assign(map[string]interface{}{
 			"user_id":    userID,
@@ -307,33 +363,3 @@ func (h *ResultHandler) updateUserProblemStatus(tx *gorm.DB, userID uint, proble
 		}).
 		FirstOrCreate(&models.UserProblemStatus{}).Error
 }
-
-// 添加缓存ID
-func (h *ResultHandler) AddCachedId(id string) {
-	h.cachedIds = append(h.cachedIds, id)
-}
-
-// 清理缓存
-func (h *ResultHandler) cleanupCache() {
-	for _, id := range h.cachedIds {
-		if err := h.deleteCachedFile(id); err != nil {
-			logError("[ResultHandler] Failed to delete cached file %s: %v", id, err)
-		}
-	}
-	h.cachedIds = nil
-}
-
-// 删除单个缓存文件
-func (h *ResultHandler) deleteCachedFile(id string) error {
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodDelete, h.judgeAddr+"/file/"+id, nil)
-	if err != nil {
-		return err
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
-}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// saveTrace 保存评测追踪，重测时覆盖旧记录
+func (h *ResultHandler) saveTrace(submissionID uint, data []byte) error {
+	trace := models.JudgeTrace{
+		SubmissionID: submissionID,
+		Size:         len(data),
+		Data:         data,
+		CreatedAt:    time.Now(),
+	}
+	return h.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&trace).Error
+}
+
+// StartTraceCleanup 定时清理过期的评测追踪，并把记录数控制在上限内
+func StartTraceCleanup() {
+	go func() {
+		for {
+			cleanupTraces()
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func cleanupTraces() {
+	if config.Judge.TraceRetentionDays > 0 {
+		deadline := time.Now().AddDate(0, 0, -config.Judge.TraceRetentionDays)
+		result := config.DB.Where("created_at < ?", deadline).Delete(&models.JudgeTrace{})
+		if result.Error != nil {
+			logError("[Trace] Failed to delete expired traces: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("[Trace] Deleted %d expired traces", result.RowsAffected)
+		}
+	}
+
+	if config.Judge.TraceMaxCount > 0 {
+		var total int64
+		config.DB.Model(&models.JudgeTrace{}).Count(&total)
+		if excess := total - int64(config.Judge.TraceMaxCount); excess > 0 {
+			// 按时间删除最旧的记录
+			var ids []uint
+			config.DB.Model(&models.JudgeTrace{}).
+				Order("created_at ASC").
+				Limit(int(excess)).
+				Pluck("submission_id", &ids)
+			if len(ids) > 0 {
+				config.DB.Where("submission_id IN ?", ids).Delete(&models.JudgeTrace{})
+				log.Printf("[Trace] Deleted %d traces over the limit", len(ids))
+			}
+		}
+	}
+}
@@ -1,17 +1,63 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	writeWait      = 10 * time.Second    // 单次写入超时
+	pongWait       = 60 * time.Second    // 等待 pong 的超时
+	pingPeriod     = (pongWait * 9) / 10 // 发送 ping 的间隔，必须小于 pongWait
+	maxMessageSize = 4096                // 客户端消息最大长度
+	sendBufferSize = 256                 // 每个连接的发送缓冲
+
+	// BroadcastChannel 多个 API 实例之间转发 WebSocket 消息的 Redis 频道
+	BroadcastChannel = "ws:broadcast"
+
+	// TopicAnnouncements 全站公告主题
+	TopicAnnouncements = "announcements"
+)
+
+// TopicSubmission 单个提交的评测进度主题
+func TopicSubmission(submissionID uint) string {
+	return fmt.Sprintf("submission:%d", submissionID)
+}
+
+// TopicContestScoreboard 比赛榜单更新主题
+func TopicContestScoreboard(contestID string) string {
+	return fmt.Sprintf("contest:%s:scoreboard", contestID)
+}
+
+// TopicContestClarifications 比赛公开的提问回答主题
+func TopicContestClarifications(contestID string) string {
+	return fmt.Sprintf("contest:%s:clarifications", contestID)
+}
+
+// TopicContestAnnouncements 比赛公告主题
+func TopicContestAnnouncements(contestID string) string {
+	return fmt.Sprintf("contest:%s:announcements", contestID)
+}
+
+// TopicContestJudges 比赛裁判主题，推送新的提问，只有管理员可以订阅
+func TopicContestJudges(contestID string) string {
+	return fmt.Sprintf("contest:%s:judges", contestID)
+}
+
 // 全局WebSocket管理器实例
 var wsManager *WebSocketManager
 
@@ -21,16 +67,44 @@ type WebSocketMessage struct {
 	Data interface{} `json:"data"`
 }
 
+// ClientRequest 客户端发来的订阅消息
+type ClientRequest struct {
+	Type  string `json:"type"`  // subscribe, unsubscribe
+	Topic string `json:"topic"` // submission:<id>, contest:<id>:scoreboard, contest:<id>:clarifications, contest:<id>:announcements, contest:<id>:judges, announcements
+}
+
+// broadcastEnvelope 经 Redis 转发的消息，UserID、UserIDs 和 Topic 三选一
+type broadcastEnvelope struct {
+	UserID  uint             `json:"userId,omitempty"`
+	UserIDs []uint           `json:"userIds,omitempty"`
+	Topic   string           `json:"topic,omitempty"`
+	Message WebSocketMessage `json:"message"`
+}
+
+// Client 单个 WebSocket 连接，一个用户可以同时持有多个
+type Client struct {
+	manager *WebSocketManager
+	conn    *websocket.Conn
+	userID  uint
+	role    string
+	send    chan []byte
+	topics  map[string]struct{} // 受 manager.mu 保护
+}
+
 // WebSocketManager WebSocket管理器
 type WebSocketManager struct {
-	connections sync.Map     // userID -> []websocket.Conn
-	maxConns    int          // 最大连接数
-	connCount   atomic.Int32 // 当前连接数
+	mu              sync.RWMutex
+	users           map[uint]map[*Client]struct{}   // userID -> 连接集合
+	topics          map[string]map[*Client]struct{} // topic -> 订阅的连接
+	maxConns        int                             // 最大连接数，0 表示不限制
+	maxConnsPerUser int                             // 每个用户的最大连接数，0 表示不限制
+	connCount       atomic.Int32                    // 当前连接数
 }
 
-// InitWebSocketManager 初始化WebSocket管理器
+// InitWebSocketManager 初始化WebSocket管理器，并开始接收其他实例转发的消息
 func InitWebSocketManager() {
-	wsManager = NewWebSocketManager()
+	wsManager = NewWebSocketManager(config.WebSocket.MaxConns, config.WebSocket.MaxConnsPerUser)
+	go wsManager.runBroadcastSubscriber()
 }
 
 // GetWebSocketManager 获取WebSocket管理器实例
@@ -39,64 +113,269 @@ func GetWebSocketManager() *WebSocketManager {
 }
 
 // NewWebSocketManager 创建WebSocket管理器
-func NewWebSocketManager() *WebSocketManager {
-	return &WebSocketManager{}
+func NewWebSocketManager(maxConns, maxConnsPerUser int) *WebSocketManager {
+	return &WebSocketManager{
+		users:           make(map[uint]map[*Client]struct{}),
+		topics:          make(map[string]map[*Client]struct{}),
+		maxConns:        maxConns,
+		maxConnsPerUser: maxConnsPerUser,
+	}
 }
 
-// AddConnection 添加连接
-func (m *WebSocketManager) AddConnection(userID uint, conn *websocket.Conn) error {
+// addClient 注册连接
+func (m *WebSocketManager) addClient(client *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// 检查连接数限制
-	if m.connCount.Load() >= int32(m.maxConns) {
+	if m.maxConns > 0 && m.connCount.Load() >= int32(m.maxConns) {
 		return errors.New("达到最大连接数限制")
 	}
+	if m.maxConnsPerUser > 0 && len(m.users[client.userID]) >= m.maxConnsPerUser {
+		return errors.New("该用户连接数过多")
+	}
 
-	m.connections.Store(userID, conn)
+	if m.users[client.userID] == nil {
+		m.users[client.userID] = make(map[*Client]struct{})
+	}
+	m.users[client.userID][client] = struct{}{}
 	m.connCount.Add(1)
 	return nil
 }
 
-// RemoveConnection 移除连接
-func (m *WebSocketManager) RemoveConnection(userID uint) {
-	if _, exists := m.connections.LoadAndDelete(userID); exists {
-		m.connCount.Add(-1)
+// removeClient 移除连接及其全部订阅
+func (m *WebSocketManager) removeClient(client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients, ok := m.users[client.userID]
+	if !ok {
+		return
+	}
+	if _, ok := clients[client]; !ok {
+		return
+	}
+
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(m.users, client.userID)
 	}
+	for topic := range client.topics {
+		m.unsubscribeLocked(client, topic)
+	}
+	m.connCount.Add(-1)
+	close(client.send)
 }
 
-// SendToUser 发送消息给指定用户
-func (m *WebSocketManager) SendToUser(userID uint, msg WebSocketMessage) error {
-	log.Printf("[WebSocket] Sending message to user %d: %+v", userID, msg)
-	if conn, ok := m.connections.Load(userID); ok {
-		if wsConn, ok := conn.(*websocket.Conn); ok {
-			data, err := json.Marshal(msg)
-			if err != nil {
-				log.Printf("[WebSocket] Failed to marshal message: %v", err)
-				return err
-			}
-			if err := wsConn.WriteMessage(websocket.TextMessage, data); err != nil {
-				log.Printf("[WebSocket] Failed to send message: %v", err)
-				m.RemoveConnection(userID)
-				return err
+// subscribe 订阅主题
+func (m *WebSocketManager) subscribe(client *Client, topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.topics[topic] == nil {
+		m.topics[topic] = make(map[*Client]struct{})
+	}
+	m.topics[topic][client] = struct{}{}
+	client.topics[topic] = struct{}{}
+}
+
+// unsubscribe 取消订阅
+func (m *WebSocketManager) unsubscribe(client *Client, topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unsubscribeLocked(client, topic)
+}
+
+func (m *WebSocketManager) unsubscribeLocked(client *Client, topic string) {
+	delete(client.topics, topic)
+	delete(m.topics[topic], client)
+	if len(m.topics[topic]) == 0 {
+		delete(m.topics, topic)
+	}
+}
+
+// enqueue 放入连接的发送缓冲，缓冲已满说明客户端太慢，直接断开
+func (m *WebSocketManager) enqueue(client *Client, data []byte) {
+	select {
+	case client.send <- data:
+	default:
+		log.Printf("[WebSocket] 用户 %d 的发送缓冲已满，断开连接", client.userID)
+		go client.conn.Close()
+	}
+}
+
+// deliverToUser 发送给本实例上该用户的所有连接
+func (m *WebSocketManager) deliverToUser(userID uint, data []byte) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for client := range m.users[userID] {
+		m.enqueue(client, data)
+	}
+}
+
+// deliverToTopic 发送给本实例上订阅了该主题的所有连接
+func (m *WebSocketManager) deliverToTopic(topic string, data []byte) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for client := range m.topics[topic] {
+		m.enqueue(client, data)
+	}
+}
+
+// publish 通过 Redis 转发给所有 API 实例
+func (m *WebSocketManager) publish(envelope broadcastEnvelope) error {
+	return publishEnvelope(envelope)
+}
+
+// publishEnvelope 发布到转发频道，不依赖本进程的管理器，评测结果处理中也可以调用
+func publishEnvelope(envelope broadcastEnvelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return config.RDB.Publish(context.Background(), BroadcastChannel, data).Err()
+}
+
+// PublishScoreboardUpdate 通知订阅者比赛榜单已变化，客户端收到后重新拉取榜单
+func PublishScoreboardUpdate(contestID string) {
+	err := publishEnvelope(broadcastEnvelope{
+		Topic: TopicContestScoreboard(contestID),
+		Message: WebSocketMessage{
+			Type: "scoreboard_updated",
+			Data: map[string]interface{}{"contestId": contestID},
+		},
+	})
+	if err != nil {
+		logError("[WebSocket] Failed to publish scoreboard update for contest %s: %v", contestID, err)
+	}
+}
+
+// runBroadcastSubscriber 接收 Redis 转发的消息并投递到本实例的连接
+func (m *WebSocketManager) runBroadcastSubscriber() {
+	pubsub := config.RDB.Subscribe(context.Background(), BroadcastChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		var envelope broadcastEnvelope
+		if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+			logError("[WebSocket] Failed to unmarshal broadcast: %v", err)
+			continue
+		}
+
+		data, err := json.Marshal(envelope.Message)
+		if err != nil {
+			continue
+		}
+		switch {
+		case envelope.Topic != "":
+			m.deliverToTopic(envelope.Topic, data)
+		case len(envelope.UserIDs) > 0:
+			for _, userID := range envelope.UserIDs {
+				m.deliverToUser(userID, data)
 			}
-			log.Printf("[WebSocket] Message sent successfully to user %d", userID)
-			return nil
+		default:
+			m.deliverToUser(envelope.UserID, data)
 		}
-		log.Printf("[WebSocket] Invalid connection type for user %d", userID)
-		return fmt.Errorf("invalid connection type")
 	}
-	log.Printf("[WebSocket] No connection found for user %d", userID)
-	return fmt.Errorf("no connection found")
 }
 
-// HandleWebSocket 处理WebSocket连接
-func HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uint) {
-	log.Printf("[WebSocket] 收到新的WebSocket连接请求")
-	log.Printf("[WebSocket] 请求URL: %s", r.URL.String())
-	log.Printf("[WebSocket] 请求头: %+v", r.Header)
+// SendToUser 发送消息给指定用户的所有连接（包括其他 API 实例上的连接）
+func (m *WebSocketManager) SendToUser(userID uint, msg WebSocketMessage) error {
+	return m.publish(broadcastEnvelope{UserID: userID, Message: msg})
+}
+
+// SendToUsers 发送消息给多个用户的所有连接，只经 Redis 转发一次
+func (m *WebSocketManager) SendToUsers(userIDs []uint, msg WebSocketMessage) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return m.publish(broadcastEnvelope{UserIDs: userIDs, Message: msg})
+}
+
+// BroadcastToTopic 发送消息给订阅了指定主题的所有连接
+func (m *WebSocketManager) BroadcastToTopic(topic string, msg WebSocketMessage) error {
+	return m.publish(broadcastEnvelope{Topic: topic, Message: msg})
+}
+
+// BroadcastToUser 向指定用户广播消息
+func (m *WebSocketManager) BroadcastToUser(userID uint, msg interface{}) error {
+	return m.SendToUser(userID, WebSocketMessage{
+		Type: "judge_result",
+		Data: msg,
+	})
+}
+
+// DeliverProgress 把评测进度推送给提交者和订阅了该提交的连接。
+// 进度事件本身经 Redis 发布给每个实例，这里只投递到本实例的连接
+func (m *WebSocketManager) DeliverProgress(p *types.JudgeProgress) {
+	data, err := json.Marshal(WebSocketMessage{
+		Type: "judge_progress",
+		Data: p,
+	})
+	if err != nil {
+		return
+	}
+
+	topic := TopicSubmission(p.SubmissionID)
+	sent := make(map[*Client]struct{})
+
+	m.mu.RLock()
+	for client := range m.users[p.UserID] {
+		m.enqueue(client, data)
+		sent[client] = struct{}{}
+	}
+	for client := range m.topics[topic] {
+		if _, ok := sent[client]; !ok {
+			m.enqueue(client, data)
+		}
+	}
+	m.mu.RUnlock()
+}
+
+// canSubscribe 检查连接是否有权订阅主题
+func (m *WebSocketManager) canSubscribe(client *Client, topic string) bool {
+	switch {
+	case topic == TopicAnnouncements:
+		return true
+	case strings.HasPrefix(topic, "contest:") && strings.HasSuffix(topic, ":scoreboard"):
+		return canAccessContest(client, strings.TrimSuffix(strings.TrimPrefix(topic, "contest:"), ":scoreboard"))
+	case strings.HasPrefix(topic, "contest:") && strings.HasSuffix(topic, ":clarifications"):
+		return canAccessContest(client, strings.TrimSuffix(strings.TrimPrefix(topic, "contest:"), ":clarifications"))
+	case strings.HasPrefix(topic, "contest:") && strings.HasSuffix(topic, ":announcements"):
+		return canAccessContest(client, strings.TrimSuffix(strings.TrimPrefix(topic, "contest:"), ":announcements"))
+	case strings.HasPrefix(topic, "contest:") && strings.HasSuffix(topic, ":judges"):
+		return client.role == "admin"
+	case strings.HasPrefix(topic, "submission:"):
+		if client.role == "admin" {
+			return true
+		}
+		// 普通用户只能订阅自己的提交
+		id, err := strconv.ParseUint(strings.TrimPrefix(topic, "submission:"), 10, 64)
+		if err != nil {
+			return false
+		}
+		var count int64
+		config.DB.Model(&models.Submission{}).
+			Where("id = ? AND user_id = ?", id, client.userID).
+			Count(&count)
+		return count > 0
+	}
+	return false
+}
 
-	// 检查token
-	token := r.URL.Query().Get("token")
-	log.Printf("[WebSocket] 收到token: %s", token)
+// canAccessContest 比赛主题与比赛页面的访问权限一致，私有比赛只对参赛者、白名单和管理员开放
+func canAccessContest(client *Client, contestID string) bool {
+	var contest models.Contest
+	if err := config.DB.First(&contest, "id = ?", contestID).Error; err != nil {
+		return false
+	}
+	return contest.CanAccess(config.DB, client.userID, client.role)
+}
 
+// HandleWebSocket 处理WebSocket连接
+func HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uint, role string) {
 	if wsManager == nil {
 		log.Printf("[WebSocket] 错误: WebSocket管理器未初始化")
 		http.Error(w, "WebSocket服务未准备就绪", http.StatusServiceUnavailable)
@@ -105,8 +384,6 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uint) {
 
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
-			origin := r.Header.Get("Origin")
-			log.Printf("[WebSocket] 检查Origin: %s", origin)
 			return true // 开发环境允许所有来源
 		},
 	}
@@ -116,50 +393,128 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uint) {
 		log.Printf("[WebSocket] 升级连接失败: %v", err)
 		return
 	}
-	defer conn.Close()
 
-	log.Printf("[WebSocket] 连接成功建立, 用户ID: %d", userID)
+	client := &Client{
+		manager: wsManager,
+		conn:    conn,
+		userID:  userID,
+		role:    role,
+		send:    make(chan []byte, sendBufferSize),
+		topics:  make(map[string]struct{}),
+	}
 
 	// 将连接添加到管理器
-	wsManager.AddConnection(userID, conn)
-	defer wsManager.RemoveConnection(userID)
+	if err := wsManager.addClient(client); err != nil {
+		log.Printf("[WebSocket] 用户 %d 添加连接失败: %v", userID, err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	log.Printf("[WebSocket] 连接成功建立, 用户ID: %d", userID)
+
+	go client.writePump()
 
 	// 发送连接成功消息
-	welcomeMsg := WebSocketMessage{
+	client.sendJSON(WebSocketMessage{
 		Type: "connected",
 		Data: map[string]interface{}{
 			"message": "WebSocket连接成功",
 			"userId":  userID,
 		},
+	})
+
+	client.readPump()
+}
+
+// sendJSON 直接发送给当前连接
+func (c *Client) sendJSON(msg WebSocketMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
 	}
-	if err := wsManager.SendToUser(userID, welcomeMsg); err != nil {
-		log.Printf("[WebSocket] 发送欢迎消息失败: %v", err)
-	}
+	c.manager.enqueue(c, data)
+}
+
+// readPump 读取客户端消息，处理心跳和订阅请求
+func (c *Client) readPump() {
+	defer func() {
+		c.manager.removeClient(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
 
-	// 保持连接活跃
 	for {
-		messageType, p, err := conn.ReadMessage()
+		_, p, err := c.conn.ReadMessage()
 		if err != nil {
-			log.Printf("[WebSocket] 读取消息失败: %v", err)
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("[WebSocket] 读取消息失败: %v", err)
+			}
 			break
 		}
-		log.Printf("[WebSocket] 收到消息: type=%d, payload=%s", messageType, string(p))
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
 
-		// 处理心跳消息
+		// 兼容前端的文本心跳
 		if string(p) == "ping" {
-			if err := conn.WriteMessage(websocket.TextMessage, []byte("pong")); err != nil {
-				log.Printf("[WebSocket] 发送pong失败: %v", err)
-				break
+			c.manager.enqueue(c, []byte("pong"))
+			continue
+		}
+
+		var req ClientRequest
+		if err := json.Unmarshal(p, &req); err != nil {
+			continue
+		}
+
+		switch req.Type {
+		case "subscribe":
+			if !c.manager.canSubscribe(c, req.Topic) {
+				c.sendJSON(WebSocketMessage{
+					Type: "error",
+					Data: map[string]interface{}{"message": "无权订阅该主题", "topic": req.Topic},
+				})
+				continue
 			}
+			c.manager.subscribe(c, req.Topic)
+			c.sendJSON(WebSocketMessage{Type: "subscribed", Data: map[string]interface{}{"topic": req.Topic}})
+		case "unsubscribe":
+			c.manager.unsubscribe(c, req.Topic)
+			c.sendJSON(WebSocketMessage{Type: "unsubscribed", Data: map[string]interface{}{"topic": req.Topic}})
 		}
 	}
 }
 
-// BroadcastToUser 向指定用户广播消息
-func (m *WebSocketManager) BroadcastToUser(userID uint, msg interface{}) error {
-	log.Printf("[WebSocket] Broadcasting message to user %d: %+v", userID, msg)
-	return m.SendToUser(userID, WebSocketMessage{
-		Type: "judge_result",
-		Data: msg,
-	})
+// writePump 是唯一写连接的协程，负责发送消息和定时 ping
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// 连接已被移除
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
 }
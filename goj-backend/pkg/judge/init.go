@@ -1,11 +1,18 @@
 package judge
 
 import (
+	"errors"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/remote"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-// Init 初始化评测系统
+// Init 初始化评测系统，由评测机进程 (cmd/judge-worker) 调用
 func Init() error {
 	// 创建评测管理器
 	judgeManager := manager.NewJudgeManager(
@@ -16,5 +23,60 @@ func Init() error {
 	// 启动评测管理器
 	judgeManager.Start()
 
+	// 测试本节点速度
+	if config.Judge.BenchmarkEnabled {
+		judgeManager.StartBenchmark()
+	}
+
+	// 启动远程评测
+	if err := remote.Start(); err != nil {
+		log.Printf("[Judge] Failed to start remote judge: %v", err)
+	}
+
 	return nil
 }
+
+// InitResultConsumer 启动结果消费者，由 API 服务调用。
+// 评测机把结果写入 judge:result，这里负责落库并发布最终的评测进度。
+// 结果落库成功后才从处理中列表删除，失败时放回队列重试，不会丢失
+func InitResultConsumer() {
+	resultHandler := handler.NewResultHandler()
+	handler.StartTraceCleanup()
+
+	if count, err := manager.RecoverResults(); err != nil {
+		log.Printf("[Judge] Failed to recover unfinished results: %v", err)
+	} else if count > 0 {
+		log.Printf("[Judge] Recovered %d unfinished results", count)
+	}
+
+	go func() {
+		log.Printf("[Judge] Starting result consumer")
+		for {
+			result, raw, err := manager.GetFromResultQueue()
+			if err != nil {
+				log.Printf("[Judge] Failed to get result: %v", err)
+				if raw != "" {
+					// 无法解析的结果重试也不会成功，直接丢弃
+					manager.AckResult(raw)
+				}
+				time.Sleep(time.Second) // 获取失败时等待一秒
+				continue
+			}
+
+			err = resultHandler.HandleResult(result)
+			if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+				// 提交已被删除时同样丢弃
+				if err := manager.AckResult(raw); err != nil {
+					log.Printf("[Judge] Failed to ack result for submission %d: %v", result.ID, err)
+				}
+				continue
+			}
+
+			log.Printf("[Judge] Failed to handle result for submission %d, requeueing: %v", result.ID, err)
+			if err := manager.RequeueResult(raw); err != nil {
+				log.Printf("[Judge] Failed to requeue result for submission %d: %v", result.ID, err)
+			}
+			time.Sleep(time.Second) // 数据库故障时避免反复重试
+		}
+	}()
+}
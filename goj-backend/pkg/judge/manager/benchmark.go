@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	BenchmarkKey          = "judge:benchmarks"          // 各节点的测试结果，hash: 节点 -> BenchmarkResult
+	BenchmarkReferenceKey = "judge:benchmark:reference" // 基准用时(ms)，未配置时取第一次测试的结果
+	BenchmarkChannel      = "judge:benchmark:run"       // 通知所有评测进程重新测试
+
+	benchmarkRuns = 3
+)
+
+// benchmarkCode 固定的 CPU 与内存访问负载，参考机器上约 1 秒
+const benchmarkCode = `#include <cstdio>
+const int N = 1 << 22;
+static unsigned a[N];
+int main() {
+    unsigned x = 12345;
+    for (int round = 0; round < 40; round++)
+        for (int i = 0; i < N; i++) {
+            x = x * 1103515245u + 12345u;
+            a[(x >> 8) & (N - 1)] += x;
+        }
+    unsigned s = 0;
+    for (int i = 0; i < N; i++) s ^= a[i];
+    printf("%u\n", s);
+    return 0;
+}
+`
+
+// Benchmark 在评测机上运行固定负载，计算并保存本节点的速度系数
+func (m *JudgeManager) Benchmark() (*types.BenchmarkResult, error) {
+	s := &LanguageStrategy{judgeAddr: m.judgeAddr}
+	defer func() { cleanupFiles(m.judgeAddr, s.cachedIds) }()
+
+	compiled, err := s.compileSource("bench.cpp", "bench", benchmarkCode, types.TracePhaseCompile)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]int, 0, benchmarkRuns)
+	for i := 0; i < benchmarkRuns; i++ {
+		resp, err := s.send(context.Background(), types.TracePhaseRun, i+1, types.SandboxRequest{
+			Cmd: []types.SandboxCmd{{
+				Args: []string{"./bench"},
+				Env:  []string{"PATH=/usr/bin:/bin"},
+				Files: []interface{}{
+					map[string]string{"content": ""},
+					map[string]interface{}{"name": "stdout", "max": 1024},
+					map[string]interface{}{"name": "stderr", "max": 1024},
+				},
+				CpuLimit:    20000000000, // 20s
+				MemoryLimit: 256 << 20,   // 256MB
+				ProcLimit:   1,
+				CopyIn: map[string]interface{}{
+					"bench": map[string]string{"fileId": compiled.fileId},
+				},
+			}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp[0].Status != "Accepted" {
+			return nil, fmt.Errorf("benchmark run failed: %s", resp[0].Status)
+		}
+		times = append(times, int(resp[0].Time/1000000))
+	}
+	sort.Ints(times)
+	median := times[len(times)/2]
+	if median < 1 {
+		median = 1
+	}
+
+	ctx := context.Background()
+	reference := config.Judge.BenchmarkReferenceMs
+	if reference <= 0 {
+		// 未配置基准时以第一台完成测试的节点为基准
+		config.RDB.SetNX(ctx, BenchmarkReferenceKey, median, 0)
+		reference, _ = config.RDB.Get(ctx, BenchmarkReferenceKey).Int()
+		if reference <= 0 {
+			reference = median
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	result := &types.BenchmarkResult{
+		Node:       hostname + "@" + m.judgeAddr,
+		JudgeAddr:  m.judgeAddr,
+		TimeMs:     median,
+		Factor:     math.Round(float64(median)/float64(reference)*1000) / 1000,
+		MeasuredAt: time.Now().UnixMilli(),
+	}
+	data, _ := json.Marshal(result)
+	if err := config.RDB.HSet(ctx, BenchmarkKey, result.Node, data).Err(); err != nil {
+		return nil, err
+	}
+
+	m.setNodeFactor(result.Factor)
+	log.Printf("[Benchmark] Node %s: %dms, factor %.3f (reference %dms)", result.Node, median, result.Factor, reference)
+	return result, nil
+}
+
+// StartBenchmark 启动时测试一次，并在收到通知时重新测试
+func (m *JudgeManager) StartBenchmark() {
+	go func() {
+		if _, err := m.Benchmark(); err != nil {
+			log.Printf("[Benchmark] Failed: %v", err)
+		}
+
+		pubsub := config.RDB.Subscribe(context.Background(), BenchmarkChannel)
+		defer pubsub.Close()
+		for range pubsub.Channel() {
+			if _, err := m.Benchmark(); err != nil {
+				log.Printf("[Benchmark] Failed: %v", err)
+			}
+		}
+	}()
+}
+
+// RequestBenchmark 通知所有评测进程重新测试
+func RequestBenchmark() error {
+	return config.RDB.Publish(context.Background(), BenchmarkChannel, "").Err()
+}
+
+// GetBenchmarks 获取所有节点的测试结果
+func GetBenchmarks() ([]types.BenchmarkResult, error) {
+	values, err := config.RDB.HGetAll(context.Background(), BenchmarkKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]types.BenchmarkResult, 0, len(values))
+	for _, value := range values {
+		var result types.BenchmarkResult
+		if err := json.Unmarshal([]byte(value), &result); err == nil {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Node < results[j].Node })
+	return results, nil
+}
+
+// ClusterFactor 所有节点速度系数的平均值，没有测试结果时返回 0
+func ClusterFactor() float64 {
+	results, err := GetBenchmarks()
+	if err != nil || len(results) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, result := range results {
+		sum += result.Factor
+	}
+	return math.Round(sum/float64(len(results))*1000) / 1000
+}
+
+// ScaleTimeLimit 按节点与题目标定时的速度系数换算时间限制，题目未标定或节点未测试时不换算
+func ScaleTimeLimit(timeLimit int64, calibrationFactor, nodeFactor float64) int64 {
+	if calibrationFactor <= 0 || nodeFactor <= 0 {
+		return timeLimit
+	}
+	return int64(math.Ceil(float64(timeLimit) * nodeFactor / calibrationFactor))
+}
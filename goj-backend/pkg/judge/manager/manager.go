@@ -3,34 +3,37 @@ package manager
 import (
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
-	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"log"
+	"net/http"
+	"sync"
 	"time"
 )
 
+// JudgeManager 评测管理器，只负责从队列取任务、调用评测机并回传结果，
+// 数据库写入和 WebSocket 推送由 API 服务消费结果队列完成
 type JudgeManager struct {
-	judgeAddr     string
-	concurrency   int
-	ws            *handler.WebSocketManager
-	resultHandler *handler.ResultHandler
-	semaphore     chan struct{}
-	timeout       time.Duration   // 最长执行时间
-	maxRetries    int             // 最大重试次数
-	retryDelays   []time.Duration // 重试间隔
+	judgeAddr   string
+	concurrency int
+	semaphore   chan struct{}
+	timeout     time.Duration   // 最长执行时间
+	maxRetries  int             // 最大重试次数
+	retryDelays []time.Duration // 重试间隔
+	slots       *runSlots       // 节点级的测试点运行名额，所有提交共享
+
+	factorMu   sync.RWMutex
+	nodeFactor float64 // 本节点的速度系数，未测试时为 0
 }
 
 func NewJudgeManager(judgeAddr string, concurrency int) *JudgeManager {
-	ws := handler.NewWebSocketManager()
 	return &JudgeManager{
-		judgeAddr:     judgeAddr,
-		concurrency:   concurrency,
-		ws:            ws,
-		resultHandler: handler.NewResultHandler(ws, judgeAddr),
-		semaphore:     make(chan struct{}, concurrency),
-		timeout:       600 * time.Second,                                                    // 600秒
-		maxRetries:    1,                                                                    // 3次重试
-		retryDelays:   []time.Duration{3 * time.Second, 10 * time.Second, 60 * time.Second}, // 重试间隔
+		judgeAddr:   judgeAddr,
+		concurrency: concurrency,
+		semaphore:   make(chan struct{}, concurrency),
+		timeout:     600 * time.Second,                                                    // 600秒
+		maxRetries:  1,                                                                    // 3次重试
+		retryDelays: []time.Duration{3 * time.Second, 10 * time.Second, 60 * time.Second}, // 重试间隔
+		slots:       newRunSlots(config.Judge.NodeMaxRuns),
 	}
 }
 
@@ -102,19 +105,21 @@ func (m *JudgeManager) processQueue() {
 
 			// 如果所有重试都失败
 			if err != nil {
+				var trace []byte
+				if result != nil {
+					trace = result.Trace
+				}
 				result = &types.JudgeResult{
 					ID:        task.ID,
+					UserID:    task.UserID,
+					ProblemID: task.ProblemID,
 					Status:    types.StatusSystemError,
 					ErrorInfo: err.Error(),
+					Trace:     trace,
 				}
 			}
 
-			// 处理结果
-			if err := m.resultHandler.HandleResult(result); err != nil {
-				log.Printf("[Manager] Failed to handle result: %v", err)
-			}
-
-			// 发送到结果队列
+			// 发送到结果队列，由 API 服务落库并推送
 			if err := SendJudgeResult(result); err != nil {
 				log.Printf("[Manager] Failed to send result: %v", err)
 			}
@@ -122,26 +127,96 @@ func (m *JudgeManager) processQueue() {
 	}
 }
 
+func (m *JudgeManager) setNodeFactor(factor float64) {
+	m.factorMu.Lock()
+	m.nodeFactor = factor
+	m.factorMu.Unlock()
+}
+
+func (m *JudgeManager) getNodeFactor() float64 {
+	m.factorMu.RLock()
+	defer m.factorMu.RUnlock()
+	return m.nodeFactor
+}
+
 // executeJudge 执行评测
 func (m *JudgeManager) executeJudge(task *types.JudgeTask) (*types.JudgeResult, error) {
+	// 按本节点速度换算时间限制，重试时使用同一个原始限制
+	scaled := *task
+	scaled.TimeLimit = ScaleTimeLimit(task.TimeLimit, task.CalibrationFactor, m.getNodeFactor())
+	if scaled.TimeLimit != task.TimeLimit {
+		log.Printf("[Manager] Task %d time limit scaled: %dms -> %dms", task.ID, task.TimeLimit, scaled.TimeLimit)
+	}
+	task = &scaled
+
 	// 获取语言配置
-	langConfig, ok := config.Language.Languages[task.Language]
+	langConfig, ok := config.GetLanguage(task.Language)
 	if !ok {
 		return nil, fmt.Errorf("unsupported language: %s", task.Language)
 	}
 
 	// 使用统一的评测策略
 	strategy := &LanguageStrategy{
-		judgeAddr: m.judgeAddr,
-		config:    &langConfig,
+		judgeAddr:   m.judgeAddr,
+		config:      &langConfig,
+		mode:        config.Judge.TestcaseMode,
+		parallelism: config.Judge.TestcaseParallelism,
+		slots:       m.slots,
+		recheck: recheckPolicy{
+			count:  config.Judge.RecheckCount,
+			margin: config.Judge.RecheckMargin,
+			pick:   config.Judge.RecheckPick,
+		},
+	}
+	if config.Judge.TraceEnabled {
+		strategy.tracer = newTracer(task, m.judgeAddr, config.Judge.TraceMaxFieldBytes)
 	}
 
 	result, err := strategy.Judge(task)
+	// 评测结束后清理评测机上缓存的文件
+	defer cleanupFiles(m.judgeAddr, strategy.cachedIds)
 	if err != nil {
 		log.Printf("[Manager] Judge error for task %d: %v", task.ID, err)
+		// 系统错误同样保留追踪，便于排查
+		if result == nil && strategy.tracer != nil {
+			result = &types.JudgeResult{
+				ID:        task.ID,
+				UserID:    task.UserID,
+				ProblemID: task.ProblemID,
+				Status:    types.StatusSystemError,
+				ErrorInfo: err.Error(),
+			}
+		}
 	} else {
 		log.Printf("[Manager] Judge completed for task %d with status: %s", task.ID, result.Status)
 	}
 
+	if result != nil {
+		result.Trace = strategy.tracer.encode()
+	}
+
 	return result, err
 }
+
+// cleanupFiles 删除评测机上缓存的文件
+func cleanupFiles(judgeAddr string, ids []string) {
+	for _, id := range ids {
+		if err := deleteCachedFile(judgeAddr, id); err != nil {
+			log.Printf("[Manager] Failed to delete cached file %s: %v", id, err)
+		}
+	}
+}
+
+// deleteCachedFile 删除单个缓存文件
+func deleteCachedFile(judgeAddr, id string) error {
+	req, err := http.NewRequest(http.MethodDelete, judgeAddr+"/file/"+id, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
@@ -3,15 +3,25 @@ package manager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"log"
+	"os"
+
+	"github.com/redis/go-redis/v9"
 )
 
 const (
 	JudgeQueueKey  = "judge:queue"  // Redis队列键
+	RemoteQueueKey = "judge:remote" // 远程题目的评测队列
 	ResultQueueKey = "judge:result" // 结果队列键
+
+	// ResultProcessingKey 正在落库的结果，后接 API 实例的主机名。落库成功后才删除，
+	// 失败时放回结果队列，进程退出时遗留的结果在下次启动时恢复
+	ResultProcessingKey = "judge:result:processing:%s"
 )
 
 // SendToJudgeQueue 发送任务到评测队列
@@ -25,22 +35,42 @@ func SendToJudgeQueue(task *types.JudgeTask) error {
 		return fmt.Errorf("failed to marshal task: %v", err)
 	}
 
+	// 远程题目交给远程评测处理
+	queueKey := JudgeQueueKey
+	if task.ProblemType == types.ProblemTypeRemote {
+		queueKey = RemoteQueueKey
+	}
+
 	ctx := context.Background()
-	if err := config.RDB.LPush(ctx, JudgeQueueKey, jsonData).Err(); err != nil {
+	if err := config.RDB.LPush(ctx, queueKey, jsonData).Err(); err != nil {
 		// log.Printf("\033[31m[Queue] Failed to push task %d to queue: %v\033[0m", task.ID, err)
 		return fmt.Errorf("failed to push task to queue: %v", err)
 	}
 
 	// log.Printf("\033[31m[Queue] Task %d pushed to queue with code length: %d\033[0m", task.ID, len(task.Code))
+	handler.PublishProgress(&types.JudgeProgress{
+		SubmissionID: task.ID,
+		UserID:       task.UserID,
+		Stage:        types.ProgressQueued,
+	})
 	return nil
 }
 
 // GetFromJudgeQueue 从评测队列获取任务
 func GetFromJudgeQueue() (*types.JudgeTask, error) {
+	return getTask(JudgeQueueKey)
+}
+
+// GetFromRemoteQueue 从远程评测队列获取任务
+func GetFromRemoteQueue() (*types.JudgeTask, error) {
+	return getTask(RemoteQueueKey)
+}
+
+func getTask(queueKey string) (*types.JudgeTask, error) {
 	ctx := context.Background()
 
 	// 使用阻塞式获取
-	result, err := config.RDB.BRPop(ctx, 0, JudgeQueueKey).Result()
+	result, err := config.RDB.BRPop(ctx, 0, queueKey).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to pop from queue: %v", err)
 	}
@@ -71,3 +101,63 @@ func SendJudgeResult(result *types.JudgeResult) error {
 	log.Printf("[Queue] Result for task %d pushed to result queue", result.ID)
 	return nil
 }
+
+// resultProcessingKey 本实例的处理中列表
+func resultProcessingKey() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "default"
+	}
+	return fmt.Sprintf(ResultProcessingKey, hostname)
+}
+
+// GetFromResultQueue 从结果队列获取评测结果，同时移入处理中列表。
+// 返回原始数据，处理完成后交给 AckResult 或 RequeueResult
+func GetFromResultQueue() (*types.JudgeResult, string, error) {
+	ctx := context.Background()
+
+	// 使用阻塞式获取
+	raw, err := config.RDB.BLMove(ctx, ResultQueueKey, resultProcessingKey(), "RIGHT", "LEFT", 0).Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to pop from result queue: %v", err)
+	}
+
+	var judgeResult types.JudgeResult
+	if err := json.Unmarshal([]byte(raw), &judgeResult); err != nil {
+		return nil, raw, fmt.Errorf("failed to unmarshal result: %v", err)
+	}
+
+	return &judgeResult, raw, nil
+}
+
+// AckResult 结果已落库，从处理中列表删除
+func AckResult(raw string) error {
+	return config.RDB.LRem(context.Background(), resultProcessingKey(), 1, raw).Err()
+}
+
+// RequeueResult 落库失败，把结果放回结果队列末尾稍后重试
+func RequeueResult(raw string) error {
+	ctx := context.Background()
+	_, err := config.RDB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, resultProcessingKey(), 1, raw)
+		pipe.LPush(ctx, ResultQueueKey, raw)
+		return nil
+	})
+	return err
+}
+
+// RecoverResults 把上次进程退出时未处理完的结果放回结果队列，返回恢复的数量
+func RecoverResults() (int, error) {
+	ctx := context.Background()
+	count := 0
+	for {
+		_, err := config.RDB.LMove(ctx, resultProcessingKey(), ResultQueueKey, "RIGHT", "RIGHT").Result()
+		if errors.Is(err, redis.Nil) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
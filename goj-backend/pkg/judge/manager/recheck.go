@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"context"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"sort"
+)
+
+// maxTLERechecks 单个提交最多复测的超时测试点数，避免死循环等明显超时的程序把评测量放大许多倍
+const maxTLERechecks = 2
+
+// recheckPolicy 临界超时复测策略。共享评测机上运行时间有波动，运行时间落在时间限制附近
+// （或超时不多）的测试点会重新运行若干次，按最好或中位数的一次判定
+type recheckPolicy struct {
+	count  int    // 复测次数，0 表示不复测
+	margin int    // 触发复测的范围，为时间限制的百分比
+	pick   string // best 或 median
+}
+
+// limitMs 放大后的时间限制(ms)
+func limitMs(task *types.JudgeTask, amplify int) int {
+	return int(task.TimeLimit) * amplify
+}
+
+// needed 测试点结果是否需要复测，超时的测试点还受单个提交的复测数量限制
+func (p recheckPolicy) needed(task *types.JudgeTask, amplify int, result *types.TestCaseResult) bool {
+	if p.count <= 0 || result == nil {
+		return false
+	}
+	limit := limitMs(task, amplify)
+	switch result.Status {
+	case types.StatusTimeLimitExceeded:
+		return true
+	case types.StatusAccepted:
+		return result.TimeUsed*100 >= limit*(100-p.margin)
+	}
+	return false
+}
+
+// recheckCase 对临界的测试点复测。复测时 CPU 限制放宽 margin，以便测出超时不多的真实用时，
+// 最终超出原时间限制的运行仍判为超时
+func (s *LanguageStrategy) recheckCase(ctx context.Context, task *types.JudgeTask, i int, tc types.TestCase, execFileId string, spjCompileResult *struct{ fileId string }, first *types.TestCaseResult) (*types.TestCaseResult, error) {
+	amplify := s.config.Run.LimitAmplify
+	if !s.recheck.needed(task, amplify, first) {
+		return first, nil
+	}
+
+	// 超时的测试点只复测有限几个，由复测结果判断是否只超时不多
+	timedOut := first.Status == types.StatusTimeLimitExceeded
+	if timedOut && s.tleRechecks.Add(1) > maxTLERechecks {
+		return first, nil
+	}
+
+	limit := limitMs(task, amplify)
+	runs := []*types.TestCaseResult{first}
+	for k := 0; k < s.recheck.count; k++ {
+		cmd := s.buildRunCmd(task, i, tc, execFileId)
+		cmd.CpuLimit = cmd.CpuLimit * int64(100+s.recheck.margin) / 100
+		resp, err := s.send(ctx, types.TracePhaseRecheck, i+1, types.SandboxRequest{Cmd: []types.SandboxCmd{cmd}})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		result, err := s.evaluate(task, i, tc, resp[0], spjCompileResult)
+		if err != nil {
+			return nil, err
+		}
+		// 放宽限制后仍然超时，说明超出时间限制不止 margin，不再继续复测
+		hopeless := result.Status == types.StatusTimeLimitExceeded
+		if result.Status == types.StatusAccepted && result.TimeUsed > limit {
+			result.Status = types.StatusTimeLimitExceeded
+		}
+		runs = append(runs, result)
+		if hopeless {
+			break
+		}
+	}
+
+	return pickRun(runs, s.recheck.pick), nil
+}
+
+// pickRun 从首次运行和复测中选出判定用的一次，记录复测次数和每次的用时
+func pickRun(runs []*types.TestCaseResult, pick string) *types.TestCaseResult {
+	times := make([]int, len(runs))
+	for k, run := range runs {
+		times[k] = run.TimeUsed
+	}
+
+	// 只在通过或超时的运行中选择，复测中偶发的 WA、RE 不会替换原结果
+	var candidates []*types.TestCaseResult
+	for _, run := range runs {
+		if run.Status == types.StatusAccepted || run.Status == types.StatusTimeLimitExceeded {
+			candidates = append(candidates, run)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = []*types.TestCaseResult{runs[0]}
+	}
+
+	// 按运行时间排序后取最快或中位数的一次
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].TimeUsed < candidates[b].TimeUsed
+	})
+	chosen := *candidates[0]
+	if pick == "median" {
+		chosen = *candidates[(len(candidates)-1)/2]
+	}
+	chosen.Retries = len(runs) - 1
+	chosen.RetryTimes = times
+	return &chosen
+}
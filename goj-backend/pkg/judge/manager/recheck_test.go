@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+)
+
+func run(status string, timeUsed int) *types.TestCaseResult {
+	return &types.TestCaseResult{Status: status, TimeUsed: timeUsed}
+}
+
+func TestPickRun(t *testing.T) {
+	const (
+		ac  = types.StatusAccepted
+		tle = types.StatusTimeLimitExceeded
+		wa  = types.StatusWrongAnswer
+		re  = types.StatusRuntimeError
+	)
+
+	tests := []struct {
+		name       string
+		runs       []*types.TestCaseResult
+		pick       string
+		wantStatus string
+		wantTime   int
+	}{
+		{"最好的一次", []*types.TestCaseResult{run(tle, 1100), run(ac, 950), run(ac, 980)}, "best", ac, 950},
+		{"奇数次取中位数", []*types.TestCaseResult{run(tle, 1100), run(ac, 950), run(ac, 980)}, "median", ac, 980},
+		{"偶数次取较快的中位数", []*types.TestCaseResult{run(ac, 990), run(tle, 1050), run(ac, 900), run(tle, 1200)}, "median", ac, 990},
+		{"中位数超时则判超时", []*types.TestCaseResult{run(ac, 990), run(tle, 1050), run(tle, 1080)}, "median", tle, 1050},
+		{"忽略复测中的 WA 和 RE", []*types.TestCaseResult{run(ac, 980), run(wa, 10), run(re, 20)}, "best", ac, 980},
+		{"中位数只在 AC 和 TLE 中选", []*types.TestCaseResult{run(ac, 980), run(wa, 10), run(re, 20), run(ac, 990), run(ac, 1000)}, "median", ac, 990},
+		{"没有 AC 或 TLE 时保留首次结果", []*types.TestCaseResult{run(re, 30), run(wa, 10)}, "median", re, 30},
+		{"未复测", []*types.TestCaseResult{run(ac, 500)}, "median", ac, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickRun(tt.runs, tt.pick)
+			if got.Status != tt.wantStatus || got.TimeUsed != tt.wantTime {
+				t.Errorf("got %s %dms, want %s %dms", got.Status, got.TimeUsed, tt.wantStatus, tt.wantTime)
+			}
+			if got.Retries != len(tt.runs)-1 {
+				t.Errorf("retries = %d, want %d", got.Retries, len(tt.runs)-1)
+			}
+			times := make([]int, len(tt.runs))
+			for i, r := range tt.runs {
+				times[i] = r.TimeUsed
+			}
+			if !reflect.DeepEqual(got.RetryTimes, times) {
+				t.Errorf("retry times = %v, want %v", got.RetryTimes, times)
+			}
+		})
+	}
+}
+
+func TestPickRunDoesNotModifyRuns(t *testing.T) {
+	runs := []*types.TestCaseResult{run(types.StatusAccepted, 980), run(types.StatusAccepted, 950)}
+	pickRun(runs, "best")
+	if runs[0].TimeUsed != 980 || runs[0].Retries != 0 || runs[1].RetryTimes != nil {
+		t.Fatalf("runs were modified: %+v %+v", runs[0], runs[1])
+	}
+}
+
+func TestRecheckNeeded(t *testing.T) {
+	task := &types.JudgeTask{TimeLimit: 1000}
+	policy := recheckPolicy{count: 2, margin: 10, pick: "median"}
+
+	tests := []struct {
+		name    string
+		policy  recheckPolicy
+		amplify int
+		result  *types.TestCaseResult
+		want    bool
+	}{
+		{"超时", policy, 1, run(types.StatusTimeLimitExceeded, 1000), true},
+		{"接近时间限制的通过", policy, 1, run(types.StatusAccepted, 900), true},
+		{"远低于时间限制", policy, 1, run(types.StatusAccepted, 899), false},
+		{"按放大后的时间限制计算", policy, 2, run(types.StatusAccepted, 1500), false},
+		{"错误结果不复测", policy, 1, run(types.StatusWrongAnswer, 990), false},
+		{"未开启复测", recheckPolicy{}, 1, run(types.StatusTimeLimitExceeded, 1000), false},
+		{"没有结果", policy, 1, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.needed(task, tt.amplify, tt.result); got != tt.want {
+				t.Errorf("needed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 单个提交复测的超时测试点达到上限后，之后的超时测试点直接使用首次结果，不再发送请求
+func TestRecheckTLELimit(t *testing.T) {
+	s := &LanguageStrategy{
+		config:  &config.LangConfig{Run: config.CmdConfig{LimitAmplify: 1}},
+		recheck: recheckPolicy{count: 2, margin: 10, pick: "median"},
+	}
+	s.tleRechecks.Store(maxTLERechecks)
+
+	task := &types.JudgeTask{TimeLimit: 1000}
+	first := run(types.StatusTimeLimitExceeded, 1000)
+	got, err := s.recheckCase(context.Background(), task, 0, types.TestCase{}, "", nil, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != first || got.Retries != 0 {
+		t.Fatalf("got %+v, want the first result unchanged", got)
+	}
+}
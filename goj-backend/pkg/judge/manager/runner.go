@@ -0,0 +1,305 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"sync"
+)
+
+// runSlots 节点级的测试点运行名额，限制本评测进程同时在评测机上运行的程序数
+type runSlots struct {
+	ch chan struct{}
+	mu sync.Mutex // 保证一次获取多个名额时不会与其他批量获取交错
+}
+
+func newRunSlots(n int) *runSlots {
+	if n < 1 {
+		n = 1
+	}
+	return &runSlots{ch: make(chan struct{}, n)}
+}
+
+// acquire 获取 n 个名额，超过总数时按总数获取；ctx 取消时返回 0
+func (r *runSlots) acquire(ctx context.Context, n int) int {
+	if n > cap(r.ch) {
+		n = cap(r.ch)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for got := 0; got < n; got++ {
+		select {
+		case r.ch <- struct{}{}:
+		case <-ctx.Done():
+			r.release(got)
+			return 0
+		}
+	}
+	return n
+}
+
+// release 归还 n 个名额
+func (r *runSlots) release(n int) {
+	for i := 0; i < n; i++ {
+		<-r.ch
+	}
+}
+
+// stopTracker 按评测策略记录未通过的测试点，判断哪些测试点应跳过
+type stopTracker struct {
+	mu        sync.Mutex
+	policy    string
+	testcases []types.TestCase
+	failed    []int
+}
+
+func newStopTracker(policy string, testcases []types.TestCase) *stopTracker {
+	return &stopTracker{policy: policy, testcases: testcases}
+}
+
+// record 记录测试点结果
+func (t *stopTracker) record(i int, status string) {
+	if t.policy == "" || t.policy == types.PolicyRunAll {
+		return
+	}
+	if status == types.StatusAccepted || status == types.StatusSkipped {
+		return
+	}
+	t.mu.Lock()
+	t.failed = append(t.failed, i)
+	t.mu.Unlock()
+}
+
+// skipped 测试点 j 是否因序号更小的失败测试点而应跳过
+func (t *stopTracker) skipped(j int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, f := range t.failed {
+		if t.blocks(f, j) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocks 测试点 f 未通过时是否跳过测试点 j
+func (t *stopTracker) blocks(f, j int) bool {
+	if j <= f {
+		return false
+	}
+	switch t.policy {
+	case types.PolicyStopOnFailure:
+		return true
+	case types.PolicyStopPerSubtask:
+		return t.testcases[f].Subtask == t.testcases[j].Subtask
+	}
+	return false
+}
+
+// runCase 运行并判定单个测试点，被取消时返回 nil 结果
+func (s *LanguageStrategy) runCase(ctx context.Context, task *types.JudgeTask, i int, tc types.TestCase, execFileId string, spjCompileResult *struct{ fileId string }) (*types.TestCaseResult, error) {
+	got := s.slots.acquire(ctx, 1)
+	if got == 0 {
+		return nil, nil
+	}
+	defer s.slots.release(got)
+
+	if task.ProblemType == types.ProblemTypeTwoPhase {
+		return s.runTwoPhase(ctx, task, i, tc, execFileId, spjCompileResult)
+	}
+
+	cmd := s.buildRunCmd(task, i, tc, execFileId)
+	resp, err := s.send(ctx, types.TracePhaseRun, i+1, types.SandboxRequest{Cmd: []types.SandboxCmd{cmd}})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	result, err := s.evaluate(task, i, tc, resp[0], spjCompileResult)
+	if err != nil {
+		return nil, err
+	}
+	return s.recheckCase(ctx, task, i, tc, execFileId, spjCompileResult, result)
+}
+
+// reportCase 发布单个测试点的进度
+func (s *LanguageStrategy) reportCase(task *types.JudgeTask, i, total int, result *types.TestCaseResult) {
+	s.report(task, types.JudgeProgress{
+		Stage:      types.ProgressTestcase,
+		Status:     result.Status,
+		Index:      i + 1,
+		Total:      total,
+		TimeUsed:   result.TimeUsed,
+		MemoryUsed: result.MemoryUsed,
+	})
+}
+
+// runSequential 逐个运行测试点
+func (s *LanguageStrategy) runSequential(task *types.JudgeTask, testcases []types.TestCase, tracker *stopTracker, execFileId string, spjCompileResult *struct{ fileId string }) ([]*types.TestCaseResult, error) {
+	outcomes := make([]*types.TestCaseResult, len(testcases))
+
+	for i, tc := range testcases {
+		if tracker.skipped(i) {
+			continue
+		}
+		result, err := s.runCase(context.Background(), task, i, tc, execFileId, spjCompileResult)
+		if err != nil {
+			return nil, err
+		}
+		outcomes[i] = result
+		tracker.record(i, result.Status)
+		s.reportCase(task, i, len(testcases), result)
+	}
+
+	return outcomes, nil
+}
+
+// runParallel 在单个提交内并发运行测试点。出现失败后取消按策略应跳过的测试点，
+// 序号更小的测试点不受影响，保证结果与逐个运行一致
+func (s *LanguageStrategy) runParallel(task *types.JudgeTask, testcases []types.TestCase, tracker *stopTracker, execFileId string, spjCompileResult *struct{ fileId string }) ([]*types.TestCaseResult, error) {
+	outcomes := make([]*types.TestCaseResult, len(testcases))
+
+	workers := s.parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(testcases) {
+		workers = len(testcases)
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		cancels  = make(map[int]context.CancelFunc) // 运行中测试点的取消函数
+		wg       sync.WaitGroup
+	)
+
+	jobs := make(chan int, len(testcases))
+	for i := range testcases {
+		jobs <- i
+	}
+	close(jobs)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				mu.Lock()
+				if firstErr != nil || tracker.skipped(i) {
+					mu.Unlock()
+					continue
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				cancels[i] = cancel
+				mu.Unlock()
+
+				result, err := s.runCase(ctx, task, i, testcases[i], execFileId, spjCompileResult)
+
+				mu.Lock()
+				delete(cancels, i)
+				cancel()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					for _, c := range cancels {
+						c()
+					}
+				} else if result != nil {
+					outcomes[i] = result
+					tracker.record(i, result.Status)
+					for j, c := range cancels {
+						if tracker.blocks(i, j) {
+							c()
+						}
+					}
+				}
+				mu.Unlock()
+
+				if result != nil {
+					s.reportCase(task, i, len(testcases), result)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return outcomes, nil
+}
+
+// runBatch 把测试点按并发数分组，每组合并为一次沙箱请求
+func (s *LanguageStrategy) runBatch(task *types.JudgeTask, testcases []types.TestCase, tracker *stopTracker, execFileId string, spjCompileResult *struct{ fileId string }) ([]*types.TestCaseResult, error) {
+	outcomes := make([]*types.TestCaseResult, len(testcases))
+
+	size := s.parallelism
+	if size < 1 {
+		size = 1
+	}
+
+	pending := make([]int, 0, len(testcases))
+	for i := range testcases {
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		// 跳过已确定不需要运行的测试点后取出一组
+		var batch []int
+		rest := pending[:0]
+		for _, i := range pending {
+			if tracker.skipped(i) {
+				continue
+			}
+			if len(batch) < size {
+				batch = append(batch, i)
+			} else {
+				rest = append(rest, i)
+			}
+		}
+		pending = rest
+		if len(batch) == 0 {
+			break
+		}
+
+		cmds := make([]types.SandboxCmd, 0, len(batch))
+		for _, i := range batch {
+			cmds = append(cmds, s.buildRunCmd(task, i, testcases[i], execFileId))
+		}
+
+		got := s.slots.acquire(context.Background(), len(cmds))
+		resp, err := s.send(context.Background(), types.TracePhaseRun, batch[0]+1, types.SandboxRequest{Cmd: cmds})
+		s.slots.release(got)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) != len(cmds) {
+			return nil, fmt.Errorf("batch run returned %d results for %d testcases", len(resp), len(cmds))
+		}
+
+		for k, i := range batch {
+			result, err := s.evaluate(task, i, testcases[i], resp[k], spjCompileResult)
+			if err != nil {
+				return nil, err
+			}
+			if s.recheck.needed(task, s.config.Run.LimitAmplify, result) {
+				got := s.slots.acquire(context.Background(), 1)
+				result, err = s.recheckCase(context.Background(), task, i, testcases[i], execFileId, spjCompileResult, result)
+				s.slots.release(got)
+				if err != nil {
+					return nil, err
+				}
+			}
+			outcomes[i] = result
+			tracker.record(i, result.Status)
+			s.reportCase(task, i, len(testcases), result)
+		}
+	}
+
+	return outcomes, nil
+}
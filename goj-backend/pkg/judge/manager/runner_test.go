@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+)
+
+func TestStopTracker(t *testing.T) {
+	// 子任务 a: 0 1 2，子任务 b: 3 4，未分组的测试点 5
+	testcases := []types.TestCase{
+		{Subtask: "subtask:a"}, {Subtask: "subtask:a"}, {Subtask: "subtask:a"},
+		{Subtask: "subtask:b"}, {Subtask: "subtask:b"},
+		{Subtask: "case:6"},
+	}
+	const (
+		ac  = types.StatusAccepted
+		wa  = types.StatusWrongAnswer
+		tle = types.StatusTimeLimitExceeded
+		sk  = types.StatusSkipped
+	)
+
+	tests := []struct {
+		name     string
+		policy   string
+		statuses map[int]string
+		want     []bool
+	}{
+		{"默认运行全部", "", map[int]string{1: wa}, []bool{false, false, false, false, false, false}},
+		{"运行全部", types.PolicyRunAll, map[int]string{0: wa, 3: tle}, []bool{false, false, false, false, false, false}},
+		{"首个未通过后停止", types.PolicyStopOnFailure, map[int]string{0: ac, 2: wa}, []bool{false, false, false, true, true, true}},
+		{"通过和跳过不计为失败", types.PolicyStopOnFailure, map[int]string{0: ac, 1: sk}, []bool{false, false, false, false, false, false}},
+		{"取最早的失败", types.PolicyStopOnFailure, map[int]string{4: wa, 1: tle}, []bool{false, false, true, true, true, true}},
+		{"只跳过同一子任务", types.PolicyStopPerSubtask, map[int]string{1: wa}, []bool{false, false, true, false, false, false}},
+		{"各子任务分别停止", types.PolicyStopPerSubtask, map[int]string{0: wa, 3: tle}, []bool{false, true, true, false, true, false}},
+		{"未分组的测试点互不影响", types.PolicyStopPerSubtask, map[int]string{4: wa, 5: wa}, []bool{false, false, false, false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newStopTracker(tt.policy, testcases)
+			for i, status := range tt.statuses {
+				tracker.record(i, status)
+			}
+			for j, want := range tt.want {
+				if got := tracker.skipped(j); got != want {
+					t.Errorf("skipped(%d) = %v, want %v", j, got, want)
+				}
+			}
+		})
+	}
+}
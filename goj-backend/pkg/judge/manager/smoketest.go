@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"strings"
+)
+
+// SmokeTestResult 语言配置的编译运行测试结果
+type SmokeTestResult struct {
+	Passed     bool   `json:"passed"`
+	Status     string `json:"status"`
+	CompileLog string `json:"compileLog,omitempty"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	TimeUsed   int    `json:"timeUsed"`   // ms
+	MemoryUsed int    `json:"memoryUsed"` // KB
+}
+
+// SmokeTest 用给定的语言配置在评测机上编译并运行一段代码，
+// 用于启用或修改语言前确认编译器和运行命令可用
+func SmokeTest(judgeAddr string, lang config.LangConfig, code, input string) (*SmokeTestResult, error) {
+	if err := lang.Validate(); err != nil {
+		return nil, err
+	}
+
+	strategy := &LanguageStrategy{
+		judgeAddr: judgeAddr,
+		config:    &lang,
+	}
+	defer func() { cleanupFiles(judgeAddr, strategy.cachedIds) }()
+
+	task := &types.JudgeTask{
+		Code:        code,
+		TimeLimit:   5000,
+		MemoryLimit: 256,
+	}
+
+	execFileId := ""
+	if lang.Compile != nil {
+		compiled, err := strategy.compile(task)
+		if err != nil {
+			return &SmokeTestResult{
+				Status:     types.StatusCompileError,
+				CompileLog: err.Error(),
+			}, nil
+		}
+		execFileId = compiled.fileId
+	}
+
+	cmd := strategy.buildRunCmd(task, 0, types.TestCase{Input: input}, execFileId)
+	resp, err := strategy.send(context.Background(), types.TracePhaseRun, 1, types.SandboxRequest{Cmd: []types.SandboxCmd{cmd}})
+	if err != nil {
+		return nil, err
+	}
+	run := resp[0]
+	strategy.trackCached(run.FileIds)
+
+	return &SmokeTestResult{
+		Passed:     run.Status == "Accepted",
+		Status:     mapSandboxStatus(run.Status),
+		Stdout:     truncateString(run.Files["stdout0"], 4096),
+		Stderr:     truncateString(strings.TrimSpace(run.Files["stderr0"]+" "+run.Message), 4096),
+		TimeUsed:   int(run.Time / 1000000),
+		MemoryUsed: int(run.Memory / 1024),
+	}, nil
+}
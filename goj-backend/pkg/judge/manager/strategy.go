@@ -2,25 +2,36 @@ package manager
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
 	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// sendRequest 发送请求到评测机
-func sendRequest(judgeAddr string, req types.SandboxRequest) ([]types.SandboxResponse, error) {
+// sendRequest 发送请求到评测机，ctx 取消时评测机会终止正在运行的程序
+func sendRequest(ctx context.Context, judgeAddr string, req types.SandboxRequest) ([]types.SandboxResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	resp, err := http.Post(judgeAddr+"/run", "application/json", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, judgeAddr+"/run", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %v", err)
 	}
@@ -43,6 +54,40 @@ type JudgeStrategy interface {
 type LanguageStrategy struct {
 	judgeAddr string
 	config    *config.LangConfig
+	cachedIds []string // 评测过程中缓存在评测机上的文件ID
+	tracer    *tracer  // 评测追踪，未开启时为 nil
+
+	mode        string        // 测试点执行方式: sequential, parallel, batch
+	parallelism int           // 单个提交同时运行的测试点数
+	slots       *runSlots     // 节点级的测试点运行名额
+	recheck     recheckPolicy // 临界超时复测策略
+	tleRechecks atomic.Int32  // 已复测的超时测试点数
+	managerId   string        // 通信题交互库的缓存文件ID
+	mu          sync.Mutex    // 并行运行时保护 cachedIds
+}
+
+// send 发送沙箱请求并记录到评测追踪
+func (s *LanguageStrategy) send(ctx context.Context, phase string, index int, req types.SandboxRequest) ([]types.SandboxResponse, error) {
+	start := time.Now()
+	resp, err := sendRequest(ctx, s.judgeAddr, req)
+	s.tracer.call(phase, index, req, resp, err, time.Since(start))
+	return resp, err
+}
+
+// trackCached 记录评测机缓存的文件，评测结束后统一删除
+func (s *LanguageStrategy) trackCached(fileIds map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range fileIds {
+		s.cachedIds = append(s.cachedIds, id)
+	}
+}
+
+// report 发布评测进度
+func (s *LanguageStrategy) report(task *types.JudgeTask, p types.JudgeProgress) {
+	p.SubmissionID = task.ID
+	p.UserID = task.UserID
+	handler.PublishProgress(&p)
 }
 
 // Judge 实现评测接口
@@ -63,11 +108,31 @@ func (s *LanguageStrategy) Judge(task *types.JudgeTask) (*types.JudgeResult, err
 			}, nil
 		}
 	}
+	// 通信题需要编译交互库
+	if task.ProblemType == types.ProblemTypeTwoPhase {
+		managerCompileResult, err := s.compileManager(task.ProblemID)
+		if err != nil {
+			return &types.JudgeResult{
+				ID:        task.ID,
+				UserID:    task.UserID,
+				ProblemID: task.ProblemID,
+				Status:    types.StatusSystemError,
+				ErrorInfo: fmt.Sprintf("[Manager Compile Error] %v", err),
+			}, nil
+		}
+		s.managerId = managerCompileResult.fileId
+	}
 	// 如果需要编译
 	if s.config.Compile != nil {
 		// 编译代码
+		s.report(task, types.JudgeProgress{Stage: types.ProgressCompiling})
 		compileResult, err := s.compile(task)
 		if err != nil {
+			s.report(task, types.JudgeProgress{
+				Stage:     types.ProgressCompiled,
+				Status:    types.StatusCompileError,
+				ErrorInfo: err.Error(),
+			})
 			return &types.JudgeResult{
 				ID:        task.ID,
 				UserID:    task.UserID,
@@ -76,9 +141,11 @@ func (s *LanguageStrategy) Judge(task *types.JudgeTask) (*types.JudgeResult, err
 				ErrorInfo: err.Error(),
 			}, nil
 		}
-		
-	
-		
+		s.report(task, types.JudgeProgress{
+			Stage:  types.ProgressCompiled,
+			Status: types.StatusAccepted,
+		})
+
 		// 运行测试
 		return s.runTests(task, compileResult.fileId, spjCompileResult)
 	}
@@ -121,11 +188,14 @@ func (s *LanguageStrategy) compile(task *types.JudgeTask) (*struct{ fileId strin
 	}
 
 	// 发送编译请求
-	resp, err := sendRequest(s.judgeAddr, req)
+	defer s.tracer.phase(types.TracePhaseCompile, time.Now())
+	resp, err := s.send(context.Background(), types.TracePhaseCompile, 0, req)
 	if err != nil {
 		return nil, err
 	}
 
+	s.trackCached(resp[0].FileIds)
+
 	// 检查编译结果
 	if resp[0].Status != "Accepted" {
 		stderr := resp[0].Files["stderr"]
@@ -148,6 +218,11 @@ func getTestCases(problemID string) ([]types.TestCase, error) {
 		return nil, fmt.Errorf("failed to read data directory: %v", err)
 	}
 
+	subtasks, err := loadSubtasks(problemID)
+	if err != nil {
+		return nil, err
+	}
+
 	var testcases []types.TestCase
 	// 遍历文件，查找.in和.out文件对
 	for _, file := range files {
@@ -168,9 +243,10 @@ func getTestCases(problemID string) ([]types.TestCase, error) {
 			}
 
 			testcases = append(testcases, types.TestCase{
-				Name:   baseName,
-				Input:  string(input),
-				Output: string(output),
+				Name:    baseName,
+				Subtask: subtaskOf(subtasks, baseName),
+				Input:   string(input),
+				Output:  string(output),
 			})
 		}
 	}
@@ -182,6 +258,40 @@ func getTestCases(problemID string) ([]types.TestCase, error) {
 	return testcases, nil
 }
 
+// loadSubtasks 读取 problem.json 中的子任务分组，返回测试点名称到子任务名称的映射
+func loadSubtasks(problemID string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join("data", "problems", problemID, "problem.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read problem.json: %v", err)
+	}
+
+	var problem struct {
+		Subtasks []types.Subtask `json:"subtasks"`
+	}
+	if err := json.Unmarshal(data, &problem); err != nil {
+		return nil, fmt.Errorf("failed to parse problem.json: %v", err)
+	}
+
+	groups := make(map[string]string)
+	for _, subtask := range problem.Subtasks {
+		for _, name := range subtask.Cases {
+			groups[name] = subtask.Name
+		}
+	}
+	return groups, nil
+}
+
+// subtaskOf 返回测试点所属的子任务，未分组的测试点各自成为一个子任务
+func subtaskOf(groups map[string]string, name string) string {
+	if subtask, ok := groups[name]; ok {
+		return "subtask:" + subtask
+	}
+	return "case:" + name
+}
+
 // runTests 运行测试用例
 func (s *LanguageStrategy) runTests(task *types.JudgeTask, execFileId string, spjCompileResult *struct{ fileId string }) (*types.JudgeResult, error) {
 	solution := &types.JudgeResult{
@@ -193,142 +303,72 @@ func (s *LanguageStrategy) runTests(task *types.JudgeTask, execFileId string, sp
 		MemoryUsed: 0,
 	}
 
+	defer s.tracer.phase(types.TracePhaseTests, time.Now())
+
 	// 获取测试用例
 	testcases, err := getTestCases(task.ProblemID)
 	if err != nil {
 		return nil, err
 	}
 
+	// 按配置的方式运行测试点，结果按测试点顺序返回，未运行的为 nil
+	tracker := newStopTracker(task.Policy, testcases)
+	var outcomes []*types.TestCaseResult
+	mode := s.mode
+	if task.ProblemType == types.ProblemTypeTwoPhase && mode == "batch" {
+		mode = "sequential" // 通信题每个测试点需要多次请求，无法合并
+	}
+	switch mode {
+	case "parallel":
+		outcomes, err = s.runParallel(task, testcases, tracker, execFileId, spjCompileResult)
+	case "batch":
+		outcomes, err = s.runBatch(task, testcases, tracker, execFileId, spjCompileResult)
+	default:
+		outcomes, err = s.runSequential(task, testcases, tracker, execFileId, spjCompileResult)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 按策略应跳过的测试点记为 Skipped，并发运行时已经完成的也一并覆盖，保证结果确定
+	for i := range outcomes {
+		if outcomes[i] == nil || tracker.skipped(i) {
+			outcomes[i] = &types.TestCaseResult{Status: types.StatusSkipped}
+		}
+	}
+
 	// 初始化测试点结果
 	testCaseResults := make([]types.TestCaseResult, 0)
 	testcasesStatus := make([]string, 0)
 	testCasesInfo := make([]string, 0)
 
-	// 遍历测试点，运行用户程序
+	// 按测试点顺序合并结果
 	ac := 0
 	notAc := 0
 	maxTime := 0
 	maxMemory := 0
 
-	for i, tc := range testcases {
-		memoryLimitBytes := int64(task.MemoryLimit) * 1024 * 1024 * int64(s.config.Run.LimitAmplify)
-		timeLimitNanos := int64(task.TimeLimit) * 1000000 * int64(s.config.Run.LimitAmplify)
-
-		// 构造运行命令
-		cmd := types.SandboxCmd{
-			Args: s.config.Run.Command,
-			Env:  s.config.Env,
-			Files: []interface{}{
-				map[string]string{"content": tc.Input},
-				map[string]interface{}{
-					"name": fmt.Sprintf("stdout%d", i),
-					"max":  s.config.Run.StdoutMax,
-				},
-				map[string]interface{}{
-					"name": fmt.Sprintf("stderr%d", i),
-					"max":  s.config.Run.StderrMax,
-				},
-			},
-			CpuLimit:    timeLimitNanos,
-			MemoryLimit: memoryLimitBytes,
-			ProcLimit:   s.config.Run.ProcLimit,
-			CopyIn:      make(map[string]interface{}),
-			CopyOut:     []string{fmt.Sprintf("stdout%d", i), fmt.Sprintf("stderr%d", i)},
-		}
-
-		// 如果使用 SPJ，则需要缓存用户输出
-		if task.UseSPJ {
-			cmd.CopyOutCached = []string{fmt.Sprintf("stdout%d", i)}
-		}
-
-		// 根据是否有编译文件设置不同的输入
-		if execFileId != "" {
-			cmd.CopyIn[s.config.Compile.CompiledName] = map[string]string{
-				"fileId": execFileId,
-			}
-		} else {
-			cmd.CopyIn[s.config.Filename] = map[string]string{
-				"content": task.Code,
-			}
-		}
-
-		// 发送请求
-		resp, err := sendRequest(s.judgeAddr, types.SandboxRequest{Cmd: []types.SandboxCmd{cmd}})
-		if err != nil {
-			return nil, err
-		}
-
-		// 分析运行结果
-		result := resp[0]
-		var status string
-		var errorInfo string
-
-		if result.Status == "Accepted" {
-			log.Printf("[Judge] Program execution status: Accepted")
-			log.Printf("[Judge] UseSPJ flag: %v", task.UseSPJ)
-
-			if task.UseSPJ {
-				// 检查用户输出是否存在
-				userOutputKey := fmt.Sprintf("stdout%d", i)
-				userOutputId, ok := result.FileIds[userOutputKey]
-				if !ok {
-					log.Printf("[Judge] User output not found in FileIds: %+v", result.FileIds)
-					return nil, fmt.Errorf("user output not found")
-				}
-				log.Printf("[Judge] User output fileId: %s", userOutputId)
-
-				log.Printf("[Judge] Using special judge for problem %s", task.ProblemID)
-				// 使用特判程序
-				status, errorInfo = s.specialJudge(
-						task.ProblemID,
-						filepath.Join("data", "problems", task.ProblemID, "data", tc.Name+".in"),
-						filepath.Join("data", "problems", task.ProblemID, "data", tc.Name+".out"),
-						userOutputId,
-						spjCompileResult,
-					)
-				log.Printf("[Judge] Special judge result: status=%s, error=%s", status, errorInfo)
-			} else {
-				// 普通文本比对
-				userOutput, ok := result.Files[fmt.Sprintf("stdout%d", i)]
-				if !ok {
-					log.Printf("[Judge] User output not found in Files: %+v", result.Files)
-					return nil, fmt.Errorf("user output not found")
-				}
-				log.Printf("[Judge] Using normal text comparison")
-				status, errorInfo = s.diffJudge(tc.Output, userOutput)
-			}
-		} else {
-			log.Printf("[Judge] Program execution failed with status: %s", result.Status)
-			status = mapSandboxStatus(result.Status)
-			errorInfo = fmt.Sprintf("[%s]\n%s\n", result.Status, result.Files[fmt.Sprintf("stderr%d", i)])
-		}
-
-		// 更新测试点结果
-		timeUsed := int(result.Time / 1000000)  // ns to ms
-		memoryUsed := int(result.Memory / 1024) // bytes to KB
-
-		testCaseResults = append(testCaseResults, types.TestCaseResult{
-			Status:     status,
-			TimeUsed:   timeUsed,
-			MemoryUsed: memoryUsed,
-			ErrorInfo:  errorInfo,
-		})
-		testcasesStatus = append(testcasesStatus, status)
-		testCasesInfo = append(testCasesInfo, fmt.Sprintf("Time: %dms Memory: %dKB", timeUsed, memoryUsed))
+	for i, outcome := range outcomes {
+		testCaseResults = append(testCaseResults, *outcome)
+		testcasesStatus = append(testcasesStatus, outcome.Status)
+		testCasesInfo = append(testCasesInfo, fmt.Sprintf("Time: %dms Memory: %dKB", outcome.TimeUsed, outcome.MemoryUsed))
 
 		// 更新统计信息
-		if status == types.StatusAccepted {
+		if outcome.Status == types.StatusSkipped {
+			continue
+		}
+		if outcome.Status == types.StatusAccepted {
 			ac++
 		} else {
 			notAc++
 			if notAc == 1 { // 首个错误作为整体结果
-				solution.Status = status
-				solution.ErrorInfo = fmt.Sprintf("[Test #%d]\n%s", i+1, errorInfo)
+				solution.Status = outcome.Status
+				solution.ErrorInfo = fmt.Sprintf("[Test #%d]\n%s", i+1, outcome.ErrorInfo)
 			}
 		}
 
-		maxTime = max(maxTime, timeUsed)
-		maxMemory = max(maxMemory, memoryUsed)
+		maxTime = max(maxTime, outcome.TimeUsed)
+		maxMemory = max(maxMemory, outcome.MemoryUsed)
 	}
 
 	// 更新最终结果
@@ -349,8 +389,103 @@ func (s *LanguageStrategy) runTests(task *types.JudgeTask, execFileId string, sp
 	return solution, nil
 }
 
+// buildRunCmd 构造运行单个测试点的沙箱命令
+func (s *LanguageStrategy) buildRunCmd(task *types.JudgeTask, i int, tc types.TestCase, execFileId string) types.SandboxCmd {
+	memoryLimitBytes := int64(task.MemoryLimit) * 1024 * 1024 * int64(s.config.Run.LimitAmplify)
+	timeLimitNanos := int64(task.TimeLimit) * 1000000 * int64(s.config.Run.LimitAmplify)
+
+	// 构造运行命令
+	cmd := types.SandboxCmd{
+		Args: s.config.Run.Command,
+		Env:  s.config.Env,
+		Files: []interface{}{
+			map[string]string{"content": tc.Input},
+			map[string]interface{}{
+				"name": fmt.Sprintf("stdout%d", i),
+				"max":  s.config.Run.StdoutMax,
+			},
+			map[string]interface{}{
+				"name": fmt.Sprintf("stderr%d", i),
+				"max":  s.config.Run.StderrMax,
+			},
+		},
+		CpuLimit:    timeLimitNanos,
+		MemoryLimit: memoryLimitBytes,
+		ProcLimit:   s.config.Run.ProcLimit,
+		CopyIn:      make(map[string]interface{}),
+		CopyOut:     []string{fmt.Sprintf("stdout%d", i), fmt.Sprintf("stderr%d", i)},
+	}
+
+	// 如果使用 SPJ，则需要缓存用户输出
+	if task.UseSPJ {
+		cmd.CopyOutCached = []string{fmt.Sprintf("stdout%d", i)}
+	}
+
+	// 根据是否有编译文件设置不同的输入
+	if execFileId != "" {
+		cmd.CopyIn[s.config.Compile.CompiledName] = map[string]string{
+			"fileId": execFileId,
+		}
+	} else {
+		cmd.CopyIn[s.config.Filename] = map[string]string{
+			"content": task.Code,
+		}
+	}
+
+	return cmd
+}
+
+// evaluate 根据沙箱的运行结果判定单个测试点
+func (s *LanguageStrategy) evaluate(task *types.JudgeTask, i int, tc types.TestCase, result types.SandboxResponse, spjCompileResult *struct{ fileId string }) (*types.TestCaseResult, error) {
+	s.trackCached(result.FileIds)
+	var status string
+	var errorInfo string
+
+	if result.Status == "Accepted" {
+		if task.UseSPJ {
+			// 检查用户输出是否存在
+			userOutputKey := fmt.Sprintf("stdout%d", i)
+			userOutputId, ok := result.FileIds[userOutputKey]
+			if !ok {
+				log.Printf("[Judge] User output not found in FileIds: %+v", result.FileIds)
+				return nil, fmt.Errorf("user output not found")
+			}
+
+			// 使用特判程序
+			status, errorInfo = s.specialJudge(
+				i+1,
+				task.ProblemID,
+				filepath.Join("data", "problems", task.ProblemID, "data", tc.Name+".in"),
+				filepath.Join("data", "problems", task.ProblemID, "data", tc.Name+".out"),
+				userOutputId,
+				spjCompileResult,
+			)
+			log.Printf("[Judge] Special judge result: status=%s, error=%s", status, errorInfo)
+		} else {
+			// 普通文本比对
+			userOutput, ok := result.Files[fmt.Sprintf("stdout%d", i)]
+			if !ok {
+				log.Printf("[Judge] User output not found in Files: %+v", result.Files)
+				return nil, fmt.Errorf("user output not found")
+			}
+			status, errorInfo = s.diffJudge(tc.Output, userOutput)
+		}
+	} else {
+		log.Printf("[Judge] Program execution failed with status: %s", result.Status)
+		status = mapSandboxStatus(result.Status)
+		errorInfo = fmt.Sprintf("[%s]\n%s\n", result.Status, result.Files[fmt.Sprintf("stderr%d", i)])
+	}
+
+	return &types.TestCaseResult{
+		Status:     status,
+		TimeUsed:   int(result.Time / 1000000), // ns to ms
+		MemoryUsed: int(result.Memory / 1024),  // bytes to KB
+		ErrorInfo:  errorInfo,
+	}, nil
+}
+
 // specialJudge 特判程序评测
-func (s *LanguageStrategy) specialJudge(problemID, stdInPath, stdOutPath, userOutFileId string, spjCompileResult *struct{ fileId string }) (string, string) {
+func (s *LanguageStrategy) specialJudge(index int, problemID, stdInPath, stdOutPath, userOutFileId string, spjCompileResult *struct{ fileId string }) (string, string) {
 	log.Printf("[Judge] SPJ paths: input=%s, output=%s", stdInPath, stdOutPath)
 	log.Printf("[Judge] SPJ compile result: %+v", spjCompileResult)
 
@@ -410,7 +545,7 @@ func (s *LanguageStrategy) specialJudge(problemID, stdInPath, stdOutPath, userOu
 	log.Printf("[Judge] SPJ files: %+v", req.Cmd[0].CopyIn)
 
 	// 发送请求
-	resp, err := sendRequest(s.judgeAddr, req)
+	resp, err := s.send(context.Background(), types.TracePhaseChecker, index, req)
 	if err != nil {
 		return types.StatusSystemError, fmt.Sprintf("Failed to run SPJ: %v", err)
 	}
@@ -489,20 +624,34 @@ func mapSandboxStatus(status string) string {
 
 // compileSpj 函数用于编译特判程序
 func (s *LanguageStrategy) compileSpj(problemID string) (*struct{ fileId string }, error) {
-	// 读取特判源码
-	spjCode, err := os.ReadFile(filepath.Join("data", "problems", problemID, "spj.cpp"))
+	return s.compileProblemProgram(problemID, "spj", types.TracePhaseSpjCompile)
+}
+
+// compileManager 编译通信题的交互库
+func (s *LanguageStrategy) compileManager(problemID string) (*struct{ fileId string }, error) {
+	return s.compileProblemProgram(problemID, "manager", types.TracePhaseMgrCompile)
+}
+
+// compileProblemProgram 编译题目目录下的 C++ 辅助程序（特判程序、交互库），name 为不带后缀的文件名
+func (s *LanguageStrategy) compileProblemProgram(problemID, name, phase string) (*struct{ fileId string }, error) {
+	// 读取源码
+	spjCode, err := os.ReadFile(filepath.Join("data", "problems", problemID, name+".cpp"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read SPJ code: %v", err)
+		return nil, fmt.Errorf("failed to read %s code: %v", name, err)
 	}
+	return s.compileSource(name+".cpp", name, string(spjCode), phase)
+}
 
+// compileSource 用 g++ 编译一段 C++ 代码，返回缓存的可执行文件
+func (s *LanguageStrategy) compileSource(filename, name, code, phase string) (*struct{ fileId string }, error) {
 	// 构造编译请求 - 硬编码 SPJ 编译配置
 	req := types.SandboxRequest{
 		Cmd: []types.SandboxCmd{
 			{
 				Args: []string{
 					"/usr/bin/g++",
-					"spj.cpp",
-					"-o", "spj",
+					filename,
+					"-o", name,
 					"-O2",
 					"-std=c++17",
 					"-I/usr/local/include",
@@ -523,26 +672,28 @@ func (s *LanguageStrategy) compileSpj(problemID string) (*struct{ fileId string
 				MemoryLimit: 512 << 20,    // 512MB
 				ProcLimit:   50,
 				CopyIn: map[string]interface{}{
-					"spj.cpp": map[string]string{
-						"content": string(spjCode),
+					filename: map[string]string{
+						"content": code,
 					},
 				},
 				CopyOut:       []string{"stdout", "stderr"},
-				CopyOutCached: []string{"spj"},
+				CopyOutCached: []string{name},
 			},
 		},
 	}
 
-	resp, err := sendRequest(s.judgeAddr, req)
+	defer s.tracer.phase(phase, time.Now())
+	resp, err := s.send(context.Background(), phase, 0, req)
 	if err != nil {
 		return nil, err
 	}
+	s.trackCached(resp[0].FileIds)
 
 	if resp[0].Status != "Accepted" {
 		return nil, fmt.Errorf("compile error: %s", resp[0].Files["stderr"])
 	}
 
 	return &struct{ fileId string }{
-		fileId: resp[0].FileIds["spj"],
+		fileId: resp[0].FileIds[name],
 	}, nil
 }
@@ -0,0 +1,122 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"os"
+	"sync"
+	"time"
+)
+
+// tracer 记录一次评测中的全部沙箱调用，未开启追踪时为 nil，所有方法都可以在 nil 上调用
+type tracer struct {
+	mu       sync.Mutex
+	trace    *types.JudgeTrace
+	maxField int
+	start    time.Time
+}
+
+func newTracer(task *types.JudgeTask, judgeAddr string, maxField int) *tracer {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	return &tracer{
+		trace: &types.JudgeTrace{
+			SubmissionID: task.ID,
+			JudgeAddr:    judgeAddr,
+			Worker:       hostname,
+			Language:     task.Language,
+			StartedAt:    now.UnixMilli(),
+		},
+		maxField: maxField,
+		start:    now,
+	}
+}
+
+// call 记录一次沙箱调用
+func (t *tracer) call(phase string, index int, req types.SandboxRequest, resp []types.SandboxResponse, err error, elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+
+	c := types.TraceCall{
+		Phase:      phase,
+		Index:      index,
+		Request:    t.truncate(req),
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if resp != nil {
+		c.Response = t.truncate(resp)
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	t.trace.Calls = append(t.trace.Calls, c)
+	t.mu.Unlock()
+}
+
+// phase 记录阶段耗时
+func (t *tracer) phase(name string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.trace.Phases = append(t.trace.Phases, types.TracePhase{
+		Name:       name,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+	t.mu.Unlock()
+}
+
+// encode 结束追踪并压缩
+func (t *tracer) encode() []byte {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.trace.DurationMs = time.Since(t.start).Milliseconds()
+	data, err := types.EncodeTrace(t.trace)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// truncate 转成通用 JSON 结构后截断过长的字符串（代码、输入输出等）
+func (t *tracer) truncate(v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil
+	}
+	return truncateValue(generic, t.maxField)
+}
+
+func truncateValue(v interface{}, maxLen int) interface{} {
+	switch val := v.(type) {
+	case string:
+		if maxLen > 0 && len(val) > maxLen {
+			return val[:maxLen] + fmt.Sprintf("...(truncated, %d bytes total)", len(val))
+		}
+		return val
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = truncateValue(item, maxLen)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = truncateValue(item, maxLen)
+		}
+		return val
+	default:
+		return val
+	}
+}
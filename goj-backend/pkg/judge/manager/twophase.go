@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+)
+
+// runTwoPhase 运行通信题的一个测试点：
+//  1. 选手程序以参数 1 运行，读入测试点输入，输出缓存在评测机上
+//  2. 交互库读入测试点输入和第一次的输出，生成第二次运行的输入
+//  3. 选手程序以参数 2 运行，输出交给特判程序或文本比对判定
+//
+// 阶段之间的文件都通过 CopyOutCached/fileId 传递，不经过评测进程
+func (s *LanguageStrategy) runTwoPhase(ctx context.Context, task *types.JudgeTask, i int, tc types.TestCase, execFileId string, spjCompileResult *struct{ fileId string }) (*types.TestCaseResult, error) {
+	stdoutName := fmt.Sprintf("stdout%d", i)
+	stderrName := fmt.Sprintf("stderr%d", i)
+
+	// 第一次运行
+	first := s.buildRunCmd(task, i, tc, execFileId)
+	first.Args = append(append([]string{}, first.Args...), types.TwoPhaseArgFirst)
+	first.CopyOut = []string{stderrName}
+	first.CopyOutCached = []string{stdoutName}
+	resp, err := s.send(ctx, types.TracePhaseRun, i+1, types.SandboxRequest{Cmd: []types.SandboxCmd{first}})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	firstResult := resp[0]
+	s.trackCached(firstResult.FileIds)
+	if firstResult.Status != "Accepted" {
+		return phaseFailure(firstResult, mapSandboxStatus(firstResult.Status),
+			fmt.Sprintf("[First Run: %s]\n%s\n", firstResult.Status, firstResult.Files[stderrName])), nil
+	}
+
+	// 交互库转换第一次的输出
+	managerCmd := types.SandboxCmd{
+		Args: []string{"./manager", "input.txt", "first.out"},
+		Env:  []string{"PATH=/usr/bin:/bin"},
+		Files: []interface{}{
+			map[string]string{"content": ""},
+			map[string]interface{}{
+				"name": "stdout",
+				"max":  s.config.Run.StdoutMax,
+			},
+			map[string]interface{}{
+				"name": "stderr",
+				"max":  10240,
+			},
+		},
+		CpuLimit:    10000000000, // 10s
+		MemoryLimit: 512 << 20,   // 512MB
+		ProcLimit:   50,
+		CopyIn: map[string]interface{}{
+			"manager": map[string]string{
+				"fileId": s.managerId,
+			},
+			"input.txt": map[string]string{
+				"content": tc.Input,
+			},
+			"first.out": map[string]string{
+				"fileId": firstResult.FileIds[stdoutName],
+			},
+		},
+		CopyOut:       []string{"stderr"},
+		CopyOutCached: []string{"stdout"},
+	}
+	resp, err = s.send(ctx, types.TracePhaseManager, i+1, types.SandboxRequest{Cmd: []types.SandboxCmd{managerCmd}})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	managerResult := resp[0]
+	s.trackCached(managerResult.FileIds)
+	switch managerResult.Status {
+	case "Accepted":
+	case "Nonzero Exit Status":
+		// 交互库以非零状态退出表示第一次的输出不合法
+		return phaseFailure(firstResult, types.StatusWrongAnswer,
+			fmt.Sprintf("[Manager]\n%s\n", managerResult.Files["stderr"])), nil
+	default:
+		return phaseFailure(firstResult, types.StatusSystemError,
+			fmt.Sprintf("[Manager: %s]\n%s\n", managerResult.Status, managerResult.Files["stderr"])), nil
+	}
+
+	// 第二次运行，输入为交互库的输出
+	second := s.buildRunCmd(task, i, tc, execFileId)
+	second.Args = append(append([]string{}, second.Args...), types.TwoPhaseArgSecond)
+	second.Files[0] = map[string]string{"fileId": managerResult.FileIds["stdout"]}
+	resp, err = s.send(ctx, types.TracePhaseRun, i+1, types.SandboxRequest{Cmd: []types.SandboxCmd{second}})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	result, err := s.evaluate(task, i, tc, resp[0], spjCompileResult)
+	if err != nil {
+		return nil, err
+	}
+	// 两次运行分别限制，结果取较大值
+	result.TimeUsed = max(result.TimeUsed, int(firstResult.Time/1000000))
+	result.MemoryUsed = max(result.MemoryUsed, int(firstResult.Memory/1024))
+	return result, nil
+}
+
+// phaseFailure 通信题在第二次运行前失败时的测试点结果
+func phaseFailure(firstResult types.SandboxResponse, status, errorInfo string) *types.TestCaseResult {
+	return &types.TestCaseResult{
+		Status:     status,
+		TimeUsed:   int(firstResult.Time / 1000000), // ns to ms
+		MemoryUsed: int(firstResult.Memory / 1024),  // bytes to KB
+		ErrorInfo:  errorInfo,
+	}
+}
@@ -0,0 +1,93 @@
+// Package remote 把远程题目的提交转交给其他 OJ 评测。
+//
+// 远程题目的评测任务进入 judge:remote 队列，由评测进程取出后通过对应 OJ 的适配器
+// 提交代码、轮询结果，并把远程结果映射为本地的评测状态写入 judge:result，
+// 之后与本地评测一样由 API 服务的 ResultHandler 落库和推送。
+package remote
+
+import (
+	"context"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"strings"
+	"sync"
+)
+
+// Account 远程 OJ 的账号
+type Account struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Verdict 远程评测的当前状态
+type Verdict struct {
+	Done       bool   // 是否已出最终结果
+	Status     string // 映射后的本地状态，见 types.Status*
+	RawStatus  string // 远程 OJ 原始的状态文本
+	TimeUsed   int    // 运行时间(ms)
+	MemoryUsed int    // 内存使用(KB)
+	ErrorInfo  string // 编译信息等
+}
+
+// Adapter 远程 OJ 适配器。同一个适配器会被多个账号并发使用，实现需要保证并发安全
+type Adapter interface {
+	// Submit 使用指定账号提交代码，返回远程提交编号
+	Submit(ctx context.Context, account *Account, problemID, language, code string) (string, error)
+	// Poll 查询远程提交的状态
+	Poll(ctx context.Context, account *Account, remoteID string) (*Verdict, error)
+}
+
+// Factory 根据配置中的 options 创建适配器
+type Factory func(options map[string]string) (Adapter, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register 注册适配器类型，在 init 中调用
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+func newAdapter(name string, options map[string]string) (Adapter, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown remote adapter: %s", name)
+	}
+	return factory(options)
+}
+
+// commonVerdicts 常见 OJ 状态文本到本地状态的映射，适配器可以直接使用 MapVerdict
+var commonVerdicts = map[string]string{
+	"ac":                    types.StatusAccepted,
+	"ok":                    types.StatusAccepted,
+	"accepted":              types.StatusAccepted,
+	"wa":                    types.StatusWrongAnswer,
+	"wrong answer":          types.StatusWrongAnswer,
+	"tle":                   types.StatusTimeLimitExceeded,
+	"time limit exceeded":   types.StatusTimeLimitExceeded,
+	"mle":                   types.StatusMemoryLimitExceeded,
+	"memory limit exceeded": types.StatusMemoryLimitExceeded,
+	"re":                    types.StatusRuntimeError,
+	"runtime error":         types.StatusRuntimeError,
+	"ce":                    types.StatusCompileError,
+	"compile error":         types.StatusCompileError,
+	"compilation error":     types.StatusCompileError,
+	"pe":                    types.StatusPresentationError,
+	"presentation error":    types.StatusPresentationError,
+	"ole":                   types.StatusOutputLimitExceeded,
+	"output limit exceeded": types.StatusOutputLimitExceeded,
+}
+
+// MapVerdict 把远程 OJ 的最终状态映射为本地状态，无法识别的记为系统错误
+func MapVerdict(raw string) string {
+	if status, ok := commonVerdicts[strings.ToLower(strings.TrimSpace(raw))]; ok {
+		return status
+	}
+	return types.StatusSystemError
+}
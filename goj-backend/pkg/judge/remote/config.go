@@ -0,0 +1,52 @@
+package remote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config 远程评测配置
+type Config struct {
+	Judges map[string]JudgeConfig `yaml:"judges"` // 远程 OJ 名称（题目的 remoteOJ）到配置的映射
+}
+
+// JudgeConfig 单个远程 OJ 的配置
+type JudgeConfig struct {
+	Adapter      string            `yaml:"adapter"`       // 适配器类型
+	RateLimit    int               `yaml:"rate_limit"`    // 每个账号每分钟最多提交次数
+	PollInterval int               `yaml:"poll_interval"` // 轮询间隔(秒)
+	Timeout      int               `yaml:"timeout"`       // 等待结果的最长时间(秒)
+	Languages    map[string]string `yaml:"languages"`     // 本地语言到远程语言的映射
+	Accounts     []Account         `yaml:"accounts"`
+	Options      map[string]string `yaml:"options"` // 适配器参数
+}
+
+// loadConfig 读取远程评测配置，文件不存在时返回 nil
+func loadConfig() (*Config, error) {
+	configPath := filepath.Join("pkg", "judge", "config", "remote.yaml")
+	if os.Getenv("REMOTE_JUDGE_CONFIG") != "" {
+		configPath = os.Getenv("REMOTE_JUDGE_CONFIG")
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for name, judge := range cfg.Judges {
+		if len(judge.Accounts) == 0 {
+			return nil, fmt.Errorf("remote judge %s has no accounts", name)
+		}
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,85 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	Register("mock", newMockAdapter)
+}
+
+// mockVerdictPattern 代码中形如 "mock-verdict: WA" 的注释决定模拟的结果，默认通过
+var mockVerdictPattern = regexp.MustCompile(`mock-verdict:\s*([A-Za-z ]+)`)
+
+// mockAdapter 本地模拟的远程 OJ，用于离线测试远程评测流程。
+// options.delay 为出结果前的等待时间（秒），默认 2 秒
+type mockAdapter struct {
+	delay time.Duration
+	seq   atomic.Int64
+
+	mu          sync.Mutex
+	submissions map[string]mockSubmission
+}
+
+type mockSubmission struct {
+	verdict     string
+	submittedAt time.Time
+}
+
+func newMockAdapter(options map[string]string) (Adapter, error) {
+	delay := 2 * time.Second
+	if value, ok := options["delay"]; ok {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mock delay: %v", err)
+		}
+		delay = time.Duration(seconds) * time.Second
+	}
+	return &mockAdapter{
+		delay:       delay,
+		submissions: make(map[string]mockSubmission),
+	}, nil
+}
+
+func (m *mockAdapter) Submit(ctx context.Context, account *Account, problemID, language, code string) (string, error) {
+	verdict := "AC"
+	if match := mockVerdictPattern.FindStringSubmatch(code); match != nil {
+		verdict = match[1]
+	}
+
+	id := strconv.FormatInt(m.seq.Add(1), 10)
+	m.mu.Lock()
+	m.submissions[id] = mockSubmission{verdict: verdict, submittedAt: time.Now()}
+	m.mu.Unlock()
+	return id, nil
+}
+
+func (m *mockAdapter) Poll(ctx context.Context, account *Account, remoteID string) (*Verdict, error) {
+	m.mu.Lock()
+	sub, ok := m.submissions[remoteID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("mock submission %s not found", remoteID)
+	}
+
+	if time.Since(sub.submittedAt) < m.delay {
+		return &Verdict{RawStatus: "Judging"}, nil
+	}
+
+	m.mu.Lock()
+	delete(m.submissions, remoteID)
+	m.mu.Unlock()
+	return &Verdict{
+		Done:       true,
+		Status:     MapVerdict(sub.verdict),
+		RawStatus:  sub.verdict,
+		TimeUsed:   15,
+		MemoryUsed: 1024,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// AccountLeaseKey 账号租约，后接远程 OJ 名称和用户名，值为持有者标识
+	AccountLeaseKey = "judge:remote:lease:%s:%s"
+	// AccountWindowKey 账号的提交间隔窗口，存在期间该账号不能再次提交
+	AccountWindowKey = "judge:remote:window:%s:%s"
+
+	leaseTTL     = 30 * time.Second // 租约有效期，持有期间定期续期，进程退出后自动释放
+	acquireRetry = time.Second      // 所有账号都被占用时的重试间隔
+)
+
+// 只续期或删除自己持有的租约
+var (
+	renewLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// accountPool 远程 OJ 的账号池。账号通过 Redis 租约分配，多个实例同时消费远程评测队列时
+// 每个账号同时也只处理一个提交（从提交到出结果）；两次提交之间至少间隔 interval，
+// 避免触发远程 OJ 的频率限制
+type accountPool struct {
+	judge    string
+	accounts []Account
+	interval time.Duration
+	instance string // 本进程的标识，租约的持有者标识以它开头
+}
+
+// pooledAccount 已租到的账号，用完后交给 release
+type pooledAccount struct {
+	Account
+	key    string
+	owner  string
+	cancel context.CancelFunc // 停止续期
+}
+
+func newAccountPool(judge string, accounts []Account, interval time.Duration) *accountPool {
+	hostname, _ := os.Hostname()
+	return &accountPool{
+		judge:    judge,
+		accounts: accounts,
+		interval: interval,
+		instance: fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}
+}
+
+// acquire 租用一个空闲账号，并等待到该账号允许再次提交
+func (p *accountPool) acquire(ctx context.Context) (*pooledAccount, error) {
+	for {
+		// 从随机位置开始尝试，避免总是优先使用前面的账号
+		start := rand.Intn(len(p.accounts))
+		for i := range p.accounts {
+			account := p.accounts[(start+i)%len(p.accounts)]
+			leased, err := p.lease(ctx, account)
+			if err != nil {
+				return nil, err
+			}
+			if leased == nil {
+				continue
+			}
+			if err := p.waitWindow(ctx, leased); err != nil {
+				p.release(leased)
+				return nil, err
+			}
+			return leased, nil
+		}
+
+		select {
+		case <-time.After(acquireRetry):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// lease 尝试租用账号，账号已被占用时返回 nil
+func (p *accountPool) lease(ctx context.Context, account Account) (*pooledAccount, error) {
+	key := fmt.Sprintf(AccountLeaseKey, p.judge, account.Username)
+	owner := fmt.Sprintf("%s:%d", p.instance, time.Now().UnixNano())
+	ok, err := config.RDB.SetNX(ctx, key, owner, leaseTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("lease account: %v", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			renewed, err := renewLease.Run(renewCtx, config.RDB, []string{key}, owner, leaseTTL.Milliseconds()).Int()
+			if err == nil && renewed == 0 {
+				log.Printf("[Remote] Lease on %s/%s was lost", p.judge, account.Username)
+				return
+			}
+		}
+	}()
+
+	return &pooledAccount{Account: account, key: key, owner: owner, cancel: cancel}, nil
+}
+
+// waitWindow 等待账号的提交间隔结束，并开始新的间隔
+func (p *accountPool) waitWindow(ctx context.Context, account *pooledAccount) error {
+	if p.interval <= 0 {
+		return nil
+	}
+	key := fmt.Sprintf(AccountWindowKey, p.judge, account.Username)
+	wait, err := config.RDB.PTTL(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("check submit interval: %v", err)
+	}
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	// 持有租约时只有本进程使用该账号，直接覆盖即可
+	return config.RDB.Set(ctx, key, 1, p.interval).Err()
+}
+
+// release 停止续期并归还账号
+func (p *accountPool) release(account *pooledAccount) {
+	account.cancel()
+	if err := releaseLease.Run(context.Background(), config.RDB, []string{account.key}, account.owner).Err(); err != nil {
+		log.Printf("[Remote] Failed to release %s/%s: %v", p.judge, account.Username, err)
+	}
+}
+
+// size 账号数量
+func (p *accountPool) size() int {
+	return len(p.accounts)
+}
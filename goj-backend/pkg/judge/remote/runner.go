@@ -0,0 +1,183 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/handler"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/manager"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/judge/types"
+	"log"
+	"time"
+)
+
+// remoteJudge 一个已配置的远程 OJ
+type remoteJudge struct {
+	name         string
+	adapter      Adapter
+	pool         *accountPool
+	languages    map[string]string
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// Start 加载远程评测配置并开始消费 judge:remote 队列。没有配置文件时不启动
+func Start() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg == nil || len(cfg.Judges) == 0 {
+		log.Printf("[Remote] No remote judges configured")
+		return nil
+	}
+
+	judges := make(map[string]*remoteJudge, len(cfg.Judges))
+	concurrency := 0
+	for name, jc := range cfg.Judges {
+		adapter, err := newAdapter(jc.Adapter, jc.Options)
+		if err != nil {
+			return fmt.Errorf("remote judge %s: %v", name, err)
+		}
+
+		interval := time.Duration(0)
+		if jc.RateLimit > 0 {
+			interval = time.Minute / time.Duration(jc.RateLimit)
+		}
+		rj := &remoteJudge{
+			name:         name,
+			adapter:      adapter,
+			pool:         newAccountPool(name, jc.Accounts, interval),
+			languages:    jc.Languages,
+			pollInterval: secondsOr(jc.PollInterval, 3),
+			timeout:      secondsOr(jc.Timeout, 600),
+		}
+		judges[name] = rj
+		concurrency += rj.pool.size()
+		log.Printf("[Remote] Judge %s ready: adapter %s, %d accounts", name, jc.Adapter, rj.pool.size())
+	}
+
+	// 并发数等于账号总数，更多的任务会在账号池上排队
+	sem := make(chan struct{}, concurrency)
+	go func() {
+		for {
+			task, err := manager.GetFromRemoteQueue()
+			if err != nil {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			sem <- struct{}{}
+			go func(task *types.JudgeTask) {
+				defer func() { <-sem }()
+
+				var result *types.JudgeResult
+				if rj, ok := judges[task.RemoteOJ]; ok {
+					result = rj.judge(task)
+				} else {
+					result = failure(task, fmt.Sprintf("remote judge %s is not configured", task.RemoteOJ))
+				}
+				if err := manager.SendJudgeResult(result); err != nil {
+					log.Printf("[Remote] Failed to send result: %v", err)
+				}
+			}(task)
+		}
+	}()
+	return nil
+}
+
+// judge 提交到远程 OJ 并等待结果
+func (rj *remoteJudge) judge(task *types.JudgeTask) *types.JudgeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), rj.timeout)
+	defer cancel()
+
+	language, ok := rj.languages[task.Language]
+	if !ok {
+		return failure(task, fmt.Sprintf("language %s is not supported by %s", task.Language, rj.name))
+	}
+
+	account, err := rj.pool.acquire(ctx)
+	if err != nil {
+		return failure(task, "no remote account available: "+err.Error())
+	}
+	defer rj.pool.release(account)
+
+	remoteID, err := rj.adapter.Submit(ctx, &account.Account, task.RemoteProblemID, language, task.Code)
+	if err != nil {
+		return failure(task, "remote submit failed: "+err.Error())
+	}
+	log.Printf("[Remote] Submission %d sent to %s as %s", task.ID, rj.name, remoteID)
+
+	ticker := time.NewTicker(rj.pollInterval)
+	defer ticker.Stop()
+	lastStatus := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return failure(task, fmt.Sprintf("remote judge timeout, last status: %s", lastStatus))
+		case <-ticker.C:
+		}
+
+		verdict, err := rj.adapter.Poll(ctx, &account.Account, remoteID)
+		if err != nil {
+			log.Printf("[Remote] Poll %s/%s failed: %v", rj.name, remoteID, err)
+			continue
+		}
+		if !verdict.Done {
+			if verdict.RawStatus != lastStatus {
+				lastStatus = verdict.RawStatus
+				handler.PublishProgress(&types.JudgeProgress{
+					SubmissionID: task.ID,
+					UserID:       task.UserID,
+					Stage:        types.ProgressRemote,
+					Status:       verdict.RawStatus,
+				})
+			}
+			continue
+		}
+
+		return &types.JudgeResult{
+			ID:              task.ID,
+			UserID:          task.UserID,
+			ProblemID:       task.ProblemID,
+			Status:          verdict.Status,
+			TimeUsed:        verdict.TimeUsed,
+			MemoryUsed:      verdict.MemoryUsed,
+			ErrorInfo:       remoteErrorInfo(rj.name, remoteID, verdict),
+			TestcasesStatus: []string{verdict.Status},
+			TestCasesInfo:   []string{fmt.Sprintf("Time: %dms Memory: %dKB", verdict.TimeUsed, verdict.MemoryUsed)},
+			TestCaseResults: []types.TestCaseResult{{
+				Status:     verdict.Status,
+				TimeUsed:   verdict.TimeUsed,
+				MemoryUsed: verdict.MemoryUsed,
+				ErrorInfo:  verdict.ErrorInfo,
+			}},
+		}
+	}
+}
+
+// remoteErrorInfo 结果说明中注明远程提交编号和原始状态，便于核对
+func remoteErrorInfo(name, remoteID string, verdict *Verdict) string {
+	info := fmt.Sprintf("[Remote %s #%s: %s]", name, remoteID, verdict.RawStatus)
+	if verdict.ErrorInfo != "" {
+		info += "\n" + verdict.ErrorInfo
+	}
+	return info
+}
+
+func failure(task *types.JudgeTask, errorInfo string) *types.JudgeResult {
+	log.Printf("[Remote] Submission %d failed: %s", task.ID, errorInfo)
+	return &types.JudgeResult{
+		ID:        task.ID,
+		UserID:    task.UserID,
+		ProblemID: task.ProblemID,
+		Status:    types.StatusSystemError,
+		ErrorInfo: errorInfo,
+	}
+}
+
+func secondsOr(seconds, defaultSeconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,10 @@
+package types
+
+// BenchmarkResult 评测节点的性能测试结果
+type BenchmarkResult struct {
+	Node       string  `json:"node"`       // 节点标识：主机名@评测机地址
+	JudgeAddr  string  `json:"judgeAddr"`  // 评测机地址
+	TimeMs     int     `json:"timeMs"`     // 固定负载的 CPU 时间(ms)，取多次运行的中位数
+	Factor     float64 `json:"factor"`     // 速度系数：本节点用时 / 基准用时，越大越慢
+	MeasuredAt int64   `json:"measuredAt"` // 测试时间(毫秒)
+}
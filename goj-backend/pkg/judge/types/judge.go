@@ -18,8 +18,48 @@ const (
 	StatusSignalled           = "Signalled"
 	StatusInternalError       = "Internal Error"
 	StatusPresentationError   = "Presentation Error"
+	StatusSkipped             = "Skipped"         // 因提前终止而未运行的测试点
+	StatusSourceRejected      = "Source Rejected" // 代码未通过编译前检查，不进入评测
 )
 
+// 评测策略：出现未通过的测试点后是否继续运行
+const (
+	PolicyRunAll         = "run_all"          // 运行全部测试点
+	PolicyStopOnFailure  = "stop_on_failure"  // 首个未通过后停止
+	PolicyStopPerSubtask = "stop_per_subtask" // 子任务内首个未通过后跳过该子任务的剩余测试点
+)
+
+// IsValidPolicy 检查评测策略是否合法，空值表示使用默认策略
+func IsValidPolicy(policy string) bool {
+	switch policy {
+	case "", PolicyRunAll, PolicyStopOnFailure, PolicyStopPerSubtask:
+		return true
+	}
+	return false
+}
+
+// 题目类型
+const (
+	ProblemTypeStandard = "standard"  // 每个测试点运行一次
+	ProblemTypeTwoPhase = "two_phase" // 通信题：选手程序运行两次，第一次的输出经交互库转换后作为第二次的输入
+	ProblemTypeRemote   = "remote"    // 远程题目：提交到其他 OJ 评测
+)
+
+// 通信题两次运行时传给选手程序的参数
+const (
+	TwoPhaseArgFirst  = "1"
+	TwoPhaseArgSecond = "2"
+)
+
+// IsValidProblemType 检查题目类型是否合法，空值表示普通题目
+func IsValidProblemType(problemType string) bool {
+	switch problemType {
+	case "", ProblemTypeStandard, ProblemTypeTwoPhase, ProblemTypeRemote:
+		return true
+	}
+	return false
+}
+
 // JudgeConfig 评测配置 可能 没用到 但是不敢删
 type JudgeConfig struct {
 	TimeLimit   int  `json:"timeLimit"`   // 时间限制(ms)
@@ -29,10 +69,12 @@ type JudgeConfig struct {
 
 // TestCaseResult 单个测试点的结果
 type TestCaseResult struct {
-	Status     string `json:"status"`     // 状态
-	TimeUsed   int    `json:"timeUsed"`   // 运行时间(ms)
-	MemoryUsed int    `json:"memoryUsed"` // 内存使用(KB)
-	ErrorInfo  string `json:"errorInfo"`  // 错误信息
+	Status     string `json:"status"`               // 状态
+	TimeUsed   int    `json:"timeUsed"`             // 运行时间(ms)
+	MemoryUsed int    `json:"memoryUsed"`           // 内存使用(KB)
+	ErrorInfo  string `json:"errorInfo"`            // 错误信息
+	Retries    int    `json:"retries,omitempty"`    // 临界超时的复测次数
+	RetryTimes []int  `json:"retryTimes,omitempty"` // 包括首次在内每次运行的时间(ms)
 }
 
 // JudgeResult 评测结果
@@ -47,6 +89,7 @@ type JudgeResult struct {
 	TestcasesStatus []string         `json:"testcasesStatus"` // 兼容旧版
 	TestCasesInfo   []string         `json:"testCasesInfo"`   // 兼容旧版
 	TestCaseResults []TestCaseResult `json:"testCaseResults"` // 新增：详细的测试点结果
+	Trace           []byte           `json:"trace,omitempty"` // gzip 压缩的评测追踪，落库后清空，不推送给用户
 }
 
 // JudgeTask 评测任务
@@ -61,11 +104,50 @@ type JudgeTask struct {
 	MemoryLimit int64       // 内存限制(MB)
 	Config      JudgeConfig // 评测配置
 	UseSPJ      bool        // 是否使用特殊评测
+	Policy      string      // 评测策略，见 Policy* 常量
+	ProblemType string      // 题目类型，见 ProblemType* 常量
+
+	RemoteOJ        string // 远程题目所在的 OJ
+	RemoteProblemID string // 远程 OJ 上的题号
+
+	CalibrationFactor float64 // 题目设置时间限制时的集群速度系数，0 表示未标定
 }
 
 // TestCase 测试用例
 type TestCase struct {
-	Name   string // 测试用例名称
-	Input  string // 输入数据
-	Output string // 期望输出
+	Name    string // 测试用例名称
+	Subtask string // 所属子任务
+	Input   string // 输入数据
+	Output  string // 期望输出
+}
+
+// Subtask 子任务分组，保存在题目的 problem.json 中
+type Subtask struct {
+	Name  string   `json:"name"`  // 子任务名称
+	Cases []string `json:"cases"` // 测试点名称，不含 .in/.out 扩展名
+}
+
+// 评测进度阶段
+const (
+	ProgressQueued    = "queued"    // 已进入评测队列
+	ProgressCompiling = "compiling" // 开始编译
+	ProgressCompiled  = "compiled"  // 编译结束
+	ProgressTestcase  = "testcase"  // 单个测试点评测完成
+	ProgressRemote    = "remote"    // 远程 OJ 的评测状态变化，Status 为远程原始状态
+	ProgressFinished  = "finished"  // 评测完成且结果已落库
+)
+
+// JudgeProgress 评测进度事件，经 Redis 发布后由 API 服务推送给前端
+type JudgeProgress struct {
+	SubmissionID uint         `json:"submissionId"`
+	UserID       uint         `json:"userId"`
+	Stage        string       `json:"stage"`
+	Status       string       `json:"status,omitempty"`     // 编译结果或测试点结果
+	Index        int          `json:"index,omitempty"`      // 测试点序号，从1开始
+	Total        int          `json:"total,omitempty"`      // 测试点总数
+	TimeUsed     int          `json:"timeUsed,omitempty"`   // 运行时间(ms)
+	MemoryUsed   int          `json:"memoryUsed,omitempty"` // 内存使用(KB)
+	ErrorInfo    string       `json:"errorInfo,omitempty"`  // 编译信息等
+	Result       *JudgeResult `json:"result,omitempty"`     // 最终结果，仅 finished 阶段携带
+	Timestamp    int64        `json:"timestamp"`            // 事件时间(毫秒)
 }
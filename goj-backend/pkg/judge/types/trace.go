@@ -0,0 +1,81 @@
+package types
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+)
+
+// 评测追踪中的阶段名
+const (
+	TracePhaseSpjCompile = "spj_compile" // 编译特判程序
+	TracePhaseMgrCompile = "mgr_compile" // 编译通信题交互库
+	TracePhaseCompile    = "compile"     // 编译用户代码
+	TracePhaseRun        = "run"         // 运行单个测试点
+	TracePhaseRecheck    = "recheck"     // 临界超时复测
+	TracePhaseChecker    = "checker"     // 运行特判程序
+	TracePhaseManager    = "manager"     // 运行通信题交互库
+	TracePhaseTests      = "tests"       // 全部测试点
+)
+
+// TraceCall 一次沙箱调用，文件内容已按长度截断
+type TraceCall struct {
+	Phase      string      `json:"phase"`
+	Index      int         `json:"index,omitempty"` // 测试点序号，从1开始
+	Request    interface{} `json:"request"`
+	Response   interface{} `json:"response,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"durationMs"`
+}
+
+// TracePhase 评测阶段耗时
+type TracePhase struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// JudgeTrace 单个提交的评测追踪，供管理员排查判题结果
+type JudgeTrace struct {
+	SubmissionID uint         `json:"submissionId"`
+	JudgeAddr    string       `json:"judgeAddr"` // 评测机地址
+	Worker       string       `json:"worker"`    // 评测进程所在主机
+	Language     string       `json:"language"`
+	StartedAt    int64        `json:"startedAt"` // 开始时间(毫秒)
+	DurationMs   int64        `json:"durationMs"`
+	Phases       []TracePhase `json:"phases"`
+	Calls        []TraceCall  `json:"calls"`
+}
+
+// EncodeTrace 序列化并 gzip 压缩
+func EncodeTrace(trace *JudgeTrace) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(trace); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeTrace 解压并反序列化
+func DecodeTrace(data []byte) (*JudgeTrace, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace JudgeTrace
+	if err := json.Unmarshal(raw, &trace); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
@@ -12,6 +12,12 @@ import (
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		// 浏览器的 WebSocket 无法设置请求头，允许通过 token 参数传递
+		if authHeader == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			if token := c.Query("token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
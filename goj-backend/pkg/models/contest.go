@@ -2,11 +2,19 @@ package models
 
 import (
 	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// 比赛的参赛方式
+const (
+	ContestModeIndividual = "individual" // 个人赛
+	ContestModeTeam       = "team"       // 团队赛，以队伍为单位报名和排名
+)
+
 type Contest struct {
 	ID               string `json:"id" gorm:"primarykey;type:varchar(10)"` // 5位数字编号
 	CreatedAt        time.Time
@@ -19,8 +27,19 @@ type Contest struct {
 	Role             string         `json:"role" gorm:"type:varchar(20);default:public"` // public, private
 	Status           string         `json:"status" gorm:"type:varchar(20)"`              // not_started, running, ended
 	ParticipantCount int64          `json:"participantCount" gorm:"default:0"`
-	Problems         string         `json:"problems" gorm:"type:text"`     // 题目ID列表，用逗号分隔
-	PenaltyTime      int            `json:"penaltyTime" gorm:"default:20"` // 罚时（分钟）
+	Problems         string         `json:"problems" gorm:"type:text"`                       // 题目ID列表，用逗号分隔
+	PenaltyTime      int            `json:"penaltyTime" gorm:"default:20"`                   // 罚时（分钟）
+	JudgePolicy      string         `json:"judgePolicy" gorm:"type:varchar(20)"`             // 覆盖题目的评测策略，为空时使用题目设置
+	RegisterStart    *time.Time     `json:"registerStartTime" gorm:"type:datetime"`          // 报名开始时间，为空时创建后即可报名
+	RegisterEnd      *time.Time     `json:"registerEndTime" gorm:"type:datetime"`            // 报名截止时间，为空时比赛结束前均可报名
+	Password         string         `json:"-" gorm:"type:varchar(255)"`                      // 私有比赛的访问密码（bcrypt），为空时不能通过密码加入
+	AllowedGroups    string         `json:"allowedGroups" gorm:"type:varchar(255)"`          // 允许访问私有比赛的用户组ID，用逗号分隔
+	FreezeTime       *time.Time     `json:"freezeTime" gorm:"type:datetime"`                 // 封榜时间，为空时不封榜
+	Unfrozen         bool           `json:"unfrozen" gorm:"default:false"`                   // 是否已解除封榜
+	RuleType         string         `json:"ruleType" gorm:"type:varchar(20);default:icpc"`   // 赛制：icpc, ioi, oi, codeforces
+	Mode             string         `json:"mode" gorm:"type:varchar(20);default:individual"` // 参赛方式：individual, team
+
+	ContestProblems []ContestProblem `json:"contestProblems,omitempty" gorm:"-"` // 题目设置，获取比赛详情时填写
 }
 
 func (Contest) TableName() string {
@@ -32,11 +51,82 @@ func (c Contest) MarshalJSON() ([]byte, error) {
 	type Alias Contest
 	return json.Marshal(&struct {
 		Alias
-		StartTime string `json:"startTime"`
-		EndTime   string `json:"endTime"`
+		StartTime   string `json:"startTime"`
+		EndTime     string `json:"endTime"`
+		HasPassword bool   `json:"hasPassword"`
 	}{
-		Alias:     Alias(c),
-		StartTime: c.StartTime.UTC().Format(time.RFC3339),
-		EndTime:   c.EndTime.UTC().Format(time.RFC3339),
+		Alias:       Alias(c),
+		StartTime:   c.StartTime.UTC().Format(time.RFC3339),
+		EndTime:     c.EndTime.UTC().Format(time.RFC3339),
+		HasPassword: c.Password != "",
 	})
 }
+
+// Frozen 判断榜单当前是否处于封榜状态
+func (c Contest) Frozen(now time.Time) bool {
+	return c.FreezeTime != nil && !c.Unfrozen && !now.Before(*c.FreezeTime)
+}
+
+// RegistrationOpen 判断当前是否在报名时间内
+func (c Contest) RegistrationOpen(now time.Time) bool {
+	if c.RegisterStart != nil && now.Before(*c.RegisterStart) {
+		return false
+	}
+	if c.RegisterEnd != nil {
+		return now.Before(*c.RegisterEnd)
+	}
+	return now.Before(c.EndTime)
+}
+
+// IsValidContestMode 检查参赛方式是否合法，空值表示默认的个人赛
+func IsValidContestMode(mode string) bool {
+	return mode == "" || mode == ContestModeIndividual || mode == ContestModeTeam
+}
+
+// ParticipantStatus 比赛结束后参赛状态一律为 finished，不依赖数据库中的状态是否已更新
+func (c Contest) ParticipantStatus(status string, now time.Time) string {
+	if status != "" && now.After(c.EndTime) {
+		return ParticipantFinished
+	}
+	return status
+}
+
+// TeamMode 判断是否为团队赛
+func (c Contest) TeamMode() bool {
+	return c.Mode == ContestModeTeam
+}
+
+// AllowedGroupIDs 解析允许访问私有比赛的用户组ID
+func (c Contest) AllowedGroupIDs() []uint {
+	var ids []uint
+	for _, part := range strings.Split(c.AllowedGroups, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// CanAccess 私有比赛只对管理员、参赛者和白名单用户组成员开放
+func (c Contest) CanAccess(db *gorm.DB, userID uint, role string) bool {
+	if c.Role != "private" || role == "admin" {
+		return true
+	}
+
+	var count int64
+	db.Model(&ContestParticipant{}).
+		Where("contest_id = ? AND user_id = ?", c.ID, userID).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	groupIDs := c.AllowedGroupIDs()
+	if len(groupIDs) == 0 {
+		return false
+	}
+	db.Model(&UserGroupMember{}).
+		Where("user_id = ? AND group_id IN ?", userID, groupIDs).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContestAnnouncement 比赛公告，用于比赛中发布更正等通知。ProblemID 为空时针对整场比赛
+type ContestAnnouncement struct {
+	ID        uint `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ContestID string         `json:"contestId" gorm:"type:varchar(10);not null;index"`
+	ProblemID string         `json:"problemId" gorm:"type:varchar(10)"`
+	Title     string         `json:"title" gorm:"type:varchar(100);not null"`
+	Content   string         `json:"content" gorm:"type:text"`
+	CreatedBy uint           `json:"createdBy"`
+}
+
+func (ContestAnnouncement) TableName() string {
+	return "contest_announcements"
+}
+
+// ContestAnnouncementRead 用户最后一次查看比赛公告的时间，之后发布或修改的公告为未读
+type ContestAnnouncementRead struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	ContestID string    `json:"contestId" gorm:"type:varchar(10);not null;uniqueIndex:idx_announcement_read"`
+	UserID    uint      `json:"userId" gorm:"not null;uniqueIndex:idx_announcement_read"`
+	ReadAt    time.Time `json:"readAt" gorm:"type:datetime;not null"`
+}
+
+func (ContestAnnouncementRead) TableName() string {
+	return "contest_announcement_reads"
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ClarificationPresetAnswers 裁判回答提问时可直接选用的预设回答
+var ClarificationPresetAnswers = []string{
+	"无可奉告",
+	"请仔细阅读题面",
+	"题面无误",
+	"是",
+	"否",
+}
+
+// ContestClarification 比赛中的提问及裁判的回答。ProblemID 为空时为针对整场比赛的提问
+type ContestClarification struct {
+	ID         uint `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	ContestID  string         `json:"contestId" gorm:"type:varchar(10);not null;index"`
+	ProblemID  string         `json:"problemId" gorm:"type:varchar(10)"`
+	UserID     uint           `json:"userId" gorm:"not null;index"`
+	Question   string         `json:"question" gorm:"type:text;not null"`
+	Answer     string         `json:"answer" gorm:"type:text"`
+	AnsweredBy *uint          `json:"answeredBy"`
+	AnsweredAt *time.Time     `json:"answeredAt" gorm:"type:datetime"`
+	Public     bool           `json:"public" gorm:"default:false"` // 回答是否公开给全部参赛者
+}
+
+func (ContestClarification) TableName() string {
+	return "contest_clarifications"
+}
+
+// ContestClarificationRead 用户最后一次查看比赛提问的时间，用于计算未读数
+type ContestClarificationRead struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	ContestID string    `json:"contestId" gorm:"type:varchar(10);not null;uniqueIndex:idx_clarification_read"`
+	UserID    uint      `json:"userId" gorm:"not null;uniqueIndex:idx_clarification_read"`
+	ReadAt    time.Time `json:"readAt" gorm:"type:datetime;not null"`
+}
+
+func (ContestClarificationRead) TableName() string {
+	return "contest_clarification_reads"
+}
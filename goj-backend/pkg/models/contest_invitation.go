@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// ContestInvitation 比赛邀请码，每个邀请码只能使用一次
+type ContestInvitation struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	ContestID string     `json:"contestId" gorm:"type:varchar(10);not null;index"`
+	Code      string     `json:"code" gorm:"type:varchar(20);not null;uniqueIndex"`
+	UsedBy    *uint      `json:"usedBy"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (ContestInvitation) TableName() string {
+	return "contest_invitations"
+}
@@ -6,16 +6,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// 参赛状态：报名后为 registered，比赛中首次提交后为 participating，比赛结束后为 finished
+const (
+	ParticipantRegistered    = "registered"
+	ParticipantParticipating = "participating"
+	ParticipantFinished      = "finished"
+)
+
 type ContestParticipant struct {
 	ID        uint `json:"id" gorm:"primarykey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
-	ContestID string         `json:"contestId" gorm:"type:varchar(10);not null"`
-	UserID    uint           `json:"userID" gorm:"not null"`
+	ContestID string         `json:"contestId" gorm:"type:varchar(10);not null;uniqueIndex:idx_contest_user"`
+	UserID    uint           `json:"userID" gorm:"not null;uniqueIndex:idx_contest_user"`
 	Score     int            `json:"score" gorm:"default:0"`
 	Rank      int            `json:"rank" gorm:"default:0"`
 	Status    string         `json:"status" gorm:"type:varchar(20);default:registered"` // registered, participating, finished
+	TeamID    uint           `json:"teamId" gorm:"index;default:0"`                     // 团队赛中所属的队伍，每名队员一条记录
 }
 
 func (ContestParticipant) TableName() string {
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ContestProblem 比赛中的题目，记录展示标号、顺序、标题、分值和气球颜色。
+// Contest.Problems 仍按顺序保存题目ID，供只需要题目列表的地方使用
+type ContestProblem struct {
+	ID        uint   `json:"id" gorm:"primarykey"`
+	ContestID string `json:"contestId" gorm:"type:varchar(10);not null;uniqueIndex:idx_contest_problem;uniqueIndex:idx_contest_label"`
+	ProblemID string `json:"problemId" gorm:"type:varchar(10);not null;uniqueIndex:idx_contest_problem"`
+	Label     string `json:"label" gorm:"type:varchar(10);not null;uniqueIndex:idx_contest_label"` // 展示标号，如 A、B、C
+	Position  int    `json:"position" gorm:"not null;default:0"`                                   // 顺序，从 0 开始
+	Title     string `json:"title" gorm:"type:varchar(255)"`                                       // 比赛中显示的标题，为空时使用题目标题
+	MaxScore  int    `json:"maxScore" gorm:"default:0"`                                            // 满分，为 0 时使用赛制的默认分值
+	Color     string `json:"color" gorm:"type:varchar(20)"`                                        // 气球颜色
+}
+
+func (ContestProblem) TableName() string {
+	return "contest_problems"
+}
+
+// ProblemLabel 按顺序生成默认标号：A-Z，之后为 AA、AB...
+func ProblemLabel(index int) string {
+	label := ""
+	for index >= 0 {
+		label = string(rune('A'+index%26)) + label
+		index = index/26 - 1
+	}
+	return label
+}
+
+// LoadContestProblems 按顺序获取比赛题目。没有题目设置的比赛按 Problems 字段生成默认标号
+func LoadContestProblems(db *gorm.DB, contest *Contest) ([]ContestProblem, error) {
+	var problems []ContestProblem
+	if err := db.Where("contest_id = ?", contest.ID).Order("position").Find(&problems).Error; err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+	return DefaultContestProblems(contest), nil
+}
+
+// DefaultContestProblems 按 Problems 字段的顺序生成默认标号的比赛题目
+func DefaultContestProblems(contest *Contest) []ContestProblem {
+	var problems []ContestProblem
+	for i, problemID := range strings.Split(contest.Problems, ",") {
+		if problemID == "" {
+			continue
+		}
+		problems = append(problems, ContestProblem{
+			ContestID: contest.ID,
+			ProblemID: problemID,
+			Label:     ProblemLabel(i),
+			Position:  i,
+		})
+	}
+	return problems
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// JudgeTrace 提交的评测追踪，Data 为 gzip 压缩的 JSON
+type JudgeTrace struct {
+	SubmissionID uint      `gorm:"primarykey;autoIncrement:false"`
+	Size         int       `gorm:"not null"` // 压缩后大小(byte)
+	Data         []byte    `gorm:"type:mediumblob;not null"`
+	CreatedAt    time.Time `gorm:"index;not null"`
+}
+
+func (JudgeTrace) TableName() string {
+	return "judge_traces"
+}
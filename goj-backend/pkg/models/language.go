@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Language 评测语言，Config 为 JSON 格式的完整语言配置
+type Language struct {
+	Key       string    `json:"key" gorm:"primarykey;type:varchar(20)"` // 语言标识，如 cpp、python
+	Config    string    `json:"config" gorm:"type:text;not null"`
+	Active    bool      `json:"active" gorm:"default:false"` // 是否启用，启用前需通过编译测试
+	Revision  int       `json:"revision" gorm:"default:1"`   // 当前版本号
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Language) TableName() string {
+	return "languages"
+}
+
+// LanguageRevision 语言配置的历史版本
+type LanguageRevision struct {
+	ID          uint      `json:"id" gorm:"primarykey;autoIncrement"`
+	LanguageKey string    `json:"languageKey" gorm:"type:varchar(20);index;not null"`
+	Revision    int       `json:"revision" gorm:"not null"`
+	Config      string    `json:"config" gorm:"type:text;not null"`
+	CreatedBy   uint      `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (LanguageRevision) TableName() string {
+	return "language_revisions"
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// PlagiarismReport 查重报告，范围为一场比赛或一道题目
+type PlagiarismReport struct {
+	ID          uint       `json:"id" gorm:"primarykey;autoIncrement"`
+	Scope       string     `json:"scope" gorm:"type:varchar(20);index:idx_plagiarism_scope;not null"`   // contest, problem
+	ScopeID     string     `json:"scopeId" gorm:"type:varchar(50);index:idx_plagiarism_scope;not null"` // 比赛ID或题目ID
+	Status      string     `json:"status" gorm:"type:varchar(20);not null;default:running"`             // running, finished, failed
+	Threshold   float64    `json:"threshold" gorm:"not null"`                                           // 记录相似对的最低相似度
+	Submissions int        `json:"submissions" gorm:"default:0"`                                        // 参与比较的提交数
+	PairCount   int        `json:"pairCount" gorm:"default:0"`                                          // 可疑提交对数量
+	Automatic   bool       `json:"automatic" gorm:"default:false"`                                      // 是否为比赛结束后自动生成
+	ErrorInfo   string     `json:"errorInfo" gorm:"type:text"`
+	CreatedBy   uint       `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+}
+
+func (PlagiarismReport) TableName() string {
+	return "plagiarism_reports"
+}
+
+// PlagiarismMatch 两份代码中相互匹配的行区间（闭区间，从 1 开始）
+type PlagiarismMatch struct {
+	StartA int `json:"startA"`
+	EndA   int `json:"endA"`
+	StartB int `json:"startB"`
+	EndB   int `json:"endB"`
+}
+
+// PlagiarismMatches 用于 JSON 列的匹配区间列表
+type PlagiarismMatches []PlagiarismMatch
+
+// Value 实现 driver.Valuer 接口
+func (m PlagiarismMatches) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (m *PlagiarismMatches) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), m)
+	}
+	return json.Unmarshal(bytes, m)
+}
+
+// PlagiarismPair 报告中的一对可疑提交
+type PlagiarismPair struct {
+	ID          uint              `json:"id" gorm:"primarykey;autoIncrement"`
+	ReportID    uint              `json:"reportId" gorm:"index;not null"`
+	ProblemID   string            `json:"problemId" gorm:"type:varchar(50);not null"`
+	Language    string            `json:"language" gorm:"type:varchar(50)"`
+	SubmissionA uint              `json:"submissionA" gorm:"not null"`
+	SubmissionB uint              `json:"submissionB" gorm:"not null"`
+	UserA       uint              `json:"userA" gorm:"not null"`
+	UserB       uint              `json:"userB" gorm:"not null"`
+	UsernameA   string            `json:"usernameA" gorm:"type:varchar(50)"`
+	UsernameB   string            `json:"usernameB" gorm:"type:varchar(50)"`
+	Score       float64           `json:"score" gorm:"index;not null"` // 相似度 0~1
+	Matches     PlagiarismMatches `json:"matches" gorm:"type:json"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+func (PlagiarismPair) TableName() string {
+	return "plagiarism_pairs"
+}
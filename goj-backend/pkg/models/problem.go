@@ -12,16 +12,23 @@ type Problem struct {
 	UpdatedAt       time.Time
 	DeletedAt       gorm.DeletedAt `gorm:"index"`
 	Title           string         `json:"title" gorm:"type:varchar(100);not null"`
-	Difficulty      int            `json:"difficulty" gorm:"type:tinyint;not null"`          // 1-5 表示难度等级
-	Role            string         `json:"role" gorm:"type:varchar(20);default:public"`      // public, private, contest
-	Tag             string         `json:"tag" gorm:"type:varchar(50)"`                      // 题目标签,如 dp,greedy 等
-	AcceptedCount   int64          `json:"acceptedCount" gorm:"default:0"`                   // 通过次数
-	SubmissionCount int64          `json:"submissionCount" gorm:"default:0"`                 // 提交次数
-	Source          string         `json:"source" gorm:"type:varchar(100)"`                  // 题目来源
-	Languages       string         `json:"languages" gorm:"type:varchar(100)"`               // 支持的编程语言,如 "c,cpp,java,python"
-	TimeLimit       int64          `json:"timeLimit" gorm:"type:int;not null;default:1000"`  // 时间限制,单位ms
-	MemoryLimit     int64          `json:"memoryLimit" gorm:"type:int;not null;default:128"` // 内存限制,单位MB
-	UseSPJ          bool           `json:"useSPJ" gorm:"type:tinyint;not null;default:0"`    // 是否使用SPJ
+	Difficulty      int            `json:"difficulty" gorm:"type:tinyint;not null"`             // 1-5 表示难度等级
+	Role            string         `json:"role" gorm:"type:varchar(20);default:public"`         // public, private, contest
+	Tag             string         `json:"tag" gorm:"type:varchar(50)"`                         // 题目标签,如 dp,greedy 等
+	AcceptedCount   int64          `json:"acceptedCount" gorm:"default:0"`                      // 通过次数
+	SubmissionCount int64          `json:"submissionCount" gorm:"default:0"`                    // 提交次数
+	Source          string         `json:"source" gorm:"type:varchar(100)"`                     // 题目来源
+	Languages       string         `json:"languages" gorm:"type:varchar(100)"`                  // 支持的编程语言,如 "c,cpp,java,python"
+	TimeLimit       int64          `json:"timeLimit" gorm:"type:int;not null;default:1000"`     // 时间限制,单位ms
+	MemoryLimit     int64          `json:"memoryLimit" gorm:"type:int;not null;default:128"`    // 内存限制,单位MB
+	UseSPJ          bool           `json:"useSPJ" gorm:"type:tinyint;not null;default:0"`       // 是否使用SPJ
+	JudgePolicy     string         `json:"judgePolicy" gorm:"type:varchar(20);default:run_all"` // 评测策略: run_all, stop_on_failure, stop_per_subtask
+	MaxSourceBytes  int            `json:"maxSourceBytes" gorm:"default:0"`                     // 代码长度上限(字节)，0 表示使用全局上限
+	Type            string         `json:"type" gorm:"type:varchar(20);default:standard"`       // 题目类型: standard, two_phase, remote
+	RemoteOJ        string         `json:"remoteOJ" gorm:"type:varchar(20)"`                    // 远程题目所在的 OJ，对应 remote.yaml 中的名称
+	RemoteProblemID string         `json:"remoteProblemId" gorm:"type:varchar(50)"`             // 远程 OJ 上的题号
+	// 设置时间限制时的评测机速度系数，评测时按节点速度换算；0 表示未标定，不换算
+	CalibrationFactor float64 `json:"calibrationFactor" gorm:"default:0"`
 }
 
 func (Problem) TableName() string {
@@ -7,6 +7,7 @@ import (
 type RatingHistory struct {
 	ID        uint      `gorm:"primarykey;autoIncrement"`
 	UserID    uint      `gorm:"index;not null"`
+	TeamID    uint      `gorm:"index;default:0"` // 团队赛中为队伍的 rating 变化，此时 UserID 为 0
 	ContestID string    `gorm:"index;not null"`
 	OldRating int64     `gorm:"not null"`
 	NewRating int64     `gorm:"not null"`
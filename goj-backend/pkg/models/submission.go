@@ -49,6 +49,8 @@ type Submission struct {
 	TestcasesStatus StringArray `gorm:"type:json"`
 	TestcasesInfo   StringArray `gorm:"type:json"`
 	Role            string      `gorm:"type:varchar(50);not null;default:user"`
+	VirtualID       uint        `gorm:"index;default:0"` // 虚拟参赛ID，不为 0 时为虚拟提交，不计入正式榜单
+	TeamID          uint        `gorm:"index;default:0"` // 团队赛中提交时所属的队伍，成绩计入队伍
 	TestCaseResults string      `gorm:"column:testcase_results;type:text"`
 }
 
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxTeamMembers 队伍人数上限（含队长）
+const MaxTeamMembers = 3
+
+// Team 参加团队赛的队伍，由队长管理队员和报名
+type Team struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Name        string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:varchar(255)"`
+	CaptainID   uint      `json:"captainId" gorm:"not null;index"`
+	Rating      int64     `json:"rating" gorm:"default:1500"` // 团队赛的 rating，与队员个人 rating 分开计算
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (Team) TableName() string {
+	return "teams"
+}
+
+// 队员状态：队长邀请后为 invited，用户接受后为 joined，只有 joined 的队员随队伍报名比赛
+const (
+	TeamMemberInvited = "invited"
+	TeamMemberJoined  = "joined"
+)
+
+// TeamMember 队伍成员，一个用户可以加入多支队伍
+type TeamMember struct {
+	TeamID    uint      `json:"teamId" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"primaryKey;index"`
+	Status    string    `json:"status" gorm:"type:varchar(20);default:joined"` // invited, joined
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (TeamMember) TableName() string {
+	return "team_members"
+}
+
+// ContestTeam 报名团队赛的队伍及其参赛队员
+type ContestTeam struct {
+	ID      uint
+	Name    string
+	Members []string // 参赛队员的用户名
+}
+
+// LoadContestTeams 按报名顺序读取报名比赛的队伍
+func LoadContestTeams(db *gorm.DB, contestID string) ([]ContestTeam, error) {
+	var rows []struct {
+		TeamID   uint
+		Name     string
+		Username string
+	}
+	if err := db.Table("contest_participants").
+		Select("contest_participants.team_id, teams.name, users.username").
+		Joins("JOIN teams ON teams.id = contest_participants.team_id").
+		Joins("JOIN users ON users.id = contest_participants.user_id").
+		Where("contest_participants.contest_id = ? AND contest_participants.deleted_at IS NULL", contestID).
+		Order("contest_participants.id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	teams := make([]ContestTeam, 0)
+	index := make(map[uint]int)
+	for _, row := range rows {
+		i, ok := index[row.TeamID]
+		if !ok {
+			i = len(teams)
+			index[row.TeamID] = i
+			teams = append(teams, ContestTeam{ID: row.TeamID, Name: row.Name})
+		}
+		teams[i].Members = append(teams[i].Members, row.Username)
+	}
+	return teams, nil
+}
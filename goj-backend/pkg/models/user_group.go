@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// UserGroup 用户组，用于私有比赛的访问白名单
+type UserGroup struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Name        string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (UserGroup) TableName() string {
+	return "user_groups"
+}
+
+// UserGroupMember 用户组成员
+type UserGroupMember struct {
+	GroupID   uint      `json:"groupId" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (UserGroupMember) TableName() string {
+	return "user_group_members"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// VirtualParticipation 用户对已结束比赛的虚拟参赛。在个人的计时窗口内提交的代码记为虚拟提交，
+// 只计入该用户的虚拟榜单，不影响正式榜单和 rating
+type VirtualParticipation struct {
+	ID        uint `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ContestID string    `json:"contestId" gorm:"type:varchar(10);not null;uniqueIndex:idx_virtual_contest_user"`
+	UserID    uint      `json:"userId" gorm:"not null;uniqueIndex:idx_virtual_contest_user"`
+	StartTime time.Time `json:"startTime" gorm:"type:datetime;not null"`
+	EndTime   time.Time `json:"endTime" gorm:"type:datetime;not null"` // 开始时间加上比赛时长
+}
+
+func (VirtualParticipation) TableName() string {
+	return "virtual_participations"
+}
+
+// Running 判断虚拟参赛是否在进行中
+func (v VirtualParticipation) Running(now time.Time) bool {
+	return !now.Before(v.StartTime) && now.Before(v.EndTime)
+}
+
+// Elapsed 已进行的时间，不超过比赛时长
+func (v VirtualParticipation) Elapsed(now time.Time) time.Duration {
+	if now.Before(v.StartTime) {
+		return 0
+	}
+	if now.After(v.EndTime) {
+		return v.EndTime.Sub(v.StartTime)
+	}
+	return now.Sub(v.StartTime)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Webhook 管理员注册的事件回调地址
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primarykey;autoIncrement"`
+	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	URL       string    `json:"url" gorm:"type:varchar(500);not null"`
+	Secret    string    `json:"secret" gorm:"type:varchar(100);not null"` // 用于 HMAC-SHA256 签名
+	Events    string    `json:"events" gorm:"type:varchar(500)"`          // 订阅的事件，逗号分隔，为空表示全部
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedBy uint      `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDelivery 一次事件投递记录，失败后按退避时间重试
+type WebhookDelivery struct {
+	ID            uint       `json:"id" gorm:"primarykey;autoIncrement"`
+	WebhookID     uint       `json:"webhookId" gorm:"index;not null"`
+	EventID       string     `json:"eventId" gorm:"type:varchar(50);not null"`
+	EventType     string     `json:"eventType" gorm:"type:varchar(50);not null"`
+	Payload       string     `json:"payload" gorm:"type:mediumtext;not null"`
+	Status        string     `json:"status" gorm:"type:varchar(20);index:idx_delivery_pending;not null;default:pending"` // pending, success, failed
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt" gorm:"index:idx_delivery_pending"`
+	ResponseCode  int        `json:"responseCode"`
+	ResponseBody  string     `json:"responseBody" gorm:"type:text"` // 截断后的响应内容
+	ErrorInfo     string     `json:"errorInfo" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"createdAt" gorm:"index"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
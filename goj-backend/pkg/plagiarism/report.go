@@ -0,0 +1,232 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	ScopeContest = "contest"
+	ScopeProblem = "problem"
+
+	ReportRunning  = "running"
+	ReportFinished = "finished"
+	ReportFailed   = "failed"
+
+	DefaultThreshold = 0.6
+
+	// reportTimeout 生成时间超过该值的报告视为进程已退出，标记为失败
+	reportTimeout = 2 * time.Hour
+)
+
+// candidate 参与比较的一份提交
+type candidate struct {
+	submission models.Submission
+	doc        *Document
+}
+
+// CreateReport 创建查重报告并在后台生成
+func CreateReport(scope, scopeID string, threshold float64, createdBy uint, automatic bool) (*models.PlagiarismReport, error) {
+	if scope != ScopeContest && scope != ScopeProblem {
+		return nil, fmt.Errorf("unknown scope: %s", scope)
+	}
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+
+	report := &models.PlagiarismReport{
+		Scope:     scope,
+		ScopeID:   scopeID,
+		Status:    ReportRunning,
+		Threshold: threshold,
+		Automatic: automatic,
+		CreatedBy: createdBy,
+	}
+	if err := config.DB.Create(report).Error; err != nil {
+		return nil, err
+	}
+
+	go generate(report)
+	return report, nil
+}
+
+// generate 比较范围内的提交并写入可疑提交对
+func generate(report *models.PlagiarismReport) {
+	start := time.Now()
+	count, pairs, err := buildPairs(report)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      ReportFinished,
+		"submissions": count,
+		"pair_count":  pairs,
+		"finished_at": &now,
+	}
+	if err != nil {
+		log.Printf("[Plagiarism] Report %d failed: %v", report.ID, err)
+		updates["status"] = ReportFailed
+		updates["error_info"] = err.Error()
+	} else {
+		log.Printf("[Plagiarism] Report %d finished: %d submissions, %d pairs, took %v",
+			report.ID, count, pairs, time.Since(start))
+	}
+
+	if err := config.DB.Model(&models.PlagiarismReport{}).
+		Where("id = ?", report.ID).
+		Updates(updates).Error; err != nil {
+		log.Printf("[Plagiarism] Failed to update report %d: %v", report.ID, err)
+	}
+}
+
+// buildPairs 按题目和语言分组，两两比较不同用户的提交
+func buildPairs(report *models.PlagiarismReport) (int, int, error) {
+	submissions, err := loadSubmissions(report.Scope, report.ScopeID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	groups := make(map[string][]candidate)
+	for _, s := range submissions {
+		key := s.ProblemID + "|" + s.Language
+		groups[key] = append(groups[key], candidate{
+			submission: s,
+			doc:        NewDocument(s.Language, s.Code),
+		})
+	}
+
+	var pairs []models.PlagiarismPair
+	for _, group := range groups {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i], group[j]
+				if a.submission.UserID == b.submission.UserID {
+					continue
+				}
+				score, matches := Compare(a.doc, b.doc)
+				if score < report.Threshold {
+					continue
+				}
+				pairs = append(pairs, models.PlagiarismPair{
+					ReportID:    report.ID,
+					ProblemID:   a.submission.ProblemID,
+					Language:    a.submission.Language,
+					SubmissionA: a.submission.ID,
+					SubmissionB: b.submission.ID,
+					UserA:       a.submission.UserID,
+					UserB:       b.submission.UserID,
+					UsernameA:   a.submission.Username,
+					UsernameB:   b.submission.Username,
+					Score:       score,
+					Matches:     matches,
+				})
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Score > pairs[j].Score
+	})
+
+	if len(pairs) > 0 {
+		if err := config.DB.CreateInBatches(pairs, 100).Error; err != nil {
+			return len(submissions), 0, err
+		}
+	}
+	return len(submissions), len(pairs), nil
+}
+
+// loadSubmissions 每个用户在每道题、每种语言下只取最后一次非编译错误的提交
+func loadSubmissions(scope, scopeID string) ([]models.Submission, error) {
+	query := config.DB.Model(&models.Submission{}).
+		Select("id, user_id, username, problem_id, contest_id, language, code, status").
+		Where("status <> ?", "Compile Error")
+	if scope == ScopeContest {
+		query = query.Where("contest_id = ?", scopeID)
+	} else {
+		query = query.Where("problem_id = ?", scopeID)
+	}
+
+	var submissions []models.Submission
+	if err := query.Order("id DESC").Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	latest := make([]models.Submission, 0, len(submissions))
+	for _, s := range submissions {
+		key := fmt.Sprintf("%d|%s|%s", s.UserID, s.ProblemID, s.Language)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		latest = append(latest, s)
+	}
+	return latest, nil
+}
+
+// InitContestReportTask 定时检查刚结束的比赛并自动生成查重报告，同时清理卡住的报告
+func InitContestReportTask() {
+	go func() {
+		failStaleReports()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			failStaleReports()
+			checkEndedContests()
+		}
+	}()
+}
+
+// failStaleReports 生成报告的进程中途退出时报告会一直处于 running，超时后标记为失败
+func failStaleReports() {
+	now := time.Now()
+	result := config.DB.Model(&models.PlagiarismReport{}).
+		Where("status = ? AND created_at < ?", ReportRunning, now.Add(-reportTimeout)).
+		Updates(map[string]interface{}{
+			"status":      ReportFailed,
+			"error_info":  "生成超时，可能是服务中途重启，请重新生成",
+			"finished_at": &now,
+		})
+	if result.Error != nil {
+		log.Printf("[Plagiarism] Failed to sweep stale reports: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("[Plagiarism] Marked %d stale reports as failed", result.RowsAffected)
+	}
+}
+
+// checkEndedContests 为最近一小时内结束且还没有自动报告的比赛生成报告，管理员手动生成的报告不影响
+func checkEndedContests() {
+	now := time.Now()
+	var contests []models.Contest
+	if err := config.DB.Where("end_time <= ? AND end_time > ?", now, now.Add(-time.Hour)).
+		Find(&contests).Error; err != nil {
+		log.Printf("[Plagiarism] Failed to load ended contests: %v", err)
+		return
+	}
+
+	for _, contest := range contests {
+		var count int64
+		config.DB.Model(&models.PlagiarismReport{}).
+			Where("scope = ? AND scope_id = ? AND automatic = ?", ScopeContest, contest.ID, true).
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		// 多个 API 实例时只由一个实例生成
+		lockKey := fmt.Sprintf("plagiarism:contest:%s", contest.ID)
+		ok, err := config.RDB.SetNX(context.Background(), lockKey, 1, 2*time.Hour).Result()
+		if err != nil || !ok {
+			continue
+		}
+
+		if _, err := CreateReport(ScopeContest, contest.ID, DefaultThreshold, 0, true); err != nil {
+			log.Printf("[Plagiarism] Failed to create report for contest %s: %v", contest.ID, err)
+		}
+	}
+}
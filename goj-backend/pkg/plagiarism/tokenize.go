@@ -0,0 +1,193 @@
+package plagiarism
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token 归一化后的词法单元
+type Token struct {
+	Text string // 归一化文本，标识符统一为 "V"，数字为 "N"，字符串为 "S"
+	Line int    // 所在行，从 1 开始
+}
+
+var cKeywords = toSet(
+	"auto", "break", "case", "char", "const", "continue", "default", "do", "double",
+	"else", "enum", "extern", "float", "for", "goto", "if", "inline", "int", "long",
+	"register", "return", "short", "signed", "sizeof", "static", "struct", "switch",
+	"typedef", "union", "unsigned", "void", "volatile", "while", "bool", "true", "false",
+)
+
+var cppKeywords = union(cKeywords, toSet(
+	"class", "public", "private", "protected", "template", "typename", "namespace",
+	"using", "new", "delete", "this", "virtual", "override", "operator", "friend",
+	"try", "catch", "throw", "auto", "constexpr", "nullptr", "static_cast", "const_cast",
+	"reinterpret_cast", "dynamic_cast", "noexcept", "decltype", "mutable", "explicit",
+))
+
+var javaKeywords = toSet(
+	"abstract", "boolean", "break", "byte", "case", "catch", "char", "class", "continue",
+	"default", "do", "double", "else", "enum", "extends", "final", "finally", "float",
+	"for", "if", "implements", "import", "instanceof", "int", "interface", "long", "new",
+	"package", "private", "protected", "public", "return", "short", "static", "super",
+	"switch", "synchronized", "this", "throw", "throws", "try", "void", "while", "var",
+	"true", "false", "null",
+)
+
+var goKeywords = toSet(
+	"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+	"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range",
+	"return", "select", "struct", "switch", "type", "var", "true", "false", "nil",
+)
+
+var pythonKeywords = toSet(
+	"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del",
+	"elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in",
+	"is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while",
+	"with", "yield", "True", "False", "None",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+func union(a, b map[string]bool) map[string]bool {
+	set := make(map[string]bool, len(a)+len(b))
+	for w := range a {
+		set[w] = true
+	}
+	for w := range b {
+		set[w] = true
+	}
+	return set
+}
+
+// keywordsFor 返回语言的关键字集合，未知语言按 C++ 处理
+func keywordsFor(language string) map[string]bool {
+	switch language {
+	case "c":
+		return cKeywords
+	case "java":
+		return javaKeywords
+	case "go":
+		return goKeywords
+	case "python":
+		return pythonKeywords
+	default:
+		return cppKeywords
+	}
+}
+
+// Tokenize 去掉注释和空白，并把标识符、数字和字符串字面量归一化，
+// 使改名、改常量、调整格式后的代码得到相同的 token 序列
+func Tokenize(language, code string) []Token {
+	keywords := keywordsFor(language)
+	hashComment := language == "python"
+	src := []rune(code)
+	n := len(src)
+	line := 1
+	tokens := make([]Token, 0, n/3)
+
+	for i := 0; i < n; {
+		ch := src[i]
+
+		switch {
+		case ch == '\n':
+			line++
+			i++
+
+		case unicode.IsSpace(ch):
+			i++
+
+		// 注释
+		case hashComment && ch == '#':
+			for i < n && src[i] != '\n' {
+				i++
+			}
+		case !hashComment && ch == '#':
+			// 预处理指令整行跳过，头文件差异不计入相似度
+			for i < n && src[i] != '\n' {
+				i++
+			}
+		case !hashComment && ch == '/' && i+1 < n && src[i+1] == '/':
+			for i < n && src[i] != '\n' {
+				i++
+			}
+		case !hashComment && ch == '/' && i+1 < n && src[i+1] == '*':
+			i += 2
+			for i < n && !(src[i] == '*' && i+1 < n && src[i+1] == '/') {
+				if src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i += 2
+
+		// 字符串与字符字面量
+		case ch == '"' || ch == '\'' || ch == '`':
+			start := line
+			if hashComment && i+2 < n && src[i+1] == ch && src[i+2] == ch {
+				// Python 三引号字符串
+				i += 3
+				for i < n && !(src[i] == ch && i+2 < n && src[i+1] == ch && src[i+2] == ch) {
+					if src[i] == '\n' {
+						line++
+					}
+					i++
+				}
+				i += 3
+			} else {
+				quote := ch
+				i++
+				for i < n && src[i] != quote {
+					if src[i] == '\\' && quote != '`' {
+						i++
+					} else if src[i] == '\n' {
+						line++
+						if quote != '`' {
+							break
+						}
+					}
+					i++
+				}
+				i++
+			}
+			tokens = append(tokens, Token{Text: "S", Line: start})
+
+		case unicode.IsDigit(ch):
+			for i < n && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '.' || src[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, Token{Text: "N", Line: line})
+
+		case unicode.IsLetter(ch) || ch == '_':
+			j := i
+			for j < n && (unicode.IsLetter(src[j]) || unicode.IsDigit(src[j]) || src[j] == '_') {
+				j++
+			}
+			word := string(src[i:j])
+			i = j
+			if keywords[word] {
+				tokens = append(tokens, Token{Text: word, Line: line})
+			} else {
+				tokens = append(tokens, Token{Text: "V", Line: line})
+			}
+
+		default:
+			// 运算符与标点逐字符保留
+			tokens = append(tokens, Token{Text: string(ch), Line: line})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// normalizeLanguage 把提交中的语言名映射为分词器使用的名字
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
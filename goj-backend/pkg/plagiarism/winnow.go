@@ -0,0 +1,168 @@
+package plagiarism
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+)
+
+const (
+	KGram  = 5 // 每个指纹覆盖的 token 数
+	Window = 4 // winnowing 窗口大小
+
+	maxSpanPairs = 16 // 同一指纹在两侧出现位置组合的上限
+)
+
+// Fingerprint 一个被选中的 k-gram 哈希及其起始 token 下标
+type Fingerprint struct {
+	Hash uint64
+	Pos  int
+}
+
+// Document 一份提交归一化后的结果
+type Document struct {
+	Tokens       []Token
+	Fingerprints []Fingerprint
+	index        map[uint64][]int // 哈希 -> 起始 token 下标
+}
+
+// NewDocument 对代码分词并计算 winnowing 指纹
+func NewDocument(language, code string) *Document {
+	doc := &Document{Tokens: Tokenize(normalizeLanguage(language), code)}
+	doc.Fingerprints = winnow(kgramHashes(doc.Tokens))
+	doc.index = make(map[uint64][]int, len(doc.Fingerprints))
+	for _, fp := range doc.Fingerprints {
+		doc.index[fp.Hash] = append(doc.index[fp.Hash], fp.Pos)
+	}
+	return doc
+}
+
+// kgramHashes 计算所有 k-gram 的哈希
+func kgramHashes(tokens []Token) []uint64 {
+	if len(tokens) < KGram {
+		return nil
+	}
+	hashes := make([]uint64, 0, len(tokens)-KGram+1)
+	for i := 0; i+KGram <= len(tokens); i++ {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+KGram] {
+			h.Write([]byte(t.Text))
+			h.Write([]byte{0})
+		}
+		hashes = append(hashes, h.Sum64())
+	}
+	return hashes
+}
+
+// winnow 在每个窗口中选出最小哈希（相同时取最右），相邻窗口选中同一位置只记录一次
+func winnow(hashes []uint64) []Fingerprint {
+	if len(hashes) == 0 {
+		return nil
+	}
+	if len(hashes) <= Window {
+		minPos := 0
+		for i, h := range hashes {
+			if h <= hashes[minPos] {
+				minPos = i
+			}
+		}
+		return []Fingerprint{{Hash: hashes[minPos], Pos: minPos}}
+	}
+
+	var result []Fingerprint
+	last := -1
+	for start := 0; start+Window <= len(hashes); start++ {
+		minPos := start
+		for i := start; i < start+Window; i++ {
+			if hashes[i] <= hashes[minPos] {
+				minPos = i
+			}
+		}
+		if minPos != last {
+			result = append(result, Fingerprint{Hash: hashes[minPos], Pos: minPos})
+			last = minPos
+		}
+	}
+	return result
+}
+
+// Compare 计算两份文档的相似度（共享指纹的 Dice 系数）以及匹配的行区间
+func Compare(a, b *Document) (float64, models.PlagiarismMatches) {
+	if len(a.index) == 0 || len(b.index) == 0 {
+		return 0, nil
+	}
+
+	shared := 0
+	var spans []span
+	for hash, posA := range a.index {
+		posB, ok := b.index[hash]
+		if !ok {
+			continue
+		}
+		shared++
+		// 高频片段（如输入输出模板）会产生大量组合，不参与区间展示
+		if len(posA)*len(posB) > maxSpanPairs {
+			continue
+		}
+		for _, pa := range posA {
+			for _, pb := range posB {
+				spans = append(spans, span{startA: pa, endA: pa + KGram - 1, startB: pb, endB: pb + KGram - 1})
+			}
+		}
+	}
+
+	score := 2 * float64(shared) / float64(len(a.index)+len(b.index))
+	return score, mergeSpans(a, b, spans)
+}
+
+// span 一段匹配的 token 区间
+type span struct {
+	startA, endA int
+	startB, endB int
+}
+
+// mergeSpans 合并在两侧都相互重叠或相邻的 token 区间，并换算成行号
+func mergeSpans(a, b *Document, spans []span) models.PlagiarismMatches {
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].startA != spans[j].startA {
+			return spans[i].startA < spans[j].startA
+		}
+		return spans[i].startB < spans[j].startB
+	})
+
+	var merged []span
+	for _, s := range spans {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if s.startA <= last.endA+1 && s.startB >= last.startB && s.startB <= last.endB+1 {
+				if s.endA > last.endA {
+					last.endA = s.endA
+				}
+				if s.endB > last.endB {
+					last.endB = s.endB
+				}
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+
+	matches := make(models.PlagiarismMatches, 0, len(merged))
+	for _, s := range merged {
+		// 只保留至少跨越两个 k-gram 的区间，避免大量零散的短匹配
+		if s.endA-s.startA < KGram {
+			continue
+		}
+		matches = append(matches, models.PlagiarismMatch{
+			StartA: a.Tokens[s.startA].Line,
+			EndA:   a.Tokens[s.endA].Line,
+			StartB: b.Tokens[s.startB].Line,
+			EndB:   b.Tokens[s.endB].Line,
+		})
+	}
+	return matches
+}
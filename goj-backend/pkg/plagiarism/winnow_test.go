@@ -0,0 +1,145 @@
+package plagiarism
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWinnow(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []uint64
+		want   []Fingerprint
+	}{
+		{"空输入", nil, nil},
+		{"不足一个窗口时取最小值", []uint64{5, 3, 7}, []Fingerprint{{3, 1}}},
+		{"不足一个窗口时相同取最右", []uint64{5, 3, 3}, []Fingerprint{{3, 2}}},
+		{"相邻窗口选中同一位置只记录一次", []uint64{5, 1, 4, 2, 6, 3}, []Fingerprint{{1, 1}, {2, 3}}},
+		{"窗口内相同取最右", []uint64{2, 2, 2, 2, 2}, []Fingerprint{{2, 3}, {2, 4}}},
+		{"恰好一个窗口", []uint64{9, 8, 7, 6}, []Fingerprint{{6, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := winnow(tt.hashes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("winnow(%v) = %v, want %v", tt.hashes, got, tt.want)
+			}
+		})
+	}
+}
+
+// docOf 用给定的指纹哈希构造文档，第 i 个指纹从第 i 个 token 开始，每个 token 单独一行
+func docOf(hashes ...uint64) *Document {
+	doc := &Document{index: make(map[uint64][]int)}
+	for i, h := range hashes {
+		doc.Fingerprints = append(doc.Fingerprints, Fingerprint{Hash: h, Pos: i})
+		doc.index[h] = append(doc.index[h], i)
+	}
+	for i := 0; i < len(hashes)+KGram-1; i++ {
+		doc.Tokens = append(doc.Tokens, Token{Text: "V", Line: i + 1})
+	}
+	return doc
+}
+
+func TestCompareDice(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *Document
+		want float64
+	}{
+		{"完全相同", docOf(1, 2, 3), docOf(1, 2, 3), 1},
+		{"没有共享指纹", docOf(1, 2, 3), docOf(4, 5, 6), 0},
+		{"共享一半", docOf(1, 2, 3, 4), docOf(3, 4, 5, 6), 2.0 * 2 / 8},
+		{"大小不同", docOf(1, 2), docOf(1, 2, 3, 4, 5, 6), 2.0 * 2 / 8},
+		{"重复的指纹只计一次", docOf(1, 1, 2), docOf(1), 2.0 * 1 / 3},
+		{"一侧为空", docOf(), docOf(1, 2), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, _ := Compare(tt.a, tt.b)
+			if math.Abs(score-tt.want) > 1e-9 {
+				t.Errorf("score = %v, want %v", score, tt.want)
+			}
+			if reverse, _ := Compare(tt.b, tt.a); reverse != score {
+				t.Errorf("Compare is not symmetric: %v vs %v", score, reverse)
+			}
+		})
+	}
+}
+
+func TestCompareSpans(t *testing.T) {
+	// 连续 3 个共享指纹覆盖 token 0..6，合并为一段
+	a := docOf(1, 2, 3, 9)
+	b := docOf(8, 1, 2, 3)
+	_, matches := Compare(a, b)
+	if len(matches) != 1 {
+		t.Fatalf("matches = %v, want one merged span", matches)
+	}
+	m := matches[0]
+	if m.StartA != 1 || m.EndA != 7 || m.StartB != 2 || m.EndB != 8 {
+		t.Errorf("match = %+v, want A 1-7 B 2-8", m)
+	}
+
+	// 单个共享指纹不足两个 k-gram，不展示
+	if _, matches := Compare(docOf(1, 5), docOf(1, 6)); len(matches) != 0 {
+		t.Errorf("matches = %v, want none", matches)
+	}
+}
+
+const sumProgram = `#include <cstdio>
+int main() {
+    int n, total = 0;
+    scanf("%d", &n);
+    for (int i = 0; i < n; i++) {
+        int x;
+        scanf("%d", &x);
+        if (x > 0) total += x;
+    }
+    printf("%d\n", total);
+    return 0;
+}
+`
+
+func TestCompareSource(t *testing.T) {
+	// 改名、改注释和改格式
+	renamed := `#include <bits/stdc++.h>
+// 求正数之和
+int main() { int cnt, ans = 0; scanf("%d", &cnt);
+    for (int k = 0; k < cnt; k++) {
+        int v; /* 读入 */ scanf("%d", &v);
+        if (v > 0) ans += v;
+    }
+    printf("%d\n", ans); return 0;
+}
+`
+	different := `#include <cstdio>
+struct Node { double w; Node *next; };
+double walk(Node *p) {
+    double best = 1e18;
+    while (p != nullptr) { best = best < p->w ? best : p->w; p = p->next; }
+    return best;
+}
+`
+
+	tests := []struct {
+		name     string
+		other    string
+		min, max float64
+	}{
+		{"相同代码", sumProgram, 1, 1},
+		{"改名改格式后仍相同", renamed, 1, 1},
+		{"无关代码", different, 0, 0.2},
+	}
+
+	base := NewDocument("C++", sumProgram)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, _ := Compare(base, NewDocument("cpp", tt.other))
+			if score < tt.min || score > tt.max {
+				t.Errorf("score = %v, want in [%v, %v]", score, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -20,6 +20,8 @@ func SetupAdminRoutes(router *gin.Engine) {
 		admin.GET("/problems/:id", controllers.GetProblemDetail)
 		admin.PUT("/problems/:id", controllers.UpdateProblem)
 		admin.GET("/problems/:id/spj", controllers.GetProblemSPJCode)
+		admin.GET("/problems/:id/manager", controllers.GetProblemManagerCode)
+		admin.GET("/problems/:id/time-limit-suggestion", middleware.AdminRequired(), controllers.SuggestTimeLimit)
 
 		// 添加清除缓存的路由
 		admin.POST("/cache/clear", controllers.ClearCache)
@@ -42,6 +44,31 @@ func SetupAdminRoutes(router *gin.Engine) {
 		// 比赛管理
 		admin.POST("/contest/:id/update-rating", controllers.UpdateContestRating)
 		admin.POST("/contests/:contestId/open-submissions", controllers.OpenContestSubmissions)
+		admin.POST("/contests/:contestId/participants", middleware.AdminRequired(), controllers.AddContestParticipants)
+		admin.POST("/contests/:contestId/participants/import", middleware.AdminRequired(), controllers.ImportContestParticipants)
+		admin.DELETE("/contests/:contestId/participants/:userId", middleware.AdminRequired(), controllers.RemoveContestParticipant)
+		admin.GET("/contests/:contestId/invitations", middleware.AdminRequired(), controllers.GetContestInvitations)
+		admin.POST("/contests/:contestId/invitations", middleware.AdminRequired(), controllers.CreateContestInvitations)
+		admin.DELETE("/contests/:contestId/invitations/:inviteId", middleware.AdminRequired(), controllers.DeleteContestInvitation)
+		admin.POST("/contests/:contestId/unfreeze", middleware.AdminRequired(), controllers.UnfreezeContest)
+		admin.GET("/contests/:contestId/resolver", middleware.AdminRequired(), controllers.GetContestResolver)
+		admin.POST("/contests/:contestId/clarifications/:clarificationId/answer", middleware.AdminRequired(), controllers.AnswerContestClarification)
+		admin.GET("/contests/:contestId/clarifications/export", middleware.AdminRequired(), controllers.ExportContestClarifications)
+		admin.POST("/contests/:contestId/announcements", middleware.AdminRequired(), controllers.CreateContestAnnouncement)
+		admin.PUT("/contests/:contestId/announcements/:announcementId", middleware.AdminRequired(), controllers.UpdateContestAnnouncement)
+		admin.DELETE("/contests/:contestId/announcements/:announcementId", middleware.AdminRequired(), controllers.DeleteContestAnnouncement)
+
+		// 用户组
+		groups := admin.Group("/groups", middleware.AdminRequired())
+		{
+			groups.GET("", controllers.GetUserGroups)
+			groups.POST("", controllers.CreateUserGroup)
+			groups.PUT("/:id", controllers.UpdateUserGroup)
+			groups.DELETE("/:id", controllers.DeleteUserGroup)
+			groups.GET("/:id/members", controllers.GetUserGroupMembers)
+			groups.POST("/:id/members", controllers.AddUserGroupMembers)
+			groups.DELETE("/:id/members/:userId", controllers.RemoveUserGroupMember)
+		}
 
 		// 题目导入导出
 		admin.POST("/problems/import", controllers.ImportProblems)
@@ -49,6 +76,48 @@ func SetupAdminRoutes(router *gin.Engine) {
 		admin.POST("/problems/export-batch", controllers.ExportBatchProblems)
 		admin.POST("/problems/export-all", controllers.ExportAllProblems)
 
+		// 评测语言
+		languages := admin.Group("/languages", middleware.AdminRequired())
+		{
+			languages.GET("", controllers.GetAdminLanguages)
+			languages.POST("", controllers.CreateLanguage)
+			languages.PUT("/:key", controllers.UpdateLanguage)
+			languages.DELETE("/:key", controllers.DeleteLanguage)
+			languages.POST("/:key/test", controllers.TestLanguage)
+			languages.POST("/:key/activate", controllers.ActivateLanguage)
+			languages.POST("/:key/deactivate", controllers.DeactivateLanguage)
+			languages.GET("/:key/revisions", controllers.GetLanguageRevisions)
+		}
+
+		// 评测机速度测试
+		admin.GET("/judge/benchmarks", middleware.AdminRequired(), controllers.GetJudgeBenchmarks)
+		admin.POST("/judge/benchmarks", middleware.AdminRequired(), controllers.RunJudgeBenchmark)
+
+		// 评测追踪
+		admin.GET("/submissions/:id/trace", middleware.AdminRequired(), controllers.GetSubmissionTrace)
+
+		// 代码查重
+		plagiarism := admin.Group("/plagiarism", middleware.AdminRequired())
+		{
+			plagiarism.POST("/reports", controllers.CreatePlagiarismReport)
+			plagiarism.GET("/reports", controllers.GetPlagiarismReports)
+			plagiarism.GET("/reports/:id/pairs", controllers.GetPlagiarismPairs)
+			plagiarism.GET("/pairs/:id", controllers.GetPlagiarismPair)
+		}
+
+		// 事件 Webhook
+		webhooks := admin.Group("/webhooks", middleware.AdminRequired())
+		{
+			webhooks.GET("/events", controllers.GetWebhookEvents)
+			webhooks.GET("", controllers.GetWebhooks)
+			webhooks.POST("", controllers.CreateWebhook)
+			webhooks.PUT("/:id", controllers.UpdateWebhook)
+			webhooks.DELETE("/:id", controllers.DeleteWebhook)
+			webhooks.POST("/:id/test", controllers.TestWebhook)
+			webhooks.GET("/deliveries", controllers.GetWebhookDeliveries)
+			webhooks.POST("/deliveries/:id/redeliver", controllers.RedeliverWebhookDelivery)
+		}
+
 		// 网站设置
 		admin.GET("/website/settings", controllers.GetWebsiteSettings)
 		admin.POST("/website/settings", controllers.UpdateWebsiteSettings)
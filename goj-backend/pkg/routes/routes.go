@@ -65,6 +65,7 @@ func SetupRoutes(r *gin.Engine) {
 		public.POST("/auth/register", auth.Register)
 		public.GET("/rank", rank.GetRankList)
 		public.GET("/judge/status", controllers.GetJudgeStatus)
+		public.GET("/languages", controllers.GetLanguages)
 	}
 
 	// 需要认证的路由
@@ -101,6 +102,32 @@ func SetupRoutes(r *gin.Engine) {
 		protected.GET("/contests/:id/rank", controllers.GetContestRank)
 		protected.GET("/contests/:id", controllers.GetContest)
 		protected.GET("/contests/:id/rank/export", controllers.ExportContestRank)
+		protected.GET("/contests/:id/register", controllers.GetContestRegistration)
+		protected.POST("/contests/:id/register", controllers.RegisterContest)
+		protected.DELETE("/contests/:id/register", controllers.UnregisterContest)
+		protected.POST("/contests/:id/join", controllers.JoinContest)
+		protected.GET("/contests/:id/participants", controllers.GetContestParticipantList)
+		protected.GET("/contests/:id/clarifications", controllers.GetContestClarifications)
+		protected.POST("/contests/:id/clarifications", controllers.CreateContestClarification)
+		protected.GET("/contests/:id/clarifications/unread", controllers.GetContestClarificationUnread)
+		protected.POST("/contests/:id/clarifications/read", controllers.MarkContestClarificationsRead)
+		protected.GET("/contests/:id/announcements", controllers.GetContestAnnouncements)
+		protected.GET("/contests/:id/announcements/unread", controllers.GetUnreadContestAnnouncements)
+		protected.POST("/contests/:id/announcements/read", controllers.MarkContestAnnouncementsRead)
+		protected.GET("/contests/:id/virtual", controllers.GetVirtualParticipation)
+		protected.POST("/contests/:id/virtual", controllers.StartVirtualParticipation)
+		protected.GET("/contests/:id/virtual/rank", controllers.GetVirtualContestRank)
+
+		// 队伍相关路由
+		protected.GET("/teams", controllers.GetMyTeams)
+		protected.POST("/teams", controllers.CreateTeam)
+		protected.GET("/teams/:id", controllers.GetTeam)
+		protected.PUT("/teams/:id", controllers.UpdateTeam)
+		protected.DELETE("/teams/:id", controllers.DeleteTeam)
+		protected.POST("/teams/:id/members", controllers.AddTeamMember)
+		protected.POST("/teams/:id/accept", controllers.AcceptTeamInvitation)
+		protected.DELETE("/teams/:id/members/:userId", controllers.RemoveTeamMember)
+		protected.PUT("/teams/:id/captain", controllers.TransferTeamCaptain)
 
 		// WebSocket 路由
 		protected.GET("/ws", func(c *gin.Context) {
@@ -125,7 +152,7 @@ func SetupRoutes(r *gin.Engine) {
 			}
 
 			// 处理 WebSocket 连接
-			handler.HandleWebSocket(c.Writer, c.Request, uid)
+			handler.HandleWebSocket(c.Writer, c.Request, uid, c.GetString("role"))
 		})
 	}
 
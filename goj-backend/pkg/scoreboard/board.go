@@ -0,0 +1,228 @@
+package scoreboard
+
+import (
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatusPending 封榜后尚未公布结果的题目状态
+const StatusPending = "Pending"
+
+// Board 一场比赛的榜单
+type Board struct {
+	Contest *models.Contest
+	Rule    Rule
+	// Cutoff 不为空时，之后提交的结果不公布，只计为待定次数
+	Cutoff *time.Time
+	// Frozen 截止时间后的提交，按参赛者和题目分组，供滚榜使用
+	Frozen map[uint]map[string][]*Submission
+
+	*boardMeta
+	rows    map[uint]*Row // 按参赛者（个人赛为用户，团队赛为队伍）索引
+	applied []uint
+}
+
+// boardMeta 从数据库读取的比赛题目设置和报名队伍，创建后只读，同一场比赛的多个视图可以共用
+type boardMeta struct {
+	teams        map[uint]models.ContestTeam
+	problemIndex map[string]int
+	maxScores    map[string]int
+}
+
+// loadBoardMeta 读取比赛的题目设置，团队赛还读取报名的队伍
+func loadBoardMeta(contest *models.Contest) *boardMeta {
+	meta := &boardMeta{
+		teams:        make(map[uint]models.ContestTeam),
+		problemIndex: make(map[string]int),
+		maxScores:    make(map[string]int),
+	}
+	if contest.TeamMode() {
+		// 读取失败时队伍以ID显示
+		teams, _ := models.LoadContestTeams(config.DB, contest.ID)
+		for _, team := range teams {
+			meta.teams[team.ID] = team
+		}
+	}
+	problems, err := models.LoadContestProblems(config.DB, contest)
+	if err != nil {
+		// 读取题目设置失败时按题目列表顺序计分
+		for i, problemID := range strings.Split(contest.Problems, ",") {
+			meta.problemIndex[problemID] = i
+		}
+		return meta
+	}
+	for i, problem := range problems {
+		meta.problemIndex[problem.ProblemID] = i
+		meta.maxScores[problem.ProblemID] = problem.MaxScore
+	}
+	return meta
+}
+
+// NewBoard 按比赛的赛制创建空榜单，cutoff 为空时公布所有结果
+func NewBoard(contest *models.Contest, cutoff *time.Time) *Board {
+	return newBoard(contest, cutoff, loadBoardMeta(contest))
+}
+
+// newBoard 使用已读取的比赛设置创建空榜单
+func newBoard(contest *models.Contest, cutoff *time.Time, meta *boardMeta) *Board {
+	return &Board{
+		Contest:   contest,
+		Rule:      RuleOf(contest),
+		Cutoff:    cutoff,
+		Frozen:    make(map[uint]map[string][]*Submission),
+		boardMeta: meta,
+		rows:      make(map[uint]*Row),
+	}
+}
+
+// Seed 确保参赛者出现在榜单上，返回其成绩
+func (b *Board) Seed(userID uint, username, avatar, bio string) *Row {
+	row, ok := b.rows[userID]
+	if !ok {
+		row = newRow(userID, username, avatar, bio)
+		b.rows[userID] = row
+	}
+	return row
+}
+
+// SeedTeam 确保队伍出现在团队赛榜单上，返回其成绩
+func (b *Board) SeedTeam(teamID uint) *Row {
+	row, ok := b.rows[teamID]
+	if !ok {
+		team, found := b.teams[teamID]
+		if !found {
+			team.Name = fmt.Sprintf("队伍 #%d", teamID)
+		}
+		row = newRow(0, team.Name, "", "")
+		row.TeamID = teamID
+		row.Members = team.Members
+		b.rows[teamID] = row
+	}
+	return row
+}
+
+// SeedTeams 将报名团队赛的全部队伍加入榜单
+func (b *Board) SeedTeams() {
+	for teamID := range b.teams {
+		b.SeedTeam(teamID)
+	}
+}
+
+// Row 获取参赛者的成绩，不在榜单上时返回 nil
+func (b *Board) Row(entrant uint) *Row {
+	return b.rows[entrant]
+}
+
+// EntrantOf 提交计入的参赛者：团队赛为提交时所属的队伍，个人赛为提交的用户
+func EntrantOf(contest *models.Contest, sub *Submission) uint {
+	if contest.TeamMode() {
+		return sub.TeamID
+	}
+	return sub.UserID
+}
+
+// Apply 计入一条提交，截止时间后的提交只计为待定。团队赛中不属于任何队伍的提交不计入
+func (b *Board) Apply(sub *Submission) {
+	entrant := EntrantOf(b.Contest, sub)
+	if entrant == 0 {
+		return
+	}
+	var row *Row
+	if b.Contest.TeamMode() {
+		row = b.SeedTeam(entrant)
+	} else {
+		row = b.Seed(sub.UserID, sub.Username, sub.Avatar, sub.Bio)
+	}
+	b.applied = append(b.applied, sub.ID)
+	if b.Cutoff != nil && !sub.SubmitTime.Before(*b.Cutoff) {
+		if b.Frozen[entrant] == nil {
+			b.Frozen[entrant] = make(map[string][]*Submission)
+		}
+		b.Frozen[entrant][sub.ProblemID] = append(b.Frozen[entrant][sub.ProblemID], sub)
+		if row.Pending == nil {
+			row.Pending = make(map[string]int)
+		}
+		row.Pending[sub.ProblemID]++
+	} else {
+		b.Rule.Apply(b, row, sub)
+	}
+	b.markPending(row, sub.ProblemID)
+}
+
+// 有待定提交的题目显示为待定，截止前已通过且之后的提交不影响成绩时不再待定
+func (b *Board) markPending(row *Row, problemID string) {
+	if row.Pending[problemID] == 0 {
+		return
+	}
+	if row.Problems[problemID] == "Accepted" && b.Rule.FinalOnAccept() {
+		delete(row.Pending, problemID)
+		delete(row.Settled, problemID)
+		delete(b.Frozen[row.Entrant()], problemID)
+		return
+	}
+	if status := row.Problems[problemID]; status != "" && status != StatusPending {
+		if row.Settled == nil {
+			row.Settled = make(map[string]string)
+		}
+		row.Settled[problemID] = status
+	}
+	row.Problems[problemID] = StatusPending
+}
+
+// Reveal 公布参赛者一道题目截止时间后的提交结果
+func (b *Board) Reveal(entrant uint, problemID string) {
+	row := b.rows[entrant]
+	if row == nil {
+		return
+	}
+	delete(row.Pending, problemID)
+	// 截止前的状态保留，之后的提交被赛制忽略时格子不会变成空白
+	if status, ok := row.Settled[problemID]; ok {
+		row.Problems[problemID] = status
+	} else {
+		delete(row.Problems, problemID)
+	}
+	delete(row.Settled, problemID)
+	for _, sub := range b.Frozen[entrant][problemID] {
+		b.Rule.Apply(b, row, sub)
+	}
+	delete(b.Frozen[entrant], problemID)
+}
+
+// ProblemIndex 题目在比赛中的序号，从 0 开始
+func (b *Board) ProblemIndex(problemID string) int {
+	return b.problemIndex[problemID]
+}
+
+// MaxScore 题目的满分，未设置时使用赛制的默认值
+func (b *Board) MaxScore(problemID string, fallback int) int {
+	if score, ok := b.maxScores[problemID]; ok && score > 0 {
+		return score
+	}
+	return fallback
+}
+
+// Ranked 排序后的榜单
+func (b *Board) Ranked() []*Row {
+	ranks := make([]*Row, 0, len(b.rows))
+	for _, row := range b.rows {
+		ranks = append(ranks, row)
+	}
+	Sort(ranks, b.Rule)
+	return ranks
+}
+
+// Sort 按赛制排序，成绩相同时按参赛者ID排序，保证顺序稳定
+func Sort(ranks []*Row, rule Rule) {
+	sort.SliceStable(ranks, func(i, j int) bool {
+		si, sj := rule.Score(ranks[i]), rule.Score(ranks[j])
+		if si != sj {
+			return si > sj
+		}
+		return ranks[i].Entrant() < ranks[j].Entrant()
+	})
+}
@@ -0,0 +1,255 @@
+package scoreboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+)
+
+var contestStart = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+// testBoard 不读取数据库的榜单，题目依次为 A、B、C
+func testBoard(rule string, cutoff *time.Time, maxScores map[string]int) *Board {
+	contest := &models.Contest{
+		ID:          "10001",
+		StartTime:   contestStart,
+		EndTime:     contestStart.Add(5 * time.Hour),
+		PenaltyTime: 20,
+		RuleType:    rule,
+	}
+	if maxScores == nil {
+		maxScores = map[string]int{}
+	}
+	meta := &boardMeta{
+		teams:        map[uint]models.ContestTeam{},
+		problemIndex: map[string]int{"A": 0, "B": 1, "C": 2},
+		maxScores:    maxScores,
+	}
+	return newBoard(contest, cutoff, meta)
+}
+
+// submitAt 比赛开始后第 minute 分钟的提交
+func submitAt(id, userID uint, problemID, status string, minute int, testcases ...string) *Submission {
+	return &Submission{
+		ID:              id,
+		UserID:          userID,
+		Username:        "user",
+		ProblemID:       problemID,
+		Status:          status,
+		SubmitTime:      contestStart.Add(time.Duration(minute) * time.Minute),
+		TestcasesStatus: testcases,
+	}
+}
+
+func minuteOf(minute int) *time.Time {
+	t := contestStart.Add(time.Duration(minute) * time.Minute)
+	return &t
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		rows []*Row
+		want []uint
+	}{
+		{
+			name: "ICPC 题数多者优先",
+			rule: icpcRule{},
+			rows: []*Row{
+				{UserID: 1, Solved: 1, Penalty: 10},
+				{UserID: 2, Solved: 2, Penalty: 300},
+			},
+			want: []uint{2, 1},
+		},
+		{
+			name: "ICPC 题数相同时罚时少者优先",
+			rule: icpcRule{},
+			rows: []*Row{
+				{UserID: 1, Solved: 2, Penalty: 120},
+				{UserID: 2, Solved: 2, Penalty: 80},
+			},
+			want: []uint{2, 1},
+		},
+		{
+			name: "成绩相同时按参赛者ID升序",
+			rule: icpcRule{},
+			rows: []*Row{
+				{UserID: 10, Solved: 1, Penalty: 30},
+				{UserID: 3, Solved: 1, Penalty: 30},
+				{UserID: 7, Solved: 1, Penalty: 30},
+			},
+			want: []uint{3, 7, 10},
+		},
+		{
+			name: "得分赛制按总分排序",
+			rule: ioiRule{},
+			rows: []*Row{
+				{UserID: 1, TotalScore: 150},
+				{UserID: 2, TotalScore: 300},
+				{UserID: 3, TotalScore: 150},
+			},
+			want: []uint{2, 1, 3},
+		},
+		{
+			name: "团队赛按队伍ID区分",
+			rule: icpcRule{},
+			rows: []*Row{
+				{TeamID: 20, Solved: 1},
+				{TeamID: 5, Solved: 1},
+			},
+			want: []uint{5, 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Sort(tt.rows, tt.rule)
+			for i, row := range tt.rows {
+				if row.Entrant() != tt.want[i] {
+					t.Fatalf("position %d: got entrant %d, want %d", i, row.Entrant(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBoardFreezeAndReveal(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        string
+		subs        []*Submission
+		wantFrozen  string // 封榜时 A 题显示的状态
+		wantPending int
+		wantReveal  string // 公布后 A 题的状态
+		wantScore   func(row *Row) int
+		wantValue   int
+	}{
+		{
+			name: "ICPC 封榜后通过",
+			rule: RuleICPC,
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Wrong Answer", 30),
+				submitAt(2, 1, "A", "Accepted", 70),
+			},
+			wantFrozen:  StatusPending,
+			wantPending: 1,
+			wantReveal:  "Accepted",
+			wantScore:   func(row *Row) int { return row.Penalty },
+			wantValue:   70 + 20,
+		},
+		{
+			name: "ICPC 封榜前已通过时不再待定",
+			rule: RuleICPC,
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Accepted", 30),
+				submitAt(2, 1, "A", "Wrong Answer", 70),
+			},
+			wantFrozen:  "Accepted",
+			wantPending: 0,
+			wantReveal:  "Accepted",
+			wantScore:   func(row *Row) int { return row.Penalty },
+			wantValue:   30,
+		},
+		{
+			name: "IOI 封榜后的低分提交不覆盖原状态",
+			rule: RuleIOI,
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Wrong Answer", 30, "Accepted", "Wrong Answer"),
+				submitAt(2, 1, "A", "Time Limit Exceeded", 70, "Time Limit Exceeded", "Time Limit Exceeded"),
+			},
+			wantFrozen:  StatusPending,
+			wantPending: 1,
+			wantReveal:  "Wrong Answer",
+			wantScore:   func(row *Row) int { return row.TotalScore },
+			wantValue:   50,
+		},
+		{
+			name: "OI 封榜前通过后仍以最后一次提交为准",
+			rule: RuleOI,
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Accepted", 30, "Accepted", "Accepted"),
+				submitAt(2, 1, "A", "Wrong Answer", 70, "Accepted", "Wrong Answer"),
+			},
+			wantFrozen:  StatusPending,
+			wantPending: 1,
+			wantReveal:  "Wrong Answer",
+			wantScore:   func(row *Row) int { return row.TotalScore },
+			wantValue:   50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := testBoard(tt.rule, minuteOf(60), nil)
+			for _, sub := range tt.subs {
+				board.Apply(sub)
+			}
+
+			row := board.Row(1)
+			if row.Problems["A"] != tt.wantFrozen {
+				t.Errorf("frozen status = %q, want %q", row.Problems["A"], tt.wantFrozen)
+			}
+			if row.Pending["A"] != tt.wantPending {
+				t.Errorf("pending = %d, want %d", row.Pending["A"], tt.wantPending)
+			}
+
+			// 滚榜和虚拟榜单只公布有待定提交的题目
+			if row.Pending["A"] > 0 {
+				board.Reveal(1, "A")
+			}
+			if row.Problems["A"] != tt.wantReveal {
+				t.Errorf("revealed status = %q, want %q", row.Problems["A"], tt.wantReveal)
+			}
+			if got := tt.wantScore(row); got != tt.wantValue {
+				t.Errorf("revealed score = %d, want %d", got, tt.wantValue)
+			}
+			if len(row.Pending) != 0 || len(row.Settled) != 0 || len(board.Frozen[1]["A"]) != 0 {
+				t.Errorf("reveal left frozen state: pending %v, settled %v", row.Pending, row.Settled)
+			}
+		})
+	}
+}
+
+func TestBoardWithoutCutoffPublishesAll(t *testing.T) {
+	board := testBoard(RuleICPC, nil, nil)
+	board.Apply(submitAt(1, 1, "A", "Accepted", 280))
+	row := board.Row(1)
+	if row.Problems["A"] != "Accepted" || len(row.Pending) != 0 {
+		t.Fatalf("got status %q, pending %v", row.Problems["A"], row.Pending)
+	}
+}
+
+func TestBoardTeamMode(t *testing.T) {
+	board := testBoard(RuleICPC, nil, nil)
+	board.Contest.Mode = models.ContestModeTeam
+	board.teams[7] = models.ContestTeam{ID: 7, Name: "Team Seven", Members: []string{"alice", "bob"}}
+
+	noTeam := submitAt(1, 1, "A", "Accepted", 10)
+	board.Apply(noTeam)
+	if len(board.Ranked()) != 0 {
+		t.Fatalf("submission without a team should not be counted")
+	}
+
+	first := submitAt(2, 1, "A", "Accepted", 10)
+	first.TeamID = 7
+	second := submitAt(3, 2, "B", "Accepted", 20)
+	second.TeamID = 7
+	unknown := submitAt(4, 3, "A", "Accepted", 30)
+	unknown.TeamID = 9
+	board.Apply(first)
+	board.Apply(second)
+	board.Apply(unknown)
+
+	ranks := board.Ranked()
+	if len(ranks) != 2 {
+		t.Fatalf("got %d rows, want 2", len(ranks))
+	}
+	if ranks[0].TeamID != 7 || ranks[0].Username != "Team Seven" || ranks[0].Solved != 2 || ranks[0].UserID != 0 {
+		t.Errorf("team row = %+v", ranks[0])
+	}
+	if ranks[1].TeamID != 9 || ranks[1].Username != "队伍 #9" {
+		t.Errorf("unknown team row = %+v", ranks[1])
+	}
+}
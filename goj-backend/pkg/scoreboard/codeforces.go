@@ -0,0 +1,43 @@
+package scoreboard
+
+func init() {
+	Register(RuleCodeforces, codeforcesRule{})
+}
+
+// codeforcesRule 题目分值随时间衰减：通过时得分为
+// max(0.3x, x - x*t/250 - 50w)，x 为满分，t 为通过时间（分钟），w 为通过前的错误次数。
+// 未设置满分时第 i 题（从 0 开始）为 500*(i+1)
+type codeforcesRule struct{}
+
+func (codeforcesRule) Apply(board *Board, row *Row, sub *Submission) {
+	if row.Problems[sub.ProblemID] == "Accepted" {
+		return
+	}
+	row.Attempts[sub.ProblemID]++
+	if sub.Status != "Accepted" {
+		return
+	}
+
+	x := board.MaxScore(sub.ProblemID, 500*(board.ProblemIndex(sub.ProblemID)+1))
+	minutes := int(sub.SubmitTime.Sub(board.Contest.StartTime).Minutes())
+	score := x - x*minutes/250 - 50*(row.Attempts[sub.ProblemID]-1)
+	if floor := x * 3 / 10; score < floor {
+		score = floor
+	}
+
+	row.Scores[sub.ProblemID] = score
+	row.Problems[sub.ProblemID] = "Accepted"
+	row.recount()
+}
+
+func (codeforcesRule) Score(row *Row) float64 {
+	return float64(row.TotalScore)
+}
+
+func (codeforcesRule) FinalOnAccept() bool {
+	return true
+}
+
+func (codeforcesRule) HidesResults() bool {
+	return false
+}
@@ -0,0 +1,70 @@
+package scoreboard
+
+import "testing"
+
+func TestCodeforcesScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxScores map[string]int
+		subs      []*Submission
+		wantScore int
+	}{
+		{
+			name:      "第一题默认 500 分，随时间衰减",
+			subs:      []*Submission{submitAt(1, 1, "A", "Accepted", 25)},
+			wantScore: 500 - 500*25/250,
+		},
+		{
+			name:      "第二题默认 1000 分",
+			subs:      []*Submission{submitAt(1, 1, "B", "Accepted", 0)},
+			wantScore: 1000,
+		},
+		{
+			name: "每次错误扣 50 分",
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Wrong Answer", 5),
+				submitAt(2, 1, "A", "Runtime Error", 10),
+				submitAt(3, 1, "A", "Accepted", 25),
+			},
+			wantScore: 450 - 2*50,
+		},
+		{
+			name:      "不低于满分的 30%",
+			subs:      []*Submission{submitAt(1, 1, "A", "Accepted", 200)},
+			wantScore: 150,
+		},
+		{
+			name:      "使用题目设置的满分",
+			maxScores: map[string]int{"A": 1000},
+			subs:      []*Submission{submitAt(1, 1, "A", "Accepted", 50)},
+			wantScore: 1000 - 1000*50/250,
+		},
+		{
+			name: "通过后的提交不影响得分",
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Accepted", 25),
+				submitAt(2, 1, "A", "Wrong Answer", 30),
+				submitAt(3, 1, "A", "Accepted", 35),
+			},
+			wantScore: 450,
+		},
+		{
+			name:      "未通过不得分",
+			subs:      []*Submission{submitAt(1, 1, "A", "Wrong Answer", 25)},
+			wantScore: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := testBoard(RuleCodeforces, nil, tt.maxScores)
+			for _, sub := range tt.subs {
+				board.Apply(sub)
+			}
+			row := board.Row(1)
+			if row.TotalScore != tt.wantScore {
+				t.Errorf("total score = %d, want %d", row.TotalScore, tt.wantScore)
+			}
+		})
+	}
+}
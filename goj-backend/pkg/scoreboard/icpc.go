@@ -0,0 +1,35 @@
+package scoreboard
+
+func init() {
+	Register(RuleICPC, icpcRule{})
+}
+
+// icpcRule 通过题数多者优先，题数相同时罚时少者优先。
+// 罚时为每道通过题目的通过时间（分钟）加上通过前的错误次数乘以比赛的罚时
+type icpcRule struct{}
+
+func (icpcRule) Apply(board *Board, row *Row, sub *Submission) {
+	if row.Problems[sub.ProblemID] == "Accepted" {
+		return
+	}
+	row.Attempts[sub.ProblemID]++
+	if sub.Status == "Accepted" {
+		row.Problems[sub.ProblemID] = "Accepted"
+		row.Solved++
+		minutes := int(sub.SubmitTime.Sub(board.Contest.StartTime).Minutes())
+		row.Penalty += minutes + (row.Attempts[sub.ProblemID]-1)*board.Contest.PenaltyTime
+	}
+}
+
+// 罚时不会超过 1e9 分钟，解题数优先
+func (icpcRule) Score(row *Row) float64 {
+	return float64(row.Solved)*1e9 - float64(row.Penalty)
+}
+
+func (icpcRule) FinalOnAccept() bool {
+	return true
+}
+
+func (icpcRule) HidesResults() bool {
+	return false
+}
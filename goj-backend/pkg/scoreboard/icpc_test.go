@@ -0,0 +1,116 @@
+package scoreboard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestICPCPenalty(t *testing.T) {
+	tests := []struct {
+		name         string
+		subs         []*Submission
+		wantSolved   int
+		wantPenalty  int
+		wantAttempts map[string]int
+	}{
+		{
+			name:         "一次通过",
+			subs:         []*Submission{submitAt(1, 1, "A", "Accepted", 30)},
+			wantSolved:   1,
+			wantPenalty:  30,
+			wantAttempts: map[string]int{"A": 1},
+		},
+		{
+			name: "错误两次后通过",
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Wrong Answer", 10),
+				submitAt(2, 1, "A", "Time Limit Exceeded", 20),
+				submitAt(3, 1, "A", "Accepted", 45),
+			},
+			wantSolved:   1,
+			wantPenalty:  45 + 2*20,
+			wantAttempts: map[string]int{"A": 3},
+		},
+		{
+			name: "通过后的提交不计入",
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Accepted", 15),
+				submitAt(2, 1, "A", "Wrong Answer", 20),
+				submitAt(3, 1, "A", "Accepted", 25),
+			},
+			wantSolved:   1,
+			wantPenalty:  15,
+			wantAttempts: map[string]int{"A": 1},
+		},
+		{
+			name: "未通过的题目不计罚时",
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Wrong Answer", 10),
+				submitAt(2, 1, "A", "Wrong Answer", 20),
+			},
+			wantSolved:   0,
+			wantPenalty:  0,
+			wantAttempts: map[string]int{"A": 2},
+		},
+		{
+			name: "多道题目的罚时累加",
+			subs: []*Submission{
+				submitAt(1, 1, "A", "Accepted", 20),
+				submitAt(2, 1, "B", "Wrong Answer", 50),
+				submitAt(3, 1, "B", "Accepted", 90),
+			},
+			wantSolved:   2,
+			wantPenalty:  20 + 90 + 20,
+			wantAttempts: map[string]int{"A": 1, "B": 2},
+		},
+		{
+			name:         "不足一分钟的部分不计",
+			subs:         []*Submission{{ID: 1, UserID: 1, ProblemID: "A", Status: "Accepted", SubmitTime: contestStart.Add(59 * time.Second)}},
+			wantSolved:   1,
+			wantPenalty:  0,
+			wantAttempts: map[string]int{"A": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := testBoard(RuleICPC, nil, nil)
+			for _, sub := range tt.subs {
+				board.Apply(sub)
+			}
+			row := board.Row(1)
+			if row.Solved != tt.wantSolved || row.Penalty != tt.wantPenalty {
+				t.Errorf("solved %d penalty %d, want solved %d penalty %d",
+					row.Solved, row.Penalty, tt.wantSolved, tt.wantPenalty)
+			}
+			for problemID, want := range tt.wantAttempts {
+				if row.Attempts[problemID] != want {
+					t.Errorf("attempts[%s] = %d, want %d", problemID, row.Attempts[problemID], want)
+				}
+			}
+		})
+	}
+}
+
+func TestICPCRanking(t *testing.T) {
+	board := testBoard(RuleICPC, nil, nil)
+	// 用户 1：两题，罚时 200；用户 2：两题，罚时 100；用户 3：一题，罚时 5
+	for _, sub := range []*Submission{
+		submitAt(1, 1, "A", "Accepted", 100),
+		submitAt(2, 1, "B", "Accepted", 100),
+		submitAt(3, 2, "A", "Wrong Answer", 10),
+		submitAt(4, 2, "A", "Accepted", 30),
+		submitAt(5, 2, "B", "Accepted", 50),
+		submitAt(6, 3, "A", "Accepted", 5),
+	} {
+		board.Apply(sub)
+	}
+
+	ranks := board.Ranked()
+	want := []uint{2, 1, 3}
+	for i, row := range ranks {
+		if row.UserID != want[i] {
+			t.Fatalf("position %d: got user %d, want %d", i, row.UserID, want[i])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package scoreboard
+
+func init() {
+	Register(RuleIOI, ioiRule{})
+	Register(RuleOI, oiRule{})
+}
+
+// 测试点得分赛制的默认满分
+const defaultTestcaseMaxScore = 100
+
+// ioiRule 每题按通过的测试点比例得分，取最高的一次，结果实时公布
+type ioiRule struct{}
+
+func (ioiRule) Apply(board *Board, row *Row, sub *Submission) {
+	row.Attempts[sub.ProblemID]++
+	score := testcaseScore(sub, board.MaxScore(sub.ProblemID, defaultTestcaseMaxScore))
+	if _, ok := row.Scores[sub.ProblemID]; ok && score <= row.Scores[sub.ProblemID] {
+		return
+	}
+	row.Scores[sub.ProblemID] = score
+	row.Problems[sub.ProblemID] = sub.Status
+	row.recount()
+}
+
+func (ioiRule) Score(row *Row) float64 {
+	return float64(row.TotalScore)
+}
+
+// 满分后不会再提高
+func (ioiRule) FinalOnAccept() bool {
+	return true
+}
+
+func (ioiRule) HidesResults() bool {
+	return false
+}
+
+// oiRule 每题以最后一次提交的得分为准，比赛结束前不公布结果
+type oiRule struct{}
+
+func (oiRule) Apply(board *Board, row *Row, sub *Submission) {
+	row.Attempts[sub.ProblemID]++
+	row.Scores[sub.ProblemID] = testcaseScore(sub, board.MaxScore(sub.ProblemID, defaultTestcaseMaxScore))
+	row.Problems[sub.ProblemID] = sub.Status
+	row.recount()
+}
+
+func (oiRule) Score(row *Row) float64 {
+	return float64(row.TotalScore)
+}
+
+// 之后的提交会覆盖通过时的得分
+func (oiRule) FinalOnAccept() bool {
+	return false
+}
+
+func (oiRule) HidesResults() bool {
+	return true
+}
@@ -0,0 +1,105 @@
+package scoreboard
+
+import "testing"
+
+func TestTestcaseScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		sub       *Submission
+		maxScore  int
+		wantScore int
+	}{
+		{"没有测试点时通过得满分", &Submission{Status: "Accepted"}, 100, 100},
+		{"没有测试点时未通过得零分", &Submission{Status: "Compile Error"}, 100, 0},
+		{"按通过比例得分", &Submission{TestcasesStatus: []string{"Accepted", "Wrong Answer", "Accepted", "Accepted"}}, 100, 75},
+		{"向下取整", &Submission{TestcasesStatus: []string{"Accepted", "Wrong Answer", "Wrong Answer"}}, 100, 33},
+		{"按题目满分计算", &Submission{TestcasesStatus: []string{"Accepted", "Wrong Answer"}}, 300, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testcaseScore(tt.sub, tt.maxScore); got != tt.wantScore {
+				t.Errorf("testcaseScore = %d, want %d", got, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestTestcaseRules(t *testing.T) {
+	subs := []*Submission{
+		submitAt(1, 1, "A", "Wrong Answer", 10, "Accepted", "Wrong Answer"),
+		submitAt(2, 1, "A", "Accepted", 20, "Accepted", "Accepted"),
+		submitAt(3, 1, "A", "Wrong Answer", 30, "Wrong Answer", "Wrong Answer"),
+		submitAt(4, 1, "B", "Wrong Answer", 40, "Accepted", "Wrong Answer", "Wrong Answer", "Wrong Answer"),
+	}
+
+	tests := []struct {
+		name       string
+		rule       string
+		maxScores  map[string]int
+		wantScores map[string]int
+		wantStatus string // A 题的状态
+		wantTotal  int
+		wantSolved int
+	}{
+		{
+			name:       "IOI 取最高分",
+			rule:       RuleIOI,
+			wantScores: map[string]int{"A": 100, "B": 25},
+			wantStatus: "Accepted",
+			wantTotal:  125,
+			wantSolved: 1,
+		},
+		{
+			name:       "OI 取最后一次提交",
+			rule:       RuleOI,
+			wantScores: map[string]int{"A": 0, "B": 25},
+			wantStatus: "Wrong Answer",
+			wantTotal:  25,
+			wantSolved: 0,
+		},
+		{
+			name:       "使用题目设置的满分",
+			rule:       RuleIOI,
+			maxScores:  map[string]int{"A": 40, "B": 200},
+			wantScores: map[string]int{"A": 40, "B": 50},
+			wantStatus: "Accepted",
+			wantTotal:  90,
+			wantSolved: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := testBoard(tt.rule, nil, tt.maxScores)
+			for _, sub := range subs {
+				board.Apply(sub)
+			}
+			row := board.Row(1)
+			for problemID, want := range tt.wantScores {
+				if row.Scores[problemID] != want {
+					t.Errorf("scores[%s] = %d, want %d", problemID, row.Scores[problemID], want)
+				}
+			}
+			if row.Problems["A"] != tt.wantStatus {
+				t.Errorf("status = %q, want %q", row.Problems["A"], tt.wantStatus)
+			}
+			if row.TotalScore != tt.wantTotal || row.Solved != tt.wantSolved {
+				t.Errorf("total %d solved %d, want total %d solved %d",
+					row.TotalScore, row.Solved, tt.wantTotal, tt.wantSolved)
+			}
+			if row.Attempts["A"] != 3 {
+				t.Errorf("attempts = %d, want 3", row.Attempts["A"])
+			}
+		})
+	}
+}
+
+func TestIOIFirstZeroScoreIsRecorded(t *testing.T) {
+	board := testBoard(RuleIOI, nil, nil)
+	board.Apply(submitAt(1, 1, "A", "Compile Error", 10))
+	row := board.Row(1)
+	if score, ok := row.Scores["A"]; !ok || score != 0 || row.Problems["A"] != "Compile Error" {
+		t.Fatalf("got score %d (recorded %v), status %q", score, ok, row.Problems["A"])
+	}
+}
@@ -0,0 +1,155 @@
+// Package scoreboard 比赛榜单计算。
+//
+// 每种赛制实现一个 Rule，Board 按比赛的赛制把提交逐条计入参赛者的成绩并排序。
+// 新增赛制时实现 Rule 并在 init 中调用 Register 即可。
+package scoreboard
+
+import (
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"time"
+)
+
+// 赛制
+const (
+	RuleICPC       = "icpc"       // 按通过题数和罚时排名
+	RuleIOI        = "ioi"        // 按测试点得分排名，每题取最高分，实时公布
+	RuleOI         = "oi"         // 按测试点得分排名，每题取最后一次提交，比赛结束前不公布结果
+	RuleCodeforces = "codeforces" // 通过时的得分随时间和错误次数衰减
+)
+
+// StatusHidden 比赛结束前对选手隐藏的评测结果
+const StatusHidden = "Hidden"
+
+// Rule 赛制计分规则
+type Rule interface {
+	// Apply 将一次提交计入参赛者成绩，提交按评测完成顺序传入
+	Apply(board *Board, row *Row, sub *Submission)
+	// Score 排名依据，越大越靠前
+	Score(row *Row) float64
+	// FinalOnAccept 通过后的提交是否不再影响该题成绩
+	FinalOnAccept() bool
+	// HidesResults 比赛结束前是否对选手隐藏评测结果
+	HidesResults() bool
+}
+
+var rules = map[string]Rule{}
+
+// Register 注册赛制
+func Register(name string, rule Rule) {
+	rules[name] = rule
+}
+
+// IsValidRule 检查赛制是否合法，空值表示默认的 ICPC 赛制
+func IsValidRule(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := rules[name]
+	return ok
+}
+
+// RuleName 比赛使用的赛制，未设置时为 ICPC
+func RuleName(contest *models.Contest) string {
+	if _, ok := rules[contest.RuleType]; ok {
+		return contest.RuleType
+	}
+	return RuleICPC
+}
+
+// RuleOf 比赛使用的计分规则
+func RuleOf(contest *models.Contest) Rule {
+	return rules[RuleName(contest)]
+}
+
+// ResultsHidden 判断比赛当前是否对选手隐藏评测结果
+func ResultsHidden(contest *models.Contest, now time.Time) bool {
+	return RuleOf(contest).HidesResults() && now.Before(contest.EndTime)
+}
+
+// Submission 参与计分的比赛提交
+type Submission struct {
+	ID              uint               `json:"id"`
+	UserID          uint               `json:"userId"`
+	Username        string             `json:"username"`
+	Avatar          string             `json:"avatar"`
+	Bio             string             `json:"bio"`
+	ProblemID       string             `json:"problemId"`
+	Status          string             `json:"status"`
+	SubmitTime      time.Time          `json:"submitTime"`
+	TimeUsed        int                `json:"timeUsed"`
+	MemoryUsed      int                `json:"memoryUsed"`
+	TestcasesStatus models.StringArray `json:"testcasesStatus"`
+	TestcasesInfo   models.StringArray `json:"testcasesInfo"`
+	TeamID          uint               `json:"teamId,omitempty"` // 团队赛中提交计入的队伍
+}
+
+// Row 榜单中一个参赛者的成绩
+type Row struct {
+	UserID     uint              `json:"userId"`
+	Username   string            `json:"username"`
+	Avatar     string            `json:"avatar"`
+	Bio        string            `json:"bio"`
+	Problems   map[string]string `json:"problems"`          // 题目状态 map[problemId]status
+	Scores     map[string]int    `json:"scores"`            // 得分 map[problemId]score
+	Attempts   map[string]int    `json:"attempts"`          // 尝试次数 map[problemId]attempts
+	Solved     int               `json:"solved"`            // 解题数
+	Penalty    int               `json:"penalty"`           // ICPC 罚时（分钟）
+	TotalScore int               `json:"totalScore"`        // 总分
+	Pending    map[string]int    `json:"pending,omitempty"` // 封榜后待定的提交次数 map[problemId]count
+	Settled    map[string]string `json:"settled,omitempty"` // 显示为待定前的题目状态，公布结果时先恢复
+	Rank       int               `json:"rank,omitempty"`    // 名次，读取榜单时填写
+	Virtual    bool              `json:"virtual,omitempty"` // 虚拟参赛者，只出现在虚拟榜单上
+	TeamID     uint              `json:"teamId,omitempty"`  // 团队赛中为队伍ID，此时 Username 为队名，UserID 为 0
+	Members    []string          `json:"members,omitempty"` // 团队赛中的参赛队员
+}
+
+func newRow(userID uint, username, avatar, bio string) *Row {
+	return &Row{
+		UserID:   userID,
+		Username: username,
+		Avatar:   avatar,
+		Bio:      bio,
+		Problems: make(map[string]string),
+		Scores:   make(map[string]int),
+		Attempts: make(map[string]int),
+	}
+}
+
+// Entrant 榜单中区分参赛者的ID：团队赛为队伍ID，个人赛为用户ID
+func (r *Row) Entrant() uint {
+	if r.TeamID != 0 {
+		return r.TeamID
+	}
+	return r.UserID
+}
+
+// 重新计算总分和解题数
+func (r *Row) recount() {
+	r.TotalScore = 0
+	for _, score := range r.Scores {
+		r.TotalScore += score
+	}
+	r.Solved = 0
+	for _, status := range r.Problems {
+		if status == "Accepted" {
+			r.Solved++
+		}
+	}
+}
+
+// 按通过的测试点比例计算得分
+func testcaseScore(sub *Submission, maxScore int) int {
+	if len(sub.TestcasesStatus) == 0 {
+		if sub.Status == "Accepted" {
+			return maxScore
+		}
+		return 0
+	}
+	passed := 0
+	for _, status := range sub.TestcasesStatus {
+		if status == "Accepted" {
+			passed++
+		}
+	}
+	return passed * maxScore / len(sub.TestcasesStatus)
+}
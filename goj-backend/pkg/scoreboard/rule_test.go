@@ -0,0 +1,54 @@
+package scoreboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+)
+
+func TestRuleName(t *testing.T) {
+	tests := []struct {
+		ruleType string
+		want     string
+	}{
+		{"", RuleICPC},
+		{"unknown", RuleICPC},
+		{RuleIOI, RuleIOI},
+		{RuleOI, RuleOI},
+		{RuleCodeforces, RuleCodeforces},
+	}
+
+	for _, tt := range tests {
+		if got := RuleName(&models.Contest{RuleType: tt.ruleType}); got != tt.want {
+			t.Errorf("RuleName(%q) = %q, want %q", tt.ruleType, got, tt.want)
+		}
+		if valid := IsValidRule(tt.ruleType); valid != (tt.ruleType != "unknown") {
+			t.Errorf("IsValidRule(%q) = %v", tt.ruleType, valid)
+		}
+	}
+}
+
+func TestResultsHidden(t *testing.T) {
+	end := contestStart.Add(5 * time.Hour)
+	tests := []struct {
+		name string
+		rule string
+		now  time.Time
+		want bool
+	}{
+		{"OI 比赛中隐藏", RuleOI, contestStart.Add(time.Hour), true},
+		{"OI 比赛结束后公布", RuleOI, end, false},
+		{"IOI 实时公布", RuleIOI, contestStart.Add(time.Hour), false},
+		{"ICPC 实时公布", RuleICPC, contestStart.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contest := &models.Contest{RuleType: tt.rule, StartTime: contestStart, EndTime: end}
+			if got := ResultsHidden(contest, tt.now); got != tt.want {
+				t.Errorf("ResultsHidden = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
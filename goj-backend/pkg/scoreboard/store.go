@@ -0,0 +1,278 @@
+package scoreboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/config"
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 榜单在 Redis 中的存储。每场比赛按截止时间分为多个视图：实时榜单，以及封榜或隐藏结果时对选手公布的榜单。
+// 每个视图包含参赛者成绩（hash）、排名（zset）和已计入的提交ID（set），
+// 评测完成时由 Record 增量更新，视图不存在时由读取方全量重建后 Save
+const (
+	boardBuiltKey   = "contest_board:%s:%s:built" // 视图已构建标记
+	boardRowsKey    = "contest_board:%s:%s:rows"  // 参赛者成绩 map[参赛者ID]Row
+	boardOrderKey   = "contest_board:%s:%s:order" // 排名，分数为赛制排名依据的相反数，成绩相同时按参赛者ID排序
+	boardSubsKey    = "contest_board:%s:%s:subs"  // 已计入的提交ID
+	boardViewsKey   = "contest_board:%s:views"    // 已构建的视图
+	boardVersionKey = "contest_board:%s:version"  // 每有一条提交评测完成或视图失效时加一，用于检测重建期间的变化
+	BoardTTL        = 24 * time.Hour              // 视图过期时间，每次更新时刷新
+	recordRetries   = 5                           // 增量更新遇到并发修改时的重试次数
+)
+
+// ErrStaleBoard 重建期间榜单发生变化，重建结果已过期
+var ErrStaleBoard = errors.New("scoreboard changed during rebuild")
+
+// 视图名称
+func viewName(cutoff *time.Time) string {
+	if cutoff == nil {
+		return "live"
+	}
+	return "frozen:" + strconv.FormatInt(cutoff.Unix(), 10)
+}
+
+// 视图的各个键
+func viewKeys(contestID, view string) (built, rows, order, subs string) {
+	return fmt.Sprintf(boardBuiltKey, contestID, view),
+		fmt.Sprintf(boardRowsKey, contestID, view),
+		fmt.Sprintf(boardOrderKey, contestID, view),
+		fmt.Sprintf(boardSubsKey, contestID, view)
+}
+
+// 排名集合中的成员，补零使成绩相同时按参赛者ID升序
+func orderMember(entrant uint) string {
+	return fmt.Sprintf("%010d", entrant)
+}
+
+// Cutoffs 比赛对选手公布的榜单可能使用的截止时间：封榜时间，以及隐藏结果赛制的比赛开始时间
+func Cutoffs(contest *models.Contest) []time.Time {
+	var cutoffs []time.Time
+	if contest.FreezeTime != nil {
+		cutoffs = append(cutoffs, *contest.FreezeTime)
+	}
+	if RuleOf(contest).HidesResults() {
+		cutoffs = append(cutoffs, contest.StartTime)
+	}
+	return cutoffs
+}
+
+// Version 榜单版本，重建前读取，保存时用于检测期间的新提交
+func Version(ctx context.Context, contestID string) (int64, error) {
+	version, err := config.RDB.Get(ctx, fmt.Sprintf(boardVersionKey, contestID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// Save 保存全量重建的榜单。读取版本后榜单发生变化时返回 ErrStaleBoard，不保存
+func Save(ctx context.Context, board *Board, version int64) error {
+	contestID := board.Contest.ID
+	view := viewName(board.Cutoff)
+	built, rows, order, subs := viewKeys(contestID, view)
+	versionKey := fmt.Sprintf(boardVersionKey, contestID)
+	viewsKey := fmt.Sprintf(boardViewsKey, contestID)
+
+	return config.RDB.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, versionKey).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if current != version {
+			return ErrStaleBoard
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, rows, order, subs)
+			for userID, row := range board.rows {
+				data, err := json.Marshal(row)
+				if err != nil {
+					return err
+				}
+				pipe.HSet(ctx, rows, orderMember(userID), data)
+				pipe.ZAdd(ctx, order, redis.Z{Score: -board.Rule.Score(row), Member: orderMember(userID)})
+			}
+			if len(board.applied) > 0 {
+				ids := make([]interface{}, 0, len(board.applied))
+				for _, id := range board.applied {
+					ids = append(ids, id)
+				}
+				pipe.SAdd(ctx, subs, ids...)
+			}
+			pipe.Set(ctx, built, 1, BoardTTL)
+			pipe.SAdd(ctx, viewsKey, view)
+			for _, key := range []string{rows, order, subs, viewsKey} {
+				pipe.Expire(ctx, key, BoardTTL)
+			}
+			return nil
+		})
+		return err
+	}, versionKey)
+}
+
+// Page 读取从第 offset+1 名开始的 limit 个成绩，limit 小于等于 0 时读取全部。
+// 视图尚未构建时 ok 为 false
+func Page(ctx context.Context, contestID string, cutoff *time.Time, offset, limit int) (ranks []*Row, total int64, ok bool, err error) {
+	built, rows, order, _ := viewKeys(contestID, viewName(cutoff))
+	if n, err := config.RDB.Exists(ctx, built).Result(); err != nil || n == 0 {
+		return nil, 0, false, err
+	}
+
+	total, err = config.RDB.ZCard(ctx, order).Result()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(offset + limit - 1)
+	}
+	members, err := config.RDB.ZRange(ctx, order, int64(offset), stop).Result()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	ranks = make([]*Row, 0, len(members))
+	if len(members) == 0 {
+		return ranks, total, true, nil
+	}
+	values, err := config.RDB.HMGet(ctx, rows, members...).Result()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	for i, value := range values {
+		data, isString := value.(string)
+		if !isString {
+			// 成绩与排名不一致，视为视图未构建
+			return nil, 0, false, nil
+		}
+		var row Row
+		if err := json.Unmarshal([]byte(data), &row); err != nil {
+			return nil, 0, false, err
+		}
+		row.Rank = offset + i + 1
+		ranks = append(ranks, &row)
+	}
+	return ranks, total, true, nil
+}
+
+// Record 将一条评测完成的提交计入比赛已构建的各个视图，未构建的视图留给读取时重建
+func Record(ctx context.Context, contest *models.Contest, sub *Submission) error {
+	if err := config.RDB.Incr(ctx, fmt.Sprintf(boardVersionKey, contest.ID)).Err(); err != nil {
+		return err
+	}
+
+	cutoffs := Cutoffs(contest)
+	views := make([]*time.Time, 0, len(cutoffs)+1)
+	views = append(views, nil)
+	for i := range cutoffs {
+		views = append(views, &cutoffs[i])
+	}
+
+	// 没有已构建的视图时无需读取比赛设置
+	builtKeys := make([]string, 0, len(views))
+	for _, cutoff := range views {
+		built, _, _, _ := viewKeys(contest.ID, viewName(cutoff))
+		builtKeys = append(builtKeys, built)
+	}
+	if n, err := config.RDB.Exists(ctx, builtKeys...).Result(); err != nil || n == 0 {
+		return err
+	}
+
+	// 各个视图共用一次读取的比赛设置
+	meta := loadBoardMeta(contest)
+	var firstErr error
+	for _, cutoff := range views {
+		if err := recordView(ctx, contest, meta, cutoff, sub); err != nil {
+			// 增量更新失败时丢弃该视图，下次读取时重建
+			invalidateView(ctx, contest.ID, viewName(cutoff))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// 在一个视图中计入提交，同一提交只计入一次
+func recordView(ctx context.Context, contest *models.Contest, meta *boardMeta, cutoff *time.Time, sub *Submission) error {
+	built, rows, order, subs := viewKeys(contest.ID, viewName(cutoff))
+	entrant := EntrantOf(contest, sub)
+	if entrant == 0 {
+		return nil
+	}
+	member := orderMember(entrant)
+
+	update := func(tx *redis.Tx) error {
+		if n, err := tx.Exists(ctx, built).Result(); err != nil || n == 0 {
+			return err
+		}
+		if applied, err := tx.SIsMember(ctx, subs, sub.ID).Result(); err != nil || applied {
+			return err
+		}
+
+		board := newBoard(contest, cutoff, meta)
+		data, err := tx.HGet(ctx, rows, member).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if err == nil {
+			row := &Row{}
+			if err := json.Unmarshal([]byte(data), row); err != nil {
+				return err
+			}
+			board.rows[entrant] = row
+		}
+		board.Apply(sub)
+
+		row := board.Row(entrant)
+		encoded, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, rows, member, encoded)
+			pipe.ZAdd(ctx, order, redis.Z{Score: -board.Rule.Score(row), Member: member})
+			pipe.SAdd(ctx, subs, sub.ID)
+			for _, key := range []string{built, rows, order, subs} {
+				pipe.Expire(ctx, key, BoardTTL)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < recordRetries; i++ {
+		err := config.RDB.Watch(ctx, update, built, rows, subs)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
+
+// 删除一个视图
+func invalidateView(ctx context.Context, contestID, view string) {
+	built, rows, order, subs := viewKeys(contestID, view)
+	config.RDB.Del(ctx, built, rows, order, subs)
+}
+
+// Invalidate 删除比赛的所有视图，比赛设置、参赛者或评测结果变化后调用，下次读取时重建
+func Invalidate(ctx context.Context, contestID string) {
+	// 使进行中的重建失效
+	config.RDB.Incr(ctx, fmt.Sprintf(boardVersionKey, contestID))
+
+	viewsKey := fmt.Sprintf(boardViewsKey, contestID)
+	views, _ := config.RDB.SMembers(ctx, viewsKey).Result()
+	views = append(views, viewName(nil))
+	for _, view := range views {
+		invalidateView(ctx, contestID, view)
+	}
+	config.RDB.Del(ctx, viewsKey)
+}
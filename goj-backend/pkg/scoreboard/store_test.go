@@ -0,0 +1,142 @@
+package scoreboard
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KrisLiu16/OnlineJudge-GOJ/goj-backend/pkg/models"
+)
+
+func TestViewName(t *testing.T) {
+	freeze := time.Unix(1714554000, 0)
+	if got := viewName(nil); got != "live" {
+		t.Errorf("viewName(nil) = %q", got)
+	}
+	if got := viewName(&freeze); got != "frozen:1714554000" {
+		t.Errorf("viewName(freeze) = %q", got)
+	}
+
+	built, rows, order, subs := viewKeys("10001", "live")
+	want := []string{
+		"contest_board:10001:live:built",
+		"contest_board:10001:live:rows",
+		"contest_board:10001:live:order",
+		"contest_board:10001:live:subs",
+	}
+	if got := []string{built, rows, order, subs}; !reflect.DeepEqual(got, want) {
+		t.Errorf("viewKeys = %v, want %v", got, want)
+	}
+}
+
+// 排名集合中成绩相同的成员按字典序排列，应与参赛者ID的数值顺序一致
+func TestOrderMemberSortsNumerically(t *testing.T) {
+	entrants := []uint{100, 9, 1000000, 10, 2, 99}
+	members := make([]string, len(entrants))
+	for i, entrant := range entrants {
+		members[i] = orderMember(entrant)
+	}
+	sort.Strings(members)
+	sort.Slice(entrants, func(i, j int) bool { return entrants[i] < entrants[j] })
+	for i, entrant := range entrants {
+		if members[i] != orderMember(entrant) {
+			t.Fatalf("position %d: got %s, want %s", i, members[i], orderMember(entrant))
+		}
+	}
+}
+
+func TestCutoffs(t *testing.T) {
+	freeze := contestStart.Add(4 * time.Hour)
+	tests := []struct {
+		name       string
+		rule       string
+		freezeTime *time.Time
+		want       []time.Time
+	}{
+		{"不封榜的 ICPC", RuleICPC, nil, nil},
+		{"封榜的 ICPC", RuleICPC, &freeze, []time.Time{freeze}},
+		{"OI 从开始即隐藏", RuleOI, nil, []time.Time{contestStart}},
+		{"封榜的 OI", RuleOI, &freeze, []time.Time{freeze, contestStart}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contest := &models.Contest{RuleType: tt.rule, StartTime: contestStart, FreezeTime: tt.freezeTime}
+			if got := Cutoffs(contest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cutoffs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 增量更新与 recordView 相同：从保存的成绩恢复参赛者，在新榜单上计入一条提交后保存。
+// 结果应与全量重建一致，排名集合的顺序应与 Sort 一致
+func TestIncrementalMatchesRebuild(t *testing.T) {
+	subs := []*Submission{
+		submitAt(1, 1, "A", "Wrong Answer", 10, "Accepted", "Wrong Answer"),
+		submitAt(2, 2, "A", "Accepted", 20, "Accepted", "Accepted"),
+		submitAt(3, 1, "B", "Accepted", 30, "Accepted", "Accepted"),
+		submitAt(4, 3, "B", "Wrong Answer", 40, "Wrong Answer", "Wrong Answer"),
+		submitAt(5, 1, "A", "Accepted", 70, "Accepted", "Accepted"),
+		submitAt(6, 2, "B", "Wrong Answer", 80, "Accepted", "Wrong Answer"),
+		submitAt(7, 3, "C", "Accepted", 90, "Accepted", "Accepted"),
+	}
+
+	for _, rule := range []string{RuleICPC, RuleIOI, RuleOI, RuleCodeforces} {
+		for _, cutoff := range []*time.Time{nil, minuteOf(60)} {
+			t.Run(rule+"/"+viewName(cutoff), func(t *testing.T) {
+				full := testBoard(rule, cutoff, nil)
+				saved := make(map[uint][]byte)
+				for _, sub := range subs {
+					full.Apply(sub)
+
+					board := testBoard(rule, cutoff, nil)
+					entrant := EntrantOf(board.Contest, sub)
+					if data, ok := saved[entrant]; ok {
+						row := &Row{}
+						if err := json.Unmarshal(data, row); err != nil {
+							t.Fatal(err)
+						}
+						board.rows[entrant] = row
+					}
+					board.Apply(sub)
+					data, err := json.Marshal(board.Row(entrant))
+					if err != nil {
+						t.Fatal(err)
+					}
+					saved[entrant] = data
+				}
+
+				incremental := make([]*Row, 0, len(saved))
+				for entrant, data := range saved {
+					row := &Row{}
+					if err := json.Unmarshal(data, row); err != nil {
+						t.Fatal(err)
+					}
+					want, _ := json.Marshal(full.Row(entrant))
+					if string(data) != string(want) {
+						t.Errorf("entrant %d: incremental %s, rebuild %s", entrant, data, want)
+					}
+					incremental = append(incremental, row)
+				}
+
+				// 排名集合按 (-Score, 成员) 升序
+				sort.Slice(incremental, func(i, j int) bool {
+					si, sj := -full.Rule.Score(incremental[i]), -full.Rule.Score(incremental[j])
+					if si != sj {
+						return si < sj
+					}
+					return orderMember(incremental[i].Entrant()) < orderMember(incremental[j].Entrant())
+				})
+				ranked := full.Ranked()
+				for i := range ranked {
+					if ranked[i].Entrant() != incremental[i].Entrant() {
+						t.Fatalf("position %d: zset order %d, Sort %d", i, incremental[i].Entrant(), ranked[i].Entrant())
+					}
+				}
+			})
+		}
+	}
+}
@@ -87,6 +87,14 @@ const routes: Array<RouteRecordRaw> = [
     name: 'groups',
     component: () => import('@/views/group/GroupsView.vue'),
   },
+  {
+    path: '/teams',
+    name: 'teams',
+    component: () => import('@/views/team/TeamsView.vue'),
+    meta: {
+      requiresAuth: true,
+    },
+  },
   {
     path: '/profile/:username?',
     name: 'profile',
@@ -178,6 +186,16 @@ const routes: Array<RouteRecordRaw> = [
     name: 'contest-rank',
     component: () => import('@/views/contest/ContestRankView.vue'),
   },
+  {
+    path: '/contest/:id/virtual',
+    name: 'contest-virtual-rank',
+    component: () => import('@/views/contest/ContestRankView.vue'),
+  },
+  {
+    path: '/contest/:id/clarifications',
+    name: 'contest-clarifications',
+    component: () => import('@/views/contest/ContestClarificationsView.vue'),
+  },
   {
     path: '/contest/:id/participants',
     name: 'contest-participants',
@@ -95,6 +95,9 @@ export const useUserStore = defineStore('user', {
             case 'judge_status':
               window.dispatchEvent(new CustomEvent('judge_status', { detail: message.data }))
               break
+            case 'judge_progress':
+              window.dispatchEvent(new CustomEvent('judge_progress', { detail: message.data }))
+              break
           }
         } catch (error) {
           // console.error('[WebSocket] 消息解析错误:', error)
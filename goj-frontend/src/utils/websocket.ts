@@ -0,0 +1,97 @@
+import { useUserStore } from '@/stores/modules/user'
+
+// 页面共用的 WebSocket 连接，按主题订阅服务端推送，断线后自动重连并恢复订阅
+
+type MessageHandler = (data: any) => void
+
+let socket: WebSocket | null = null
+let reconnectTimer: ReturnType<typeof setTimeout> | null = null
+let heartbeatTimer: ReturnType<typeof setInterval> | null = null
+const topics = new Map<string, number>() // 主题 -> 订阅次数
+const handlers = new Map<string, Set<MessageHandler>>() // 消息类型 -> 处理函数
+
+const send = (payload: object) => {
+  if (socket?.readyState === WebSocket.OPEN) {
+    socket.send(JSON.stringify(payload))
+  }
+}
+
+const connect = () => {
+  const userStore = useUserStore()
+  if (socket || !userStore.token) return
+
+  const baseUrl =
+    import.meta.env.VITE_WS_URL ||
+    `${window.location.protocol === 'https:' ? 'wss' : 'ws'}://${window.location.host}/api`
+  socket = new WebSocket(`${baseUrl}/ws?token=${userStore.token}`)
+
+  socket.onopen = () => {
+    topics.forEach((_, topic) => send({ type: 'subscribe', topic }))
+    heartbeatTimer = setInterval(() => {
+      if (socket?.readyState === WebSocket.OPEN) socket.send('ping')
+    }, 30000)
+  }
+
+  socket.onmessage = (event) => {
+    if (event.data === 'pong') return
+    try {
+      const message = JSON.parse(event.data)
+      handlers.get(message.type)?.forEach((handler) => handler(message.data))
+    } catch {
+      // 忽略无法解析的消息
+    }
+  }
+
+  socket.onclose = () => {
+    socket = null
+    if (heartbeatTimer) clearInterval(heartbeatTimer)
+    heartbeatTimer = null
+    // 仍有订阅或监听时重连
+    if ((topics.size > 0 || handlers.size > 0) && !reconnectTimer) {
+      reconnectTimer = setTimeout(() => {
+        reconnectTimer = null
+        connect()
+      }, 5000)
+    }
+  }
+}
+
+const disconnectIfIdle = () => {
+  if (topics.size === 0 && handlers.size === 0 && socket) {
+    socket.close()
+    socket = null
+  }
+}
+
+// 订阅主题，返回取消订阅的函数
+export const subscribeTopic = (topic: string) => {
+  const count = topics.get(topic) || 0
+  topics.set(topic, count + 1)
+  if (count === 0) send({ type: 'subscribe', topic })
+  connect()
+
+  return () => {
+    const current = topics.get(topic) || 0
+    if (current <= 1) {
+      topics.delete(topic)
+      send({ type: 'unsubscribe', topic })
+    } else {
+      topics.set(topic, current - 1)
+    }
+    disconnectIfIdle()
+  }
+}
+
+// 监听指定类型的消息，返回取消监听的函数
+export const onSocketMessage = (type: string, handler: MessageHandler) => {
+  if (!handlers.has(type)) handlers.set(type, new Set())
+  handlers.get(type)!.add(handler)
+  connect()
+
+  return () => {
+    const set = handlers.get(type)
+    set?.delete(handler)
+    if (set && set.size === 0) handlers.delete(type)
+    disconnectIfIdle()
+  }
+}